@@ -0,0 +1,65 @@
+//go:build js && wasm
+// +build js,wasm
+
+// Command go-test-report-wasm exposes the parser and the renderers to
+// JavaScript, so web/index.html can turn a go test -json log into a
+// report without a server. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o web/go-test-report.wasm ./cmd/go-test-report-wasm
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" web/
+//
+// (misc/wasm instead of lib/wasm before Go 1.24) and serve the web
+// directory with any static file server.
+package main
+
+import (
+	"strings"
+	"syscall/js"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/render"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+func main() {
+	js.Global().Set("goTestReport", js.FuncOf(goTestReport))
+	js.Global().Set("goTestReportFormats", js.FuncOf(formats))
+	select {}
+}
+
+// goTestReport is called from JavaScript as goTestReport(log, format) and
+// returns {output} with the report of log, or {error}.
+func goTestReport(_ js.Value, args []js.Value) interface{} {
+	if len(args) != 2 {
+		return result("", "usage: goTestReport(log, format)")
+	}
+	r, ok := render.Lookup(args[1].String())
+	if !ok {
+		return result("", "unknown format "+args[1].String())
+	}
+	ti, err := report.Parse(strings.NewReader(args[0].String()))
+	if err != nil {
+		return result("", err.Error())
+	}
+	ti.SetCount()
+	b := &strings.Builder{}
+	err = r.Render(b, ti)
+	if err != nil {
+		return result("", err.Error())
+	}
+	return result(b.String(), "")
+}
+
+func formats(js.Value, []js.Value) interface{} {
+	var names []interface{}
+	for _, name := range render.Names() {
+		names = append(names, name)
+	}
+	return names
+}
+
+func result(output, err string) interface{} {
+	if len(err) > 0 {
+		return map[string]interface{}{"error": err}
+	}
+	return map[string]interface{}{"output": output}
+}
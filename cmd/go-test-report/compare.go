@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/history"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	path := fs.String("history", "", "history file")
+	branch := fs.String("branch", "", "only consider runs of this branch")
+	format := fs.String("format", "markdown", "output format: markdown or json")
+	threshold := fs.Float64("threshold", 20, "minimum duration change in percent to report")
+	minDur := fs.Duration("min-duration", 100*time.Millisecond, "ignore duration changes of tests faster than this in both runs")
+	fs.Var(&statusSymbols, "symbols", symbolsUsage)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-test-report compare -history runs.jsonl [flags] [report.xml]\n\nCompares the report, or else the last run of the history, with the run before\nit in the history. Exits like diff.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if len(*path) < 1 || fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	runs, err := history.Read(*path)
+	if err != nil {
+		fatal(exitInput, err)
+	}
+	runs = history.FilterBranch(runs, *branch)
+	var newTi *report.TestInfo
+	if fs.NArg() == 1 {
+		newTi, err = report.Read(fs.Arg(0))
+		if err != nil {
+			fatal(exitInput, err)
+		}
+		// The report may already be the last run of the history.
+		if n := len(runs); n > 0 && len(newTi.RunID) > 0 && runs[n-1].ID == newTi.RunID {
+			runs = runs[:n-1]
+		}
+	} else if n := len(runs); n > 0 {
+		newTi, runs = runs[n-1].TestInfo(), runs[:n-1]
+	}
+	if newTi == nil || len(runs) < 1 {
+		fatal(exitInput, errors.New("compare: no previous run in the history"))
+	}
+	prev := runs[len(runs)-1]
+	d := report.Compare(prev.TestInfo(), newTi, *threshold, *minDur)
+	writeDiff(d, *format)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/render"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+func runConvert(args []string) {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	to := fs.String("to", "", "format to convert to: "+strings.Join(render.Names(), ", "))
+	out := fs.String("o", "", "write to `path` instead of stdout")
+	for _, name := range []string{"lang", "html-css", "html-logo", "html-assets", "symbols", "xml-output", "xml-compact", "xml-empty", "indent", "subtests", "xml-root", "xml-namespace", "xml-root-attr"} {
+		f := flag.Lookup(name)
+		fs.Var(f.Value, f.Name, f.Usage)
+	}
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-test-report convert -to format [-o path] report.xml\n\nRenders a report written with -format xml or json in another format.")
+		fs.PrintDefaults()
+	}
+	inputs := parseArgs(fs, args)
+	if len(inputs) != 1 || len(*to) < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	input := inputs[0]
+	if _, ok := render.Lookup(*to); !ok {
+		fatal(exitUsage, usageError(fmt.Sprintf("unknown format %q, want one of %s", *to, strings.Join(render.Names(), ", "))))
+	}
+	ti, err := report.Read(input)
+	if err != nil {
+		fatal(exitInput, err)
+	}
+	dir := "."
+	if len(*out) > 0 {
+		dir = filepath.Dir(*out)
+	}
+	r, _, err := formatRenderer(*to, dir)
+	if err != nil {
+		fatal(exitOutput, err)
+	}
+	if len(*out) > 0 {
+		err = render.WriteFile(*out, r, ti)
+	} else {
+		w := bufio.NewWriter(os.Stdout)
+		err = r.Render(w, ti)
+		if err == nil {
+			err = w.Flush()
+		}
+	}
+	if err != nil {
+		fatal(exitOutput, err)
+	}
+}
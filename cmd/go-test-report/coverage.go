@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var coverProfile = flag.String("coverprofile", "", "add the statement coverage of the go test cover `profile` to the packages of the report; in run mode it is taken from the go test flags")
+
+// goTestCoverProfile returns the -coverprofile of the go test flags.
+func goTestCoverProfile(goFlags []string) string {
+	path := ""
+	for i := 0; i < len(goFlags); i++ {
+		name := strings.TrimLeft(goFlags[i], "-")
+		value, ok := "", false
+		if j := strings.Index(name, "="); j >= 0 {
+			name, value, ok = name[:j], name[j+1:], true
+		}
+		if name != "coverprofile" && name != "test.coverprofile" {
+			continue
+		}
+		if !ok && i+1 < len(goFlags) {
+			i++
+			value = goFlags[i]
+		}
+		path = value
+	}
+	return path
+}
+
+// applyCoverProfile adds the coverage of -coverprofile to ti.
+func applyCoverProfile(ti *report.TestInfo) error {
+	if len(*coverProfile) < 1 {
+		return nil
+	}
+	f, err := os.Open(*coverProfile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	p, err := report.ReadCoverProfile(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *coverProfile, err)
+	}
+	ti.ApplyCoverProfile(p)
+	return nil
+}
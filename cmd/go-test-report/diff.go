@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/render"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// exitSlower is the exit status of diff when tests only got slower.
+const exitSlower = 5
+
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := fs.String("format", "markdown", "output format: markdown or json")
+	threshold := fs.Float64("threshold", 20, "minimum duration change in percent to report")
+	minDur := fs.Duration("min-duration", 100*time.Millisecond, "ignore duration changes of tests faster than this in both reports")
+	fs.Var(&statusSymbols, "symbols", symbolsUsage)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-test-report diff [flags] old.xml new.xml\n\nExits with 1 if tests failed that did not fail before, with 5 if tests only\ngot slower by the threshold, and with 0 otherwise.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	oldTi, err := report.Read(fs.Arg(0))
+	if err != nil {
+		fatal(exitInput, err)
+	}
+	newTi, err := report.Read(fs.Arg(1))
+	if err != nil {
+		fatal(exitInput, err)
+	}
+	d := report.Compare(oldTi, newTi, *threshold, *minDur)
+	writeDiff(d, *format)
+}
+
+// writeDiff prints d in format and exits with exitFailed on new failures
+// or with exitSlower on slower tests.
+func writeDiff(d *report.Diff, format string) {
+	var err error
+	switch format {
+	case "json":
+		err = render.DiffJSON(os.Stdout, d)
+	case "markdown", "md":
+		err = render.DiffMarkdown(os.Stdout, d)
+	default:
+		err = usageError(fmt.Sprintf("unknown diff format %q", format))
+	}
+	if err != nil {
+		fatal(exitOutput, err)
+	}
+	if len(d.NewFailures) > 0 {
+		os.Exit(exitFailed)
+	}
+	for _, e := range d.DurationChanges {
+		if e.Change > 0 {
+			os.Exit(exitSlower)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/history"
+)
+
+// feedEntries is the number of breakages in the feed.
+const feedEntries = 50
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID      string    `xml:"id"`
+	Title   string    `xml:"title"`
+	Updated time.Time `xml:"updated"`
+	Link    atomLink  `xml:"link"`
+	Summary string    `xml:"summary"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string       `xml:"id"`
+	Title   string       `xml:"title"`
+	Updated time.Time    `xml:"updated"`
+	Author  string       `xml:"author>name"`
+	Links   []atomLink   `xml:"link"`
+	Entries []*atomEntry `xml:"entry"`
+}
+
+// handleFeed serves an Atom feed of the tests that started failing,
+// newest first, on every branch or the one of ?branch=.
+func (s *server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	runs, err := s.runs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	base := scheme + "://" + r.Host
+	branch := r.URL.Query().Get("branch")
+	feed := &atomFeed{
+		ID:      base + r.URL.RequestURI(),
+		Title:   "New test failures",
+		Updated: time.Now().UTC(),
+		Author:  "go-test-report",
+		Links:   []atomLink{{Href: base + r.URL.RequestURI(), Rel: "self"}, {Href: base + "/?branch=" + url.QueryEscape(branch)}},
+	}
+	if len(branch) > 0 {
+		feed.Title += " on " + branch
+	}
+	list := history.Breakages(runs)
+	for i := len(list) - 1; i >= 0 && len(feed.Entries) < feedEntries; i-- {
+		b := list[i]
+		title := fmt.Sprintf("%s %s failed", b.Test.Package, b.Test.Test)
+		summary := fmt.Sprintf("Run %s", b.Run.ID)
+		if len(b.Run.Branch) > 0 {
+			title += " on " + b.Run.Branch
+			summary += " on " + b.Run.Branch
+		}
+		if len(b.Run.Commit) > 0 {
+			summary += " at commit " + b.Run.Commit
+		}
+		feed.Entries = append(feed.Entries, &atomEntry{
+			ID:      "tag:go-test-report," + b.Run.Time.Format("2006-01-02") + ":" + b.Run.ID + "/" + b.Test.Package + "/" + b.Test.Test,
+			Title:   title,
+			Updated: b.Run.Time.UTC(),
+			Link:    atomLink{Href: base + "/test?pkg=" + url.QueryEscape(b.Test.Package) + "&test=" + url.QueryEscape(b.Test.Test)},
+			Summary: summary + ".",
+		})
+	}
+	if len(feed.Entries) > 0 {
+		feed.Updated = feed.Entries[0].Updated
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	_, _ = w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(feed)
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var (
+	includePkg   = flag.String("include-pkg", "", "only report the packages matching the `regexp`")
+	excludePkg   = flag.String("exclude-pkg", "", "leave out the packages matching the `regexp`")
+	includeTest  = flag.String("include-test", "", "only report the tests whose full name matches the `regexp`")
+	excludeTest  = flag.String("exclude-test", "", "leave out the tests whose full name matches the `regexp`")
+	failuresOnly = flag.Bool("failures-only", false, "list only the failed, skipped and flaky tests in the report, still counting all of them")
+)
+
+// parseFilter returns the filter of the flags, or nil if they are unset.
+func parseFilter() *report.Filter {
+	f := &report.Filter{FailuresOnly: *failuresOnly}
+	for _, e := range []struct {
+		flag, expr string
+		re         **regexp.Regexp
+	}{
+		{"include-pkg", *includePkg, &f.IncludePkg},
+		{"exclude-pkg", *excludePkg, &f.ExcludePkg},
+		{"include-test", *includeTest, &f.IncludeTest},
+		{"exclude-test", *excludeTest, &f.ExcludeTest},
+	} {
+		if len(e.expr) < 1 {
+			continue
+		}
+		re, err := regexp.Compile(e.expr)
+		if err != nil {
+			fatal(exitUsage, usageError(fmt.Sprintf("-%s: %v", e.flag, err)))
+		}
+		*e.re = re
+	}
+	if *f == (report.Filter{}) {
+		return nil
+	}
+	return f
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/history"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var (
+	gerritURL      = flag.String("gerrit", "", "post a review with the summary and robot comments on failures to the Gerrit server at this URL; credentials come from its userinfo or GERRIT_USER and GERRIT_HTTP_PASSWORD. Files are found through the package directories of -modules, or else the go.mod of the current directory")
+	gerritChange   = flag.String("gerrit-change", envFirst("GERRIT_CHANGE_NUMBER"), "Gerrit change to review")
+	gerritRevision = flag.String("gerrit-revision", envFirst("GERRIT_PATCHSET_REVISION"), "revision of the change to review; the current one if empty")
+	gerritLabel    = flag.String("gerrit-label", "", "also vote +1 or -1 on this label, such as Verified")
+)
+
+// gerritMaxFailures bounds the failed tests listed in the summary.
+const gerritMaxFailures = 20
+
+type gerritComment struct {
+	Line       int    `json:"line"`
+	Message    string `json:"message"`
+	RobotID    string `json:"robot_id"`
+	RobotRunID string `json:"robot_run_id"`
+}
+
+type gerritReview struct {
+	Message       string                     `json:"message"`
+	Tag           string                     `json:"tag"`
+	Labels        map[string]int             `json:"labels,omitempty"`
+	RobotComments map[string][]gerritComment `json:"robot_comments,omitempty"`
+}
+
+// gerritReviewOf summarizes ti and puts a robot comment on every place a
+// failed test logged from, when its file can be found from the directory
+// of its package, see report.TestInfo.GroupModules, or else in module.
+func gerritReviewOf(ti *report.TestInfo, module string) *gerritReview {
+	runID := ti.RunID
+	if len(runID) < 1 {
+		runID = history.RunID(time.Now())
+	}
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "go-test-report: %d tests, %d passed, %d failed, %d skipped", ti.Total, ti.Pass, ti.Fail, ti.Skip)
+	if ti.Incomplete {
+		b.WriteString(" (incomplete)")
+	}
+	r := &gerritReview{Tag: "autogenerated:go-test-report", RobotComments: map[string][]gerritComment{}}
+	var failed []string
+	for _, tp := range ti.TpList {
+		if tp.Action == events.ActionFail && tp.Fail < 1 {
+			failed = append(failed, "* "+tp.Package)
+		}
+		for _, u := range tp.TEList {
+			if u.Action != events.ActionFail {
+				continue
+			}
+			failed = append(failed, "* "+tp.Package+" "+u.Test)
+			dir, ok := tp.Dir, len(tp.Dir) > 0
+			if !ok {
+				dir, ok = packageDir(tp.Package, module)
+			}
+			if !ok {
+				continue
+			}
+			for _, loc := range u.Locations() {
+				file := path.Join(dir, loc.File)
+				r.RobotComments[file] = append(r.RobotComments[file], gerritComment{
+					Line:       loc.Line,
+					Message:    u.Test + ": " + loc.Message,
+					RobotID:    "go-test-report",
+					RobotRunID: runID,
+				})
+			}
+		}
+	}
+	if len(failed) > gerritMaxFailures {
+		failed = append(failed[:gerritMaxFailures], fmt.Sprintf("* and %d more", len(failed)-gerritMaxFailures))
+	}
+	if len(failed) > 0 {
+		b.WriteString("\n\nFailed:\n" + strings.Join(failed, "\n"))
+	}
+	r.Message = b.String()
+	if len(*gerritLabel) > 0 {
+		vote := 1
+		if ti.Fail > 0 || ti.Incomplete {
+			vote = -1
+		}
+		r.Labels = map[string]int{*gerritLabel: vote}
+	}
+	return r
+}
+
+// packageDir returns the directory of pkg relative to the root of
+// module.
+func packageDir(pkg, module string) (string, bool) {
+	switch {
+	case len(module) < 1:
+		return "", false
+	case pkg == module:
+		return "", true
+	case strings.HasPrefix(pkg, module+"/"):
+		return strings.TrimPrefix(pkg, module+"/"), true
+	}
+	return "", false
+}
+
+// modulePath reads the module path from the go.mod of the working
+// directory, if there is one.
+func modulePath() string {
+	f, err := os.Open("go.mod")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+func postGerritReview(ti *report.TestInfo, server, change, revision string) error {
+	if len(change) < 1 {
+		return usageError("-gerrit needs -gerrit-change")
+	}
+	if len(revision) < 1 {
+		revision = "current"
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return usageError(fmt.Sprintf("-gerrit: %v", err))
+	}
+	user, pass := os.Getenv("GERRIT_USER"), os.Getenv("GERRIT_HTTP_PASSWORD")
+	if u.User != nil {
+		user = u.User.Username()
+		pass, _ = u.User.Password()
+		u.User = nil
+	}
+	// Authenticated REST calls go under /a/.
+	prefix := "/"
+	if len(user) > 0 {
+		prefix = "/a/"
+	}
+	endpoint := u.Scheme + "://" + u.Host + strings.TrimSuffix(u.EscapedPath(), "/") + prefix +
+		"changes/" + url.PathEscape(change) + "/revisions/" + url.PathEscape(revision) + "/review"
+	body, err := json.Marshal(gerritReviewOf(ti, modulePath()))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(user) > 0 {
+		req.SetBasicAuth(user, pass)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("gerrit: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+func TestGerritReviewFiles(t *testing.T) {
+	ti, err := report.Parse(strings.NewReader(`{"Action":"run","Package":"example.com/lib/a","Test":"TestA"}
+{"Action":"output","Package":"example.com/lib/a","Test":"TestA","Output":"    a_test.go:12: got 1\n"}
+{"Action":"fail","Package":"example.com/lib/a","Test":"TestA"}
+{"Action":"fail","Package":"example.com/lib/a"}
+{"Action":"run","Package":"example.com/app/b","Test":"TestB"}
+{"Action":"output","Package":"example.com/app/b","Test":"TestB","Output":"    b_test.go:3: got 2\n"}
+{"Action":"fail","Package":"example.com/app/b","Test":"TestB"}
+{"Action":"fail","Package":"example.com/app/b"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The module example.com/lib lives in libs/ of the repository, so
+	// its import paths do not match the layout.
+	ti.GroupModules([]*report.Module{{Path: "example.com/lib", Dir: "libs"}})
+	r := gerritReviewOf(ti, "example.com/app")
+	var files []string
+	for f := range r.RobotComments {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	if want := []string{"b/b_test.go", "libs/a/a_test.go"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("got comments on %v, want %v", files, want)
+	}
+}
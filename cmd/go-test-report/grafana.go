@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/history"
+)
+
+// grafanaMetrics are the timeseries served to the Grafana JSON
+// datasource, by target name.
+var grafanaMetrics = map[string]func(run *history.Run) float64{
+	"pass_rate": (*history.Run).PassRate,
+	"duration":  func(run *history.Run) float64 { return run.Elapsed },
+	"failures":  func(run *history.Run) float64 { return float64(run.Fail) },
+	"tests":     func(run *history.Run) float64 { return float64(run.Total) },
+}
+
+var grafanaMetricNames = []string{"pass_rate", "duration", "failures", "tests"}
+
+// grafanaRoot answers the connection test of the Grafana JSON
+// datasource, whose URL is /grafana. /grafana/timeseries serves the
+// Infinity datasource.
+func (s *server) grafanaRoot(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/grafana/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// grafanaSearch lists the metrics. /search takes names, the /metrics of
+// newer datasource versions takes label and value pairs.
+func (s *server) grafanaSearch(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/grafana/search" {
+		writeJSON(w, grafanaMetricNames)
+		return
+	}
+	type metric struct {
+		Label string `json:"label"`
+		Value string `json:"value"`
+	}
+	list := make([]metric, len(grafanaMetricNames))
+	for i, name := range grafanaMetricNames {
+		list[i] = metric{Label: name, Value: name}
+	}
+	writeJSON(w, list)
+}
+
+type grafanaRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	Targets []struct {
+		Target string `json:"target"`
+		RefID  string `json:"refId"`
+		Hide   bool   `json:"hide"`
+		// Payload.Branch charts the runs of one branch, Payload.Race those
+		// with or without the race detector.
+		Payload struct {
+			Branch string `json:"branch"`
+			Race   *bool  `json:"race"`
+		} `json:"payload"`
+	} `json:"targets"`
+	MaxDataPoints int `json:"maxDataPoints"`
+}
+
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	RefID      string       `json:"refId,omitempty"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// grafanaQuery returns a datapoint per run in the requested range for
+// each target, as [value, unix milliseconds].
+func (s *server) grafanaQuery(w http.ResponseWriter, r *http.Request) {
+	var req grafanaRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		jsonError(w, err, http.StatusBadRequest)
+		return
+	}
+	all, err := history.Read(s.history)
+	if err != nil {
+		jsonError(w, err, http.StatusInternalServerError)
+		return
+	}
+	list := []*grafanaSeries{}
+	for _, t := range req.Targets {
+		metric := grafanaMetrics[t.Target]
+		if metric == nil || t.Hide {
+			continue
+		}
+		runs := history.FilterBranch(all, t.Payload.Branch)
+		if t.Payload.Race != nil {
+			runs = history.FilterRace(runs, *t.Payload.Race)
+		}
+		runs = runsBetween(runs, req.Range.From, req.Range.To)
+		if req.MaxDataPoints > 0 && len(runs) > req.MaxDataPoints {
+			runs = runs[len(runs)-req.MaxDataPoints:]
+		}
+		series := &grafanaSeries{Target: t.Target, RefID: t.RefID, Datapoints: [][2]float64{}}
+		for _, run := range runs {
+			series.Datapoints = append(series.Datapoints, [2]float64{metric(run), float64(run.Time.UnixNano() / int64(time.Millisecond))})
+		}
+		list = append(list, series)
+	}
+	writeJSON(w, list)
+}
+
+// grafanaTimeseries returns a row per run with every metric, oldest
+// first, optionally between the RFC 3339 times ?from= and ?to= and of
+// one ?branch=.
+func (s *server) grafanaTimeseries(w http.ResponseWriter, r *http.Request) {
+	var from, to time.Time
+	for name, t := range map[string]*time.Time{"from": &from, "to": &to} {
+		v := r.URL.Query().Get(name)
+		if len(v) < 1 {
+			continue
+		}
+		var err error
+		*t, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			jsonError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+	runs, err := s.runs(r)
+	if err != nil {
+		jsonError(w, err, http.StatusInternalServerError)
+		return
+	}
+	list := []map[string]interface{}{}
+	for _, run := range runsBetween(runs, from, to) {
+		row := map[string]interface{}{"time": run.Time, "id": run.ID, "branch": run.Branch, "commit": run.Commit, "race": run.Race}
+		for name, metric := range grafanaMetrics {
+			row[name] = metric(run)
+		}
+		list = append(list, row)
+	}
+	writeJSON(w, list)
+}
+
+// runsBetween returns the runs from from to to; zero times leave that end
+// open.
+func runsBetween(runs []*history.Run, from, to time.Time) []*history.Run {
+	var list []*history.Run
+	for _, run := range runs {
+		if (!from.IsZero() && run.Time.Before(from)) || (!to.IsZero() && run.Time.After(to)) {
+			continue
+		}
+		list = append(list, run)
+	}
+	return list
+}
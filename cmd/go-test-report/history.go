@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/history"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/render"
+)
+
+var (
+	historyPath = flag.String("history", "", "append this run to a JSON-lines history file")
+	branchName  = flag.String("branch", envFirst("GITHUB_HEAD_REF", "GITHUB_REF_NAME", "CI_COMMIT_REF_NAME", "GIT_BRANCH"), "branch recorded in the history")
+	commitID    = flag.String("commit", envFirst("GITHUB_SHA", "CI_COMMIT_SHA", "GIT_COMMIT"), "commit recorded in the history")
+	driftFactor = flag.Float64("drift-factor", 2, "flag tests slower than this factor of their historical median duration")
+	driftMin    = flag.Duration("drift-min", 100*time.Millisecond, "ignore drift of tests faster than this")
+	historyKeep = flag.Int("history-keep", 0, "after appending to -history, keep only the latest `n` runs of every branch; 0 keeps all")
+	historyAge  = flag.Duration("history-max-age", 0, "after appending to -history, drop the runs older than `d`, such as 720h; 0 keeps all")
+)
+
+func driftOptions() *history.Options {
+	return &history.Options{Commit: *commitID, DriftFactor: *driftFactor, DriftMin: *driftMin}
+}
+
+func envFirst(keys ...string) string {
+	for _, k := range keys {
+		if v := os.Getenv(k); len(v) > 0 {
+			return v
+		}
+	}
+	return ""
+}
+
+var historyCommands = map[string]func(runs []*history.Run, args []string) error{
+	"runs":         historyRuns,
+	"test":         historyTestCmd,
+	"first-failed": historyFirstFailed,
+	"drift":        historyDrift,
+	"flaky":        historyFlaky,
+	"branches":     historyBranches,
+}
+
+func runHistory(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	path := fs.String("history", "", "history file")
+	branch := fs.String("branch", "", "only consider runs of this branch")
+	fs.Var(&statusSymbols, "symbols", symbolsUsage)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-test-report history -history runs.jsonl [-branch name] runs|first-failed|drift|flaky|test <package> <test>|branches <a> <b>|prune [-keep n] [-max-age d]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	cmd, ok := historyCommands[fs.Arg(0)]
+	if fs.Arg(0) == "prune" {
+		cmd, ok = func(runs []*history.Run, args []string) error {
+			return historyPrune(*path, *branch, args)
+		}, true
+	}
+	if len(*path) < 1 || !ok {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	runs, err := history.Read(*path)
+	if err != nil {
+		fatal(exitInput, err)
+	}
+	err = cmd(history.FilterBranch(runs, *branch), fs.Args()[1:])
+	if err != nil {
+		fatal(exitInput, err)
+	}
+}
+
+func historyRuns(runs []*history.Run, _ []string) error {
+	return writeRuns(os.Stdout, runs)
+}
+
+func writeRuns(w io.Writer, runs []*history.Run) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tBRANCH\tCOMMIT\tTOTAL\tPASS\tFAIL\tSKIP\tPASS RATE\tDURATION")
+	for _, r := range runs {
+		fmt.Fprintf(tw, "%s\t%s\t%.12s\t%d\t%d\t%d\t%d\t%.1f%%\t%s\n", r.ID, r.Branch, r.Commit, r.Total, r.Pass, r.Fail, r.Skip,
+			r.PassRate(), time.Duration(r.Elapsed*float64(time.Second)).Round(time.Millisecond))
+	}
+	return tw.Flush()
+}
+
+func historyTestCmd(runs []*history.Run, args []string) error {
+	if len(args) != 2 {
+		return usageError("usage: history test <package> <test>")
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tBRANCH\tCOMMIT\tACTION\tDURATION")
+	for _, r := range runs {
+		t := r.Test(args[0], args[1])
+		if t == nil {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.12s\t%s\t%s\n", r.ID, r.Branch, r.Commit, render.StatusSymbols.Status(t.Action), time.Duration(t.Elapsed*float64(time.Second)))
+	}
+	return tw.Flush()
+}
+
+// historyFirstFailed lists the failures of the latest run with the run
+// and commit where each of them started failing.
+func historyFirstFailed(runs []*history.Run, _ []string) error {
+	if len(runs) < 1 {
+		return errors.New("history is empty")
+	}
+	last := runs[len(runs)-1]
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tTEST\tFIRST FAILED\tCOMMIT\tRUNS")
+	for _, t := range last.Tests {
+		if t.Action != events.ActionFail {
+			continue
+		}
+		first := history.FirstFailed(runs, t.Package, t.Test)
+		streak := 0
+		for i := len(runs) - 1; runs[i] != first; i-- {
+			streak++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.12s\t%d\n", t.Package, t.Test, first.ID, first.Commit, streak+1)
+	}
+	return tw.Flush()
+}
+
+// historyDrift lists the tests of the latest run that were slower than
+// the median of the runs before it.
+func historyDrift(runs []*history.Run, _ []string) error {
+	if len(runs) < 1 {
+		return errors.New("history is empty")
+	}
+	last, prev := runs[len(runs)-1], runs[:len(runs)-1]
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tTEST\tDURATION\tMEDIAN\tFACTOR")
+	for _, t := range last.Tests {
+		if t.Action != events.ActionPass {
+			continue
+		}
+		med, ok := history.MedianElapsed(prev, t.Package, t.Test)
+		if !ok || !driftOptions().Drifted(t.Elapsed, med) {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%.1fx\n", t.Package, t.Test, time.Duration(t.Elapsed*float64(time.Second)),
+			time.Duration(med*float64(time.Second)), t.Elapsed/med)
+	}
+	return tw.Flush()
+}
+
+func historyFlaky(runs []*history.Run, _ []string) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tTEST\tRUNS\tFAILS\tFLIPS\tSCORE")
+	for _, f := range history.FlakyRanking(runs) {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%.2f\n", f.Package, f.Test, f.Runs, f.Fails, f.Flips, f.Score)
+	}
+	return tw.Flush()
+}
+
+func historyBranches(runs []*history.Run, args []string) error {
+	if len(args) != 2 {
+		return usageError("usage: history branches <branch-a> <branch-b>")
+	}
+	c, err := history.CompareBranches(runs, args[0], args[1])
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "PACKAGE\tTEST\t%s\t%s\n", c.A, c.B)
+	for _, list := range [][]*history.BranchTest{c.FailOnA, c.FailOnB} {
+		for _, e := range list {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Package, e.Test, e.A, e.B)
+		}
+	}
+	return tw.Flush()
+}
+
+// historyPrune rewrites the history file at path without the runs the
+// retention flags of args drop; with branch set, only its runs are pruned.
+func historyPrune(path, branch string, args []string) error {
+	fs := flag.NewFlagSet("history prune", flag.ExitOnError)
+	keep := fs.Int("keep", 0, "keep only the latest `n` runs of every branch; 0 keeps all")
+	maxAge := fs.Duration("max-age", 0, "drop the runs older than `d`, such as 720h; 0 keeps all")
+	_ = fs.Parse(args)
+	if *keep < 0 || *maxAge < 0 || (*keep == 0 && *maxAge == 0) || fs.NArg() > 0 {
+		return usageError("usage: history prune [-keep n] [-max-age d], with at least one of them")
+	}
+	n, err := pruneHistory(path, history.Retention{PerBranch: *keep, MaxAge: *maxAge, Branch: branch})
+	if err != nil {
+		fatal(exitOutput, err)
+	}
+	fmt.Printf("pruned %d runs\n", n)
+	return nil
+}
+
+// pruneHistory applies r to the history file at path and returns the
+// number of runs dropped.
+func pruneHistory(path string, r history.Retention) (int, error) {
+	return history.PruneFile(path, r, time.Now())
+}
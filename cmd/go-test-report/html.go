@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/render"
+)
+
+var (
+	htmlAssets = flag.String("html-assets", "", "write the stylesheet and script of the html format to `dir` and link them instead of inlining them")
+	htmlCSS    = flag.String("html-css", "", "add the stylesheet at `path` to the html format, after the default styles")
+	htmlLogo   = flag.String("html-logo", "", "show the image at `path or URL` in the header of the html format; files are inlined")
+	lang       = flag.String("lang", "en", "language of the html format: "+strings.Join(render.Languages(), ", "))
+)
+
+// htmlOptions returns the renderer of the html format with the -lang,
+// -html-css and -html-logo options applied.
+func htmlOptions() (render.HTMLRenderer, error) {
+	known := false
+	for _, l := range render.Languages() {
+		known = known || l == *lang
+	}
+	if !known {
+		return render.HTMLRenderer{}, usageError(fmt.Sprintf("unknown -lang %q", *lang))
+	}
+	h := render.HTMLRenderer{Lang: *lang}
+	if len(*htmlCSS) > 0 {
+		b, err := ioutil.ReadFile(*htmlCSS)
+		if err != nil {
+			return h, err
+		}
+		h.Stylesheet = string(b)
+	}
+	if len(*htmlLogo) > 0 {
+		logo, err := logoURL(*htmlLogo)
+		if err != nil {
+			return h, err
+		}
+		h.Logo = logo
+	}
+	return h, nil
+}
+
+// htmlRenderer returns the renderer of the html format for reports in
+// dir, and the paths of the assets it wrote for them.
+func htmlRenderer(dir string) (render.Renderer, []string, error) {
+	h, err := htmlOptions()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(*htmlAssets) < 1 {
+		return h, nil, nil
+	}
+	paths, err := render.WriteAssets(*htmlAssets)
+	if err != nil {
+		return nil, nil, err
+	}
+	assets, err := filepath.Abs(*htmlAssets)
+	if err != nil {
+		return nil, nil, err
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	rel, err := filepath.Rel(dir, assets)
+	if err != nil {
+		return nil, nil, err
+	}
+	h.AssetURL = filepath.ToSlash(rel)
+	return h, paths, nil
+}
+
+// logoURL returns s if it is a URL, or else the file s as a data URL.
+func logoURL(s string) (string, error) {
+	for _, scheme := range []string{"http:", "https:", "data:"} {
+		if strings.HasPrefix(s, scheme) {
+			return s, nil
+		}
+	}
+	b, err := ioutil.ReadFile(s)
+	if err != nil {
+		return "", err
+	}
+	typ := mime.TypeByExtension(filepath.Ext(s))
+	if len(typ) < 1 {
+		typ = http.DetectContentType(b)
+	}
+	return "data:" + typ + ";base64," + base64.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+type inputList []string
+
+func (l *inputList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *inputList) Set(path string) error {
+	*l = append(*l, path)
+	return nil
+}
+
+var inputs inputList
+
+func init() {
+	flag.Var(&inputs, "input", "read the go test -json stream from `path` instead of stdin; repeatable, the later files being reruns of the failed tests, and tests that failed and then passed are marked flaky. To combine the logs of test shards, use go-test-report merge")
+}
+
+// openInput opens the first -input, or stdin, and returns its name for
+// errors.
+func openInput() (io.ReadCloser, string, error) {
+	if len(inputs) < 1 {
+		return io.NopCloser(os.Stdin), "stdin", nil
+	}
+	f, err := os.Open(inputs[0])
+	return f, inputs[0], err
+}
+
+// mergeReruns folds the streams of the -input files after the first
+// into ti as reruns.
+func mergeReruns(ctx context.Context, ti *report.TestInfo) error {
+	if len(inputs) < 2 {
+		return nil
+	}
+	for _, path := range inputs[1:] {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		re, err := report.ParseContext(ctx, f, parseOptions())
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if re.Incomplete {
+			ti.Incomplete = true
+			return nil
+		}
+		ti.MergeRerun(re)
+		re.Close()
+	}
+	return nil
+}
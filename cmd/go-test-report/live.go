@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/render"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var liveAddr = flag.String("live", "", "serve an html page on `addr` that reloads as packages finish while reading stdin, and the final report until interrupted; with watch, after every run")
+
+// liveRefresh is the least time between two renders of the live page.
+const liveRefresh = 2 * time.Second
+
+// liveServer serves the html report of the packages finished so far. The
+// page is rendered on the parsing goroutine, so it never reads packages
+// being changed.
+type liveServer struct {
+	h    render.HTMLRenderer
+	time time.Time
+	pkgs []*report.TestPkg
+	last time.Time
+
+	mu      sync.Mutex
+	page    []byte
+	done    bool
+	clients map[chan bool]bool
+}
+
+// live is the server of -live, if any.
+var live *liveServer
+
+func startLive(addr string) (*liveServer, error) {
+	h, err := htmlOptions()
+	if err != nil {
+		return nil, err
+	}
+	h.Live = "/events"
+	l := &liveServer{h: h, time: time.Now(), clients: map[chan bool]bool{}}
+	l.render()
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", l.handlePage)
+	mux.HandleFunc("/events", l.handleEvents)
+	go func() {
+		log.Println(http.Serve(ln, mux))
+	}()
+	log.Printf("serving the live report on http://%s/", ln.Addr())
+	return l, nil
+}
+
+// packageDone adds tp to the page, rendering it at most every
+// liveRefresh.
+func (l *liveServer) packageDone(tp *report.TestPkg) {
+	l.pkgs = append(l.pkgs, tp)
+	if time.Since(l.last) >= liveRefresh {
+		l.render()
+	}
+}
+
+func (l *liveServer) render() {
+	ti := &report.TestInfo{TpList: l.pkgs, Time: &l.time, Count: &report.Count{}}
+	ti.SetCount()
+	l.publish(l.h, ti, false)
+	l.last = time.Now()
+}
+
+// update replaces the page with ti, which is still live.
+func (l *liveServer) update(ti *report.TestInfo) {
+	l.publish(l.h, ti, false)
+}
+
+// finish replaces the page with the final report ti.
+func (l *liveServer) finish(ti *report.TestInfo) {
+	h := l.h
+	h.Live = ""
+	l.publish(h, ti, true)
+}
+
+func (l *liveServer) publish(h render.HTMLRenderer, ti *report.TestInfo, done bool) {
+	b := &bytes.Buffer{}
+	err := h.Render(b, ti)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.page, l.done = b.Bytes(), done
+	for c := range l.clients {
+		select {
+		case c <- done:
+		default:
+		}
+	}
+}
+
+// wait blocks until SIGINT or SIGTERM.
+func (l *liveServer) wait() {
+	log.Println("serving the final report until interrupted")
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	<-c
+}
+
+func (l *liveServer) handlePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	l.mu.Lock()
+	page := l.page
+	l.mu.Unlock()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(page)
+}
+
+// handleEvents sends a message for every new page and a done event for
+// the final one.
+func (l *liveServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	c := make(chan bool, 1)
+	l.mu.Lock()
+	if l.done {
+		c <- true
+	}
+	l.clients[c] = true
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		delete(l.clients, c)
+		l.mu.Unlock()
+	}()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	f.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case done := <-c:
+			if done {
+				fmt.Fprint(w, "event: done\ndata:\n\n")
+				f.Flush()
+				return
+			}
+			fmt.Fprint(w, "data: reload\n\n")
+			f.Flush()
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/render"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var lowMemory = flag.Bool("low-memory", false, "write packages to the xml report as they finish instead of keeping the whole run in memory; cannot be combined with -split, -baseline, -history, -rerun-file, -rerun-cmd, -plugin, -pushgateway, -textfile-dir, -publish, -gerrit, -digest, -test-timeout, -coverprofile, -slow-threshold, -init-panic-test, -timestamps or -live")
+
+// generateStream writes the report of r while it is being read. Only
+// -fail-on any and the thresholds are supported, since regressions need
+// the whole report.
+func generateStream(r io.Reader, name string) {
+	checkFailOn()
+	if *format != "xml" {
+		fatal(exitUsage, usageError("-low-memory only supports -format xml"))
+	}
+	layout, err := xmlRenderer()
+	tree := layout.Tree
+	layout.Tree = false
+	if err != nil || !layout.Default() {
+		fatal(exitUsage, usageError("-low-memory only writes the default xml layout"))
+	}
+	if *split {
+		fatal(exitUsage, usageError("-low-memory cannot be combined with -split"))
+	}
+	if len(inputs) > 1 {
+		fatal(exitUsage, usageError("-low-memory reads a single -input"))
+	}
+	if len(*liveAddr) > 0 {
+		fatal(exitUsage, usageError("-low-memory cannot be combined with -live"))
+	}
+	if len(*baselinePath) > 0 || len(*historyPath) > 0 || len(*rerunFile) > 0 || len(*rerunCmd) > 0 || len(plugins) > 0 || len(*pushGateway) > 0 || len(*textfileDir) > 0 || len(*publishURL) > 0 || len(*gerritURL) > 0 || *digest || *testTimeout > 0 || len(*coverProfile) > 0 || *slowFlag > 0 || *initPanics || *timestamps != "absolute" {
+		fatal(exitUsage, usageError("-low-memory only writes the report"))
+	}
+	path, err := reportPath()
+	if err != nil {
+		fatal(exitOutput, err)
+	}
+	out := os.Stdout
+	if len(path) > 0 {
+		f, err := os.Create(path)
+		if err != nil {
+			fatal(exitOutput, err)
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	s, err := render.NewXMLStream(w)
+	if err != nil {
+		fatal(exitOutput, err)
+	}
+	defer s.Discard()
+	s.Tree = tree
+	var writeErr error
+	p := report.NewParser()
+	p.Release = true
+	opts := parseOptions()
+	p.OutputBudget = opts.OutputBudget
+	p.Malformed = opts.Malformed
+	p.Filter = opts.Filter
+	span := &runSpan{}
+	pkgFailed := false
+	p.OnPackageDone = func(tp *report.TestPkg) {
+		span.add(tp)
+		pkgFailed = pkgFailed || tp.BuildFailed || tp.Action == events.ActionFail
+		if writeErr == nil {
+			writeErr = s.Package(tp)
+		}
+	}
+	if streaming() {
+		streamHooks(p, os.Stderr)
+	}
+	ctx, stop := interruptContext()
+	ti, err := p.ParseContext(ctx, r)
+	stop()
+	if err != nil {
+		// fatal skips the deferred calls.
+		s.Discard()
+		fatal(exitInput, fmt.Errorf("%s: %w", name, err))
+	}
+	ti.Race = ti.Race || *raceFlag
+	if t, ok := sourceDateEpoch(); ok {
+		ti.Time = &t
+	}
+	if writeErr == nil {
+		writeErr = s.Close(ti)
+	}
+	ti.Close()
+	if writeErr == nil {
+		writeErr = w.Flush()
+	}
+	if writeErr != nil {
+		s.Discard()
+		fatal(exitOutput, writeErr)
+	}
+	logIncomplete(ti)
+	if ti.MalformedLines > 0 {
+		log.Printf("%d lines of the stream were not JSON", ti.MalformedLines)
+	}
+	if len(path) > 0 {
+		log.Println(path)
+	}
+	code := 0
+	count := s.Count()
+	if *failOn == "any" && (count.Fail > 0 || pkgFailed) {
+		code = exitFailed
+	}
+	for _, m := range missedThresholds(&count, span.duration()) {
+		log.Println(m)
+		code = exitFailed
+	}
+	if code != 0 {
+		os.Exit(code)
+	}
+}
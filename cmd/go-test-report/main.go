@@ -0,0 +1,498 @@
+// Command go-test-report turns the output of go test -json into a report.
+//
+// Exit codes:
+//
+//	0  success
+//	1  tests or packages failed according to -fail-on, a threshold such as
+//	   -min-pass-rate or an objective was missed
+//	2  invalid arguments
+//	3  the test stream, a report or the history could not be read
+//	4  a report, the history or a plugin could not be written or run
+//	5  diff and compare found no new failures but tests that got slower
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/history"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/render"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var (
+	baselinePath = flag.String("baseline", "", "previous report (xml) to compare this run against")
+	benchSlower  = flag.Float64("bench-threshold", 10, "with -baseline, flag benchmarks whose ns/op grew by more than this `percent`")
+	failOn       = flag.String("fail-on", "any", "exit non-zero on failed tests and packages, including those that did not build (any), on regressions against -baseline only (new), or never (none)")
+	format       = flag.String("format", "xml", "report format: "+strings.Join(render.Names(), ", "))
+	workers      = flag.Int("workers", runtime.NumCPU(), "aggregate packages on up to `n` goroutines")
+	malformed    = flag.String("malformed", "capture", "lines of the stream that are not JSON, or events of unknown actions: capture them into the package output, skip them, or error")
+	outputMemory = flag.Int64("output-memory", 0, "keep at most `MiB` of test output in memory and spill the rest to temporary files; 0 means no limit")
+	timeout      = flag.Duration("timeout", 0, "stop reading tests after `d` and write a partial report; 0 means no limit. In run mode this covers go test and its reruns, and go test's own -timeout goes after --")
+	outputDir    = flag.String("output-dir", "", "write the report to `dir`/cov.<format> instead of stdout")
+	outPath      = flag.String("o", "", "write the report to `path` instead of stdout, or to stdout if -")
+	split        = flag.Bool("split", false, "write one report per package to a directory named after the report, plus an index")
+	manifest     = flag.Bool("manifest", false, "write the SHA-256 sums of the generated report files to <report>.sha256")
+	signKey      = flag.String("sign-key", "", "sign the manifest with the PKCS#8 PEM private `key` (ed25519, ECDSA or RSA) into <report>.sha256.sig; implies -manifest")
+	digest       = flag.Bool("digest", false, "also print one line per package with its result, counts, duration and coverage to stdout, or to stderr when the report goes to stdout")
+	raceFlag     = flag.Bool("race", false, "mark the report as a run with the race detector; in run mode it is taken from the go test flags")
+	initPanics   = flag.Bool("init-panic-test", false, "report a panic while a package initializes as a failed test named init")
+	slowFlag     = flag.Duration("slow-threshold", 0, "mark tests that took longer than `d` as slow; 0 means none")
+	slowest      = flag.Int("slowest", 10, "list the `n` slowest tests of the run in the report; not with -low-memory")
+)
+
+const (
+	exitFailed = 1
+	exitUsage  = 2
+	exitInput  = 3
+	exitOutput = 4
+)
+
+// usageError is an error caused by invalid arguments.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// fatal logs err and exits with code, or with exitUsage if err is a
+// usageError.
+func fatal(code int, err error) {
+	var ue usageError
+	if errors.As(err, &ue) {
+		code = exitUsage
+	}
+	log.Println(err)
+	os.Exit(code)
+}
+
+// parseArgs parses args with fs, allowing flags after the positional
+// arguments, and returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var pos []string
+	for {
+		_ = fs.Parse(args)
+		if fs.NArg() < 1 {
+			return pos
+		}
+		pos = append(pos, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+var commands = map[string]func(args []string){
+	"convert":  runConvert,
+	"diff":     runDiff,
+	"compare":  runCompare,
+	"history":  runHistory,
+	"merge":    runMerge,
+	"serve":    runServe,
+	"run":      runRun,
+	"slo":      runSlo,
+	"summary":  runSummary,
+	"validate": runValidate,
+	"watch":    runWatch,
+	"shard":    runShard,
+	"schema":   runSchema,
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			cmd(os.Args[2:])
+			return
+		}
+	}
+	flag.Parse()
+	if len(*signKey) > 0 {
+		key, err := readSignKey(*signKey)
+		if err != nil {
+			fatal(exitInput, err)
+		}
+		manifestKey = key
+	}
+	in, name, err := openInput()
+	if err != nil {
+		fatal(exitInput, err)
+	}
+	defer in.Close()
+	if *lowMemory {
+		generateStream(in, name)
+		return
+	}
+	startPublisher()
+	ctx, stop := interruptContext()
+	var ti *report.TestInfo
+	if len(*liveAddr) > 0 || *publishTests || streaming() {
+		ti, err = parseHooked(ctx, in)
+	} else {
+		ti, err = report.ParseContext(ctx, in, parseOptions())
+	}
+	if err != nil {
+		fatal(exitInput, fmt.Errorf("%s: %w", name, err))
+	}
+	if !ti.Incomplete {
+		err = mergeReruns(ctx, ti)
+	}
+	stop()
+	if err != nil {
+		fatal(exitInput, err)
+	}
+	generate(ti)
+}
+
+var malformedPolicies = map[string]report.MalformedPolicy{
+	"capture": report.MalformedCapture,
+	"skip":    report.MalformedSkip,
+	"error":   report.MalformedError,
+}
+
+func parseOptions() report.ParseOptions {
+	policy, ok := malformedPolicies[*malformed]
+	if !ok {
+		fatal(exitUsage, usageError(fmt.Sprintf("unknown -malformed %q", *malformed)))
+	}
+	return report.ParseOptions{Workers: *workers, OutputBudget: *outputMemory << 20, Malformed: policy, Filter: parseFilter()}
+}
+
+// parseHooked parses r on one goroutine, so that -live, -publish-tests
+// and -stream see tests and packages as they finish.
+func parseHooked(ctx context.Context, r io.Reader) (*report.TestInfo, error) {
+	opts := parseOptions()
+	p := report.NewParser()
+	p.OutputBudget = opts.OutputBudget
+	p.Malformed = opts.Malformed
+	p.Filter = opts.Filter
+	if len(*liveAddr) > 0 {
+		l, err := startLive(*liveAddr)
+		if err != nil {
+			fatal(exitOutput, err)
+		}
+		live = l
+		p.OnPackageDone = l.packageDone
+	}
+	if *publishTests {
+		p.OnTestEnd = publishTest
+	}
+	if streaming() {
+		streamHooks(p, os.Stderr)
+	}
+	return p.ParseContext(ctx, r)
+}
+
+// startPublisher connects to the broker of -publish before any test is
+// read.
+func startPublisher() {
+	if *publishTests && len(*publishURL) < 1 {
+		fatal(exitUsage, usageError("-publish-tests needs -publish"))
+	}
+	if len(*publishURL) < 1 {
+		return
+	}
+	p, err := openPublisher(*publishURL)
+	if err != nil {
+		fatal(exitOutput, err)
+	}
+	pub = p
+}
+
+// deadline is when -timeout is exceeded, if set.
+var deadline time.Time
+
+// interruptContext returns a context that is cancelled on the first
+// SIGINT or SIGTERM, or once -timeout has passed, so a partial report can
+// still be written. Once stop is called, signals terminate the program
+// again.
+func interruptContext() (ctx context.Context, stop func()) {
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if *timeout <= 0 {
+		return ctx, stopSignals
+	}
+	deadline = time.Now().Add(*timeout)
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	return ctx, func() {
+		cancel()
+		stopSignals()
+	}
+}
+
+// generate annotates ti, writes it and exits according to -fail-on and
+// the thresholds.
+func generate(ti *report.TestInfo) {
+	checkFailOn()
+	mode := timestampMode()
+	ti.Race = ti.Race || *raceFlag
+	if len(*baselinePath) > 0 {
+		base, err := report.Read(*baselinePath)
+		if err != nil {
+			fatal(exitInput, err)
+		}
+		ti.ApplyBaseline(base, parseFilter())
+		ti.CompareBenchmarks(base, *benchSlower)
+		logSlowerBenchmarks(ti)
+	}
+	if len(*historyPath) > 0 {
+		ti.RunID = history.RunID(time.Now())
+		runs, err := history.Read(*historyPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			fatal(exitInput, err)
+		}
+		runs = history.FilterRace(runs, ti.Race)
+		ti.Branch, ti.Trend = *branchName, history.Trend(runs, *branchName)
+		history.Annotate(ti, history.FilterBranch(runs, *branchName), driftOptions())
+	}
+	if len(*modulesFlag) > 0 {
+		modules, err := moduleList()
+		if err != nil {
+			fatal(exitInput, err)
+		}
+		ti.GroupModules(modules)
+	}
+	applyTimeout(ti)
+	ti.MarkSlow(*slowFlag)
+	ti.SetSlowest(*slowest)
+	err := applyCoverProfile(ti)
+	if err != nil {
+		fatal(exitInput, err)
+	}
+	if *initPanics {
+		ti.AddInitPanics()
+	}
+	ti.SetCount()
+	logIncomplete(ti)
+	if ti.MalformedLines > 0 {
+		log.Printf("%d lines of the stream were not JSON", ti.MalformedLines)
+	}
+	applyTimestamps(ti, mode)
+	path, err := writeReport(ti)
+	if err != nil {
+		fatal(exitOutput, err)
+	}
+	if *digest {
+		w := os.Stdout
+		if len(path) < 1 {
+			// Keep the report on stdout well-formed.
+			w = os.Stderr
+		}
+		err := render.Digest(w, ti)
+		if err != nil {
+			fatal(exitOutput, err)
+		}
+	}
+	if len(*historyPath) > 0 {
+		err := history.Append(*historyPath, history.NewRun(ti, *branchName, *commitID, path))
+		if err != nil {
+			fatal(exitOutput, err)
+		}
+		if *historyKeep > 0 || *historyAge > 0 {
+			_, err = pruneHistory(*historyPath, history.Retention{PerBranch: *historyKeep, MaxAge: *historyAge})
+			if err != nil {
+				fatal(exitOutput, err)
+			}
+		}
+	}
+	if len(*rerunFile) > 0 || len(*rerunCmd) > 0 {
+		err := writeRerun(ti, *rerunFile, *rerunCmd)
+		if err != nil {
+			fatal(exitOutput, err)
+		}
+	}
+	err = runPlugins(ti)
+	if err != nil {
+		fatal(exitOutput, err)
+	}
+	if len(*pushGateway) > 0 {
+		err := pushMetrics(ti, *pushGateway, *pushJob, *branchName)
+		if err != nil {
+			fatal(exitOutput, err)
+		}
+	}
+	if len(*textfileDir) > 0 {
+		err := writeTextfile(ti, *textfileDir, *pushJob)
+		if err != nil {
+			fatal(exitOutput, err)
+		}
+	}
+	if len(*gerritURL) > 0 {
+		err := postGerritReview(ti, *gerritURL, *gerritChange, *gerritRevision)
+		if err != nil {
+			fatal(exitOutput, err)
+		}
+	}
+	if pub != nil {
+		err := publishRun(ti, path)
+		if err != nil {
+			fatal(exitOutput, err)
+		}
+	}
+	if live != nil {
+		live.finish(ti)
+	}
+	ti.Close()
+	code := failOnCode(ti, *failOn)
+	span := &runSpan{}
+	for _, tp := range ti.TpList {
+		span.add(tp)
+	}
+	for _, m := range missedThresholds(ti.Count, span.duration()) {
+		log.Println(m)
+		code = exitFailed
+	}
+	if live != nil {
+		live.wait()
+	}
+	if code != 0 {
+		os.Exit(code)
+	}
+}
+
+// failOnCode is the exit code the -fail-on policy gives the report ti.
+func failOnCode(ti *report.TestInfo, policy string) int {
+	switch policy {
+	case "any":
+		if ti.Fail > 0 || packageFailed(ti) {
+			return exitFailed
+		}
+	case "new":
+		if ti.Regressions > 0 {
+			return exitFailed
+		}
+	}
+	return 0
+}
+
+// packageFailed reports whether a package of ti failed, including those
+// that did not compile and those that failed without a failed test, as
+// on a panic in init or TestMain exiting non-zero.
+func packageFailed(ti *report.TestInfo) bool {
+	for _, tp := range ti.TpList {
+		if tp.BuildFailed || tp.Action == events.ActionFail {
+			return true
+		}
+	}
+	return false
+}
+
+// logSlowerBenchmarks lists the benchmarks -bench-threshold flagged.
+func logSlowerBenchmarks(ti *report.TestInfo) {
+	for _, tp := range ti.TpList {
+		for _, b := range tp.Benchmarks {
+			if b.Slower {
+				log.Printf("%s %s: %.4g ns/op, %+.1f%% against the baseline", tp.Package, b.Name, b.NsPerOp, b.Change)
+			}
+		}
+	}
+}
+
+// logIncomplete says why ti is partial, if it is.
+func logIncomplete(ti *report.TestInfo) {
+	if !ti.Incomplete {
+		return
+	}
+	if !deadline.IsZero() && !time.Now().Before(deadline) {
+		log.Printf("-timeout %v exceeded, writing a partial report", *timeout)
+		return
+	}
+	log.Println("interrupted, writing a partial report")
+}
+
+// writeReport renders ti in -format and returns the path written, or
+// "" if it went to stdout.
+func writeReport(ti *report.TestInfo) (string, error) {
+	if _, ok := render.Lookup(*format); !ok {
+		return "", usageError(fmt.Sprintf("unknown format %q", *format))
+	}
+	path, err := reportPath()
+	if err != nil {
+		return "", err
+	}
+	if len(path) < 1 {
+		return "", writeStdout(ti)
+	}
+	dir, ext := filepath.Dir(path), filepath.Ext(path)
+	if *split {
+		dir = strings.TrimSuffix(path, ext)
+	}
+	r, files, err := formatRenderer(*format, dir)
+	if err != nil {
+		return "", err
+	}
+	if *split {
+		paths, err := render.WriteSplit(dir, ext, r, ti)
+		if err != nil {
+			return "", err
+		}
+		path, files = paths[len(paths)-1], append(files, paths...)
+	} else {
+		err = render.WriteFile(path, r, ti)
+		if err != nil {
+			return "", err
+		}
+		files = append(files, path)
+	}
+	if *manifest || len(*signKey) > 0 {
+		err = writeManifest(path+".sha256", files)
+		if err != nil {
+			return "", err
+		}
+	}
+	log.Println(path)
+	return path, nil
+}
+
+// writeStdout renders ti in -format to stdout.
+func writeStdout(ti *report.TestInfo) error {
+	if *split {
+		return usageError("-split writes files: give -o or -output-dir")
+	}
+	if *manifest || len(*signKey) > 0 {
+		return usageError("-manifest sums files: give -o or -output-dir")
+	}
+	r, _, err := formatRenderer(*format, ".")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(os.Stdout)
+	err = r.Render(w, ti)
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// reportStdout reports whether the report goes to stdout: with -o -, or
+// without -o and -output-dir.
+func reportStdout() bool {
+	return *outPath == "-" || len(*outPath) < 1 && len(*outputDir) < 1
+}
+
+// reportPath returns the absolute path of the report file, creating its
+// directory, or "" if the report goes to stdout. It is absolute since it
+// is recorded in the history.
+func reportPath() (string, error) {
+	if reportStdout() {
+		return "", nil
+	}
+	path := *outPath
+	if len(path) < 1 {
+		path = filepath.Join(*outputDir, "cov."+*format)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return path, os.MkdirAll(filepath.Dir(path), os.ModePerm)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/history"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+func TestSloPassRate(t *testing.T) {
+	runs := []*history.Run{
+		{Total: 3, Pass: 1, Bench: 2},
+		// A run of a package with only benchmarks.
+		{Total: 2, Bench: 2},
+	}
+	if got := sloMetrics["pass-rate"](runs); got != 100 {
+		t.Errorf("got pass rate %v, want 100", got)
+	}
+}
+
+func TestFailOnCode(t *testing.T) {
+	pkg := func(action string, c report.Count, build bool) *report.TestPkg {
+		tp := &report.TestPkg{TestUt: &report.TestUt{}, Count: &c, BuildFailed: build}
+		tp.Package, tp.Action = "example.com/a", action
+		return tp
+	}
+	for _, tc := range []struct {
+		name   string
+		policy string
+		tp     *report.TestPkg
+		want   int
+	}{
+		{"passed", "any", pkg(events.ActionPass, report.Count{Pass: 1}, false), 0},
+		{"failed test", "any", pkg(events.ActionFail, report.Count{Fail: 1}, false), exitFailed},
+		{"build failed", "any", pkg(events.ActionFail, report.Count{}, true), exitFailed},
+		// A panic in init or TestMain exiting non-zero fail the
+		// package without a failed test.
+		{"package failed", "any", pkg(events.ActionFail, report.Count{Pass: 1}, false), exitFailed},
+		{"no regression", "new", pkg(events.ActionFail, report.Count{Fail: 1}, false), 0},
+		{"regression", "new", pkg(events.ActionFail, report.Count{Fail: 1, Regressions: 1}, false), exitFailed},
+		{"none", "none", pkg(events.ActionFail, report.Count{Fail: 1}, true), 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ti := &report.TestInfo{TpList: []*report.TestPkg{tc.tp}, Count: &report.Count{}}
+			ti.SetCount()
+			if got := failOnCode(ti, tc.policy); got != tc.want {
+				t.Errorf("got exit code %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
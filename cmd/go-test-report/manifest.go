@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// manifestKey is the key read from -sign-key.
+var manifestKey crypto.Signer
+
+// writeManifest writes the SHA-256 sums of files to path in the format of
+// sha256sum, with names relative to the directory of path, and signs it
+// with manifestKey, if any, into path.sig.
+func writeManifest(path string, files []string) error {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	b := &bytes.Buffer{}
+	for _, file := range files {
+		sum, err := sha256File(file)
+		if err != nil {
+			return err
+		}
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, abs)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "%x  %s\n", sum, filepath.ToSlash(name))
+	}
+	err = ioutil.WriteFile(path, b.Bytes(), 0666)
+	if err != nil || manifestKey == nil {
+		return err
+	}
+	sig, err := sign(manifestKey, b.Bytes())
+	if err != nil {
+		return fmt.Errorf("%s: %w", *signKey, err)
+	}
+	return ioutil.WriteFile(path+".sig", sig, 0666)
+}
+
+func sha256File(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// readSignKey reads a PKCS#8 private key in PEM.
+func readSignKey(path string) (crypto.Signer, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+	if block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s: want a PKCS#8 PRIVATE KEY, found %s", path, block.Type)
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key, ok := k.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: %T cannot sign", path, k)
+	}
+	return key, nil
+}
+
+// sign signs msg as openssl does: ed25519 over msg itself, other keys over
+// its SHA-256 digest.
+func sign(key crypto.Signer, msg []byte) ([]byte, error) {
+	if _, ok := key.(ed25519.PrivateKey); ok {
+		return key.Sign(rand.Reader, msg, crypto.Hash(0))
+	}
+	sum := sha256.Sum256(msg)
+	return key.Sign(rand.Reader, sum[:], crypto.SHA256)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/render"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var mergeModes = map[string]report.MergeMode{
+	"union": report.MergeUnion,
+	"last":  report.MergeLast,
+}
+
+func runMerge(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	duplicates := fs.String("duplicates", "union", "packages found in several reports: union keeps the tests of all of them and the later result of a test, last keeps the package of the last report")
+	to := fs.String("format", "xml", "format of the merged report: "+strings.Join(render.Names(), ", "))
+	out := fs.String("o", "", "write to `path` instead of stdout")
+	for _, name := range []string{"xml-output", "xml-compact", "xml-empty", "indent", "subtests", "xml-root", "xml-namespace", "xml-root-attr"} {
+		f := flag.Lookup(name)
+		fs.Var(f.Value, f.Name, f.Usage)
+	}
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-test-report merge [-duplicates union|last] [-o merged.xml] a.xml shard2.json ...\n\nMerges reports written with -format xml or json and go test -json logs, such as\nthose of test shards, in the order given. Every package records the input it\nwas read from as its shard.")
+		fs.PrintDefaults()
+	}
+	inputs := parseArgs(fs, args)
+	if len(inputs) < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	mode, ok := mergeModes[*duplicates]
+	if !ok {
+		fatal(exitUsage, usageError(fmt.Sprintf("-duplicates %q: want union or last", *duplicates)))
+	}
+	dir := "."
+	if len(*out) > 0 {
+		dir = filepath.Dir(*out)
+	}
+	r, _, err := formatRenderer(*to, dir)
+	if err != nil {
+		fatal(exitUsage, err)
+	}
+	var reports []*report.TestInfo
+	for _, path := range inputs {
+		ti, err := readMergeInput(path)
+		if err != nil {
+			fatal(exitInput, err)
+		}
+		for _, tp := range ti.TpList {
+			if len(tp.Shard) < 1 {
+				tp.Shard = path
+			}
+		}
+		reports = append(reports, ti)
+	}
+	ti := report.Merge(mode, reports...)
+	defer ti.Close()
+	if len(*out) > 0 {
+		err = render.WriteFile(*out, r, ti)
+	} else {
+		w := bufio.NewWriter(os.Stdout)
+		err = r.Render(w, ti)
+		if err == nil {
+			err = w.Flush()
+		}
+	}
+	if err != nil {
+		fatal(exitOutput, err)
+	}
+}
+
+// readMergeInput reads the report or go test -json log at path.
+func readMergeInput(path string) (*report.TestInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReaderSize(f, 64<<10)
+	if !isTestLog(r) {
+		return report.Read(path)
+	}
+	ti, err := report.ParseContext(context.Background(), r, parseOptions())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	ti.SetCount()
+	return ti, nil
+}
+
+// isTestLog tells a go test -json log from a report by its first line
+// that is not blank: reports start with an XML declaration or a JSON
+// object that is not an event, while logs start with an event or with
+// what go printed before the tests ran.
+func isTestLog(r *bufio.Reader) bool {
+	b, _ := r.Peek(r.Size())
+	for _, l := range bytes.Split(b, []byte("\n")) {
+		l = bytes.TrimSpace(l)
+		if len(l) < 1 {
+			continue
+		}
+		switch l[0] {
+		case '<':
+			return false
+		case '{':
+			_, err := (&events.Line{Text: l}).Decode()
+			return err == nil
+		}
+		return true
+	}
+	return false
+}
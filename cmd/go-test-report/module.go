@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var modulesFlag = flag.String("modules", "", "group packages by module: comma-separated module paths, each optionally followed by =dir, its directory in the repository, or auto to ask go list -m in the current directory, the default in run mode")
+
+// moduleList returns the modules of -modules. Without a directory, a
+// module is taken to be at the root of the repository.
+func moduleList() ([]*report.Module, error) {
+	if *modulesFlag != "auto" {
+		var list []*report.Module
+		for _, m := range strings.Split(*modulesFlag, ",") {
+			if m = strings.TrimSpace(m); len(m) < 1 {
+				continue
+			}
+			mod := &report.Module{Path: m}
+			if i := strings.Index(m, "="); i >= 0 {
+				mod.Path, mod.Dir = m[:i], filepath.ToSlash(filepath.Clean(m[i+1:]))
+			}
+			list = append(list, mod)
+		}
+		return list, nil
+	}
+	// go list -m lists every module of a go.work workspace.
+	cmd := exec.Command("go", "list", "-m", "-f", "{{.Path}}\t{{.Dir}}")
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list -m: %w", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	var list []*report.Module
+	for _, line := range strings.Split(string(bytes.TrimSpace(out)), "\n") {
+		fields := strings.SplitN(line, "\t", 2)
+		mod := &report.Module{Path: fields[0]}
+		if len(fields) == 2 && len(fields[1]) > 0 {
+			if rel, err := filepath.Rel(wd, fields[1]); err == nil {
+				mod.Dir = filepath.ToSlash(rel)
+			}
+		}
+		list = append(list, mod)
+	}
+	return list, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/render"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+type pluginList []string
+
+func (l *pluginList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *pluginList) Set(path string) error {
+	*l = append(*l, path)
+	return nil
+}
+
+var plugins pluginList
+
+func init() {
+	flag.Var(&plugins, "plugin", "run the executable at `path` with the report as JSON on its stdin; repeatable")
+}
+
+// runPlugins pipes the JSON form of ti to every -plugin executable in
+// turn. Their output goes to our stdout and stderr.
+func runPlugins(ti *report.TestInfo) error {
+	if len(plugins) < 1 {
+		return nil
+	}
+	b := &bytes.Buffer{}
+	err := render.JSON(b, ti)
+	if err != nil {
+		return err
+	}
+	for _, path := range plugins {
+		cmd := exec.Command(path)
+		cmd.Stdin = bytes.NewReader(b.Bytes())
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err := cmd.Run()
+		if err != nil {
+			return fmt.Errorf("plugin %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/history"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var (
+	publishURL   = flag.String("publish", "", "publish the run summary to nats://[user:pass@]host:4222/subject, or to a Kafka topic through the REST proxy at kafka+http://host:8082/topic")
+	publishTests = flag.Bool("publish-tests", false, "with -publish, also publish every test as it finishes while reading stdin")
+)
+
+// publisher sends messages to a message broker.
+type publisher interface {
+	publish(msg []byte) error
+	// close delivers pending messages and disconnects.
+	close() error
+}
+
+// pub is the publisher of -publish, if any. Errors of messages sent
+// while parsing are kept in pubErr and reported with the summary.
+var (
+	pub    publisher
+	pubErr error
+)
+
+func openPublisher(rawURL string) (publisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, usageError(fmt.Sprintf("-publish: %v", err))
+	}
+	topic := strings.Trim(u.Path, "/")
+	if len(topic) < 1 {
+		return nil, usageError("-publish needs a subject or topic as its path")
+	}
+	switch u.Scheme {
+	case "nats":
+		return dialNATS(u, topic)
+	case "kafka+http", "kafka+https":
+		base := strings.TrimPrefix(u.Scheme, "kafka+") + "://" + u.Host
+		return &kafkaREST{url: base + "/topics/" + url.PathEscape(topic), user: u.User}, nil
+	}
+	return nil, usageError(fmt.Sprintf("-publish: unknown scheme %q", u.Scheme))
+}
+
+type testMessage struct {
+	Type   string     `json:"type"`
+	Time   *time.Time `json:"time,omitempty"`
+	Branch string     `json:"branch,omitempty"`
+	Commit string     `json:"commit,omitempty"`
+	*history.Test
+}
+
+// publishTest sends u once it has finished.
+func publishTest(tp *report.TestPkg, u *report.TestUt) {
+	if pubErr != nil {
+		return
+	}
+	msg, err := json.Marshal(&testMessage{
+		Type:   "test",
+		Time:   u.Time,
+		Branch: *branchName,
+		Commit: *commitID,
+		Test:   &history.Test{Package: tp.Package, Test: u.Test, Action: u.Action, Elapsed: u.Elapsed},
+	})
+	if err == nil {
+		err = pub.publish(msg)
+	}
+	pubErr = err
+}
+
+type runMessage struct {
+	Type string `json:"type"`
+	*history.Run
+}
+
+// publishRun sends the summary of ti, without its tests, and closes the
+// publisher.
+func publishRun(ti *report.TestInfo, path string) error {
+	run := history.NewRun(ti, *branchName, *commitID, path)
+	run.Tests = nil
+	msg, err := json.Marshal(&runMessage{Type: "run", Run: run})
+	if err == nil && pubErr == nil {
+		pubErr = pub.publish(msg)
+	}
+	if cerr := pub.close(); pubErr == nil {
+		pubErr = cerr
+	}
+	return pubErr
+}
+
+// natsConn publishes over the text protocol of NATS core. Once
+// connected, a reader answers the PINGs of the server, which drops
+// clients that leave them unanswered, for as long as the run lasts.
+type natsConn struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	subject string
+	// mu guards w and err, both shared with the reader.
+	mu sync.Mutex
+	w  *bufio.Writer
+	// err is the first -ERR of the server.
+	err   error
+	pongs chan struct{}
+	// done is closed once the reader stopped, with the reason in readErr.
+	done    chan struct{}
+	readErr error
+}
+
+func dialNATS(u *url.URL, subject string) (*natsConn, error) {
+	host := u.Host
+	if len(u.Port()) < 1 {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	conn, err := net.DialTimeout("tcp", host, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	c := &natsConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn), subject: subject,
+		pongs: make(chan struct{}, 1), done: make(chan struct{})}
+	// The server greets with INFO before anything else.
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, fmt.Errorf("nats: unexpected greeting %q", strings.TrimSpace(line))
+	}
+	opts := map[string]interface{}{"verbose": false, "pedantic": false, "name": "go-test-report", "lang": "go"}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			opts["user"], opts["pass"] = u.User.Username(), pass
+		} else {
+			opts["auth_token"] = u.User.Username()
+		}
+	}
+	connect, _ := json.Marshal(opts)
+	fmt.Fprintf(c.w, "CONNECT %s\r\n", connect)
+	err = c.handshake()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	go c.read()
+	return c, nil
+}
+
+// handshake sends CONNECT with a PING and waits for the server to
+// answer, so that authorization and protocol errors are seen.
+func (c *natsConn) handshake() error {
+	c.w.WriteString("PING\r\n")
+	err := c.w.Flush()
+	if err != nil {
+		return err
+	}
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			c.w.WriteString("PONG\r\n")
+		case strings.HasPrefix(line, "-ERR"):
+			return natsError(line)
+		}
+	}
+}
+
+func natsError(line string) error {
+	return fmt.Errorf("nats: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+}
+
+// read handles what the server sends until the connection is closed.
+func (c *natsConn) read() {
+	defer close(c.done)
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			c.readErr = err
+			return
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PING":
+			c.mu.Lock()
+			c.w.WriteString("PONG\r\n")
+			c.w.Flush()
+			c.mu.Unlock()
+		case line == "PONG":
+			select {
+			case c.pongs <- struct{}{}:
+			default:
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			c.mu.Lock()
+			if c.err == nil {
+				c.err = natsError(line)
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+// lost returns why the connection was lost, or nil.
+func (c *natsConn) lost() error {
+	select {
+	case <-c.done:
+		if c.err != nil {
+			return c.err
+		}
+		return fmt.Errorf("nats: connection lost: %v", c.readErr)
+	default:
+		return c.err
+	}
+}
+
+func (c *natsConn) publish(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.lost(); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.w, "PUB %s %d\r\n", c.subject, len(msg))
+	c.w.Write(msg)
+	_, err := c.w.WriteString("\r\n")
+	return err
+}
+
+// close flushes the messages and waits for the server to answer a PING,
+// which it does once it has processed them.
+func (c *natsConn) close() error {
+	c.mu.Lock()
+	err := c.lost()
+	if err == nil {
+		c.w.WriteString("PING\r\n")
+		err = c.w.Flush()
+	}
+	c.mu.Unlock()
+	if err == nil {
+		select {
+		case <-c.pongs:
+			c.mu.Lock()
+			err = c.err
+			c.mu.Unlock()
+		case <-c.done:
+			c.mu.Lock()
+			err = c.lost()
+			c.mu.Unlock()
+		case <-time.After(30 * time.Second):
+			err = errors.New("nats: no answer to PING")
+		}
+	}
+	if cerr := c.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// kafkaRESTBatch is the number of messages sent to the REST proxy in one
+// request.
+const kafkaRESTBatch = 500
+
+// kafkaREST produces to a topic through the Confluent REST proxy.
+type kafkaREST struct {
+	url     string
+	user    *url.Userinfo
+	pending []json.RawMessage
+}
+
+func (k *kafkaREST) publish(msg []byte) error {
+	k.pending = append(k.pending, json.RawMessage(msg))
+	if len(k.pending) < kafkaRESTBatch {
+		return nil
+	}
+	return k.flush()
+}
+
+func (k *kafkaREST) flush() error {
+	if len(k.pending) < 1 {
+		return nil
+	}
+	type record struct {
+		Value json.RawMessage `json:"value"`
+	}
+	records := make([]record, len(k.pending))
+	for i, msg := range k.pending {
+		records[i] = record{Value: msg}
+	}
+	k.pending = nil
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, k.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	if k.user != nil {
+		pass, _ := k.user.Password()
+		req.SetBasicAuth(k.user.Username(), pass)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("kafka rest proxy: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	// Records that failed are listed with an error in the offsets.
+	var res struct {
+		Offsets []struct {
+			Error string `json:"error"`
+		} `json:"offsets"`
+	}
+	if json.Unmarshal(msg, &res) == nil {
+		for _, o := range res.Offsets {
+			if len(o.Error) > 0 {
+				return errors.New("kafka rest proxy: " + o.Error)
+			}
+		}
+	}
+	return nil
+}
+
+func (k *kafkaREST) close() error {
+	return k.flush()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestNATSPing checks that the publisher answers the PINGs the server
+// sends while the run goes on.
+func TestNATSPing(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	ponged := make(chan bool, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			ponged <- false
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		conn.Write([]byte("INFO {}\r\n"))
+		pings, pong, pending := 0, false, false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				ponged <- pong
+				return
+			}
+			switch strings.TrimSpace(line) {
+			case "PING":
+				pings++
+				if pings == 1 {
+					// Answer the handshake, then ping the client.
+					conn.Write([]byte("PONG\r\nPING\r\n"))
+				} else if pong {
+					conn.Write([]byte("PONG\r\n"))
+				} else {
+					pending = true
+				}
+			case "PONG":
+				pong = true
+				if pending {
+					conn.Write([]byte("PONG\r\n"))
+				}
+			}
+		}
+	}()
+	c, err := dialNATS(&url.URL{Host: ln.Addr().String()}, "runs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.publish([]byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.close(); err != nil {
+		t.Fatal(err)
+	}
+	if !<-ponged {
+		t.Error("the PING of the server was not answered")
+	}
+}
+
+func TestPublishSloAlert(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		if r.URL.Path != "/topics/alerts" {
+			t.Errorf("got path %s", r.URL.Path)
+		}
+		io.WriteString(w, `{"offsets":[{}]}`)
+	}))
+	defer srv.Close()
+	alert := &sloAlert{Type: "slo", Window: "168h0m0s", Runs: 3, Violations: []*sloViolated{{SLO: "pass-rate>=99", Value: 97.5}}}
+	err := publishSloAlert("kafka+"+srv.URL+"/alerts", alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"records":[{"value":{"type":"slo","window":"168h0m0s","runs":3,"violations":[{"slo":"pass-rate\u003e=99","value":97.5}]}}]}`
+	if string(body) != want {
+		t.Errorf("got %s, want %s", body, want)
+	}
+}
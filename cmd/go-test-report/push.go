@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/render"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var (
+	pushGateway = flag.String("pushgateway", "", "push run metrics to this Prometheus Pushgateway URL")
+	pushJob     = flag.String("push-job", "go-test-report", "job label used for the Pushgateway, and name of the -textfile-dir file")
+	textfileDir = flag.String("textfile-dir", "", "write run metrics to `dir`/<push-job>.prom for the node_exporter textfile collector")
+)
+
+// groupingKey builds a Pushgateway grouping key path segment, switching to
+// the base64 form for values a URL path segment cannot hold.
+func groupingKey(name, value string) string {
+	if len(value) < 1 {
+		return "/" + name + "@base64/="
+	}
+	if strings.Contains(value, "/") {
+		return "/" + name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return "/" + name + "/" + url.PathEscape(value)
+}
+
+func pushMetrics(ti *report.TestInfo, gateway, job, branch string) error {
+	b := &bytes.Buffer{}
+	err := render.Metrics(b, ti)
+	if err != nil {
+		return err
+	}
+	u := strings.TrimSuffix(gateway, "/") + "/metrics" + groupingKey("job", job)
+	if len(branch) > 0 {
+		u += groupingKey("branch", branch)
+	}
+	req, err := http.NewRequest(http.MethodPut, u, b)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("pushgateway: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// writeTextfile writes the metrics of ti to dir/job.prom. The file is
+// renamed into place so the collector never reads it half written.
+func writeTextfile(ti *report.TestInfo, dir, job string) error {
+	f, err := ioutil.TempFile(dir, "."+job+".prom.*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	err = render.Metrics(f, ti)
+	if err == nil {
+		err = f.Chmod(0644)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(dir, job+".prom"))
+}
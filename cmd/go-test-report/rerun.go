@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var (
+	rerunFile = flag.String("rerun-file", "", "write the failed tests as one package and -run expression per line to this file")
+	rerunCmd  = flag.String("rerun-cmd", "", "write a shell script rerunning only the failed tests to this file")
+)
+
+func goTestCommand(pkg, pattern string) string {
+	if len(pattern) < 1 {
+		return "go test " + pkg
+	}
+	return fmt.Sprintf("go test %s -run '%s'", pkg, pattern)
+}
+
+// failedItems returns the failed top-level tests of ti. A failed subtest
+// is rerun through its parent and a package that failed without a failed
+// test, for example in TestMain or at build time, is rerun as a whole.
+func failedItems(ti *report.TestInfo) []*shardItem {
+	var items []*shardItem
+	for _, tp := range ti.TpList {
+		failed := false
+		for _, u := range tp.TEList {
+			if u.Action != events.ActionFail || strings.Contains(u.Test, "/") {
+				continue
+			}
+			failed = true
+			items = append(items, &shardItem{Package: tp.Package, Test: u.Test})
+		}
+		if !failed && (tp.Action == events.ActionFail || tp.Fail > 0) {
+			items = append(items, &shardItem{Package: tp.Package})
+		}
+	}
+	return items
+}
+
+func writeRerun(ti *report.TestInfo, patternPath, cmdPath string) error {
+	pkgs, patterns := runPatterns(failedItems(ti))
+	write := func(path string, perm os.FileMode, header, footer string, line func(pkg string) string) error {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+		if err != nil {
+			return err
+		}
+		w := bufio.NewWriter(f)
+		w.WriteString(header)
+		for _, pkg := range pkgs {
+			w.WriteString(line(pkg) + "\n")
+		}
+		w.WriteString(footer)
+		err = w.Flush()
+		if cErr := f.Close(); err == nil {
+			err = cErr
+		}
+		return err
+	}
+	if len(patternPath) > 0 {
+		err := write(patternPath, 0o644, "", "", func(pkg string) string {
+			return pkg + "\t" + patterns[pkg]
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(cmdPath) > 0 {
+		// The script is executable, so CI can run it directly.
+		err := write(cmdPath, 0o755, "#!/bin/sh\nstatus=0\n", "exit $status\n", func(pkg string) string {
+			return goTestCommand(pkg, patterns[pkg]) + " || status=1"
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
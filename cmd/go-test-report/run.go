@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// goTestValueFlags are the go test and build flags that take a separate
+// value argument, used to tell flags apart from package patterns.
+var goTestValueFlags = map[string]bool{
+	"bench": true, "benchtime": true, "blockprofile": true, "blockprofilerate": true, "count": true,
+	"coverpkg": true, "covermode": true, "coverprofile": true, "cpu": true, "cpuprofile": true,
+	"exec": true, "fuzz": true, "fuzzminimizetime": true, "fuzztime": true, "gccgoflags": true,
+	"gcflags": true, "list": true, "ldflags": true, "memprofile": true, "memprofilerate": true,
+	"mod": true, "modfile": true, "mutexprofile": true, "mutexprofilefraction": true, "o": true,
+	"outputdir": true, "overlay": true, "p": true, "parallel": true, "pkgdir": true, "run": true,
+	"shuffle": true, "skip": true, "tags": true, "timeout": true, "toolexec": true, "trace": true,
+	"vet": true, "C": true,
+}
+
+// splitGoTestArgs separates go test flags from package patterns.
+func splitGoTestArgs(args []string) (flags, pkgs []string) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if !strings.HasPrefix(a, "-") {
+			pkgs = append(pkgs, a)
+			continue
+		}
+		flags = append(flags, a)
+		name := strings.TrimLeft(a, "-")
+		if !strings.Contains(name, "=") && goTestValueFlags[name] && i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+	return flags, pkgs
+}
+
+// goTestRace reports whether the go test flags enable the race detector.
+func goTestRace(goFlags []string) bool {
+	race := false
+	for _, f := range goFlags {
+		switch strings.TrimLeft(f, "-") {
+		case "race", "race=true", "race=1":
+			race = true
+		case "race=false", "race=0":
+			race = false
+		}
+	}
+	return race
+}
+
+// goTest runs go test -json with args and parses its output. A non-zero
+// exit status caused by failing tests is not an error. When ctx is done,
+// go test is killed and the partial report returned. With -stream, the
+// tests are printed as they finish.
+func goTest(ctx context.Context, args []string) (*report.TestInfo, error) {
+	cmd := exec.Command("go", append([]string{"test", "-json"}, args...)...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+	var ti *report.TestInfo
+	var parseErr error
+	if streaming() {
+		opts := parseOptions()
+		p := report.NewParser()
+		p.OutputBudget = opts.OutputBudget
+		p.Malformed = opts.Malformed
+		p.Filter = opts.Filter
+		streamHooks(p, os.Stderr)
+		ti, parseErr = p.ParseContext(ctx, stdout)
+	} else {
+		ti, parseErr = report.ParseContext(ctx, stdout, parseOptions())
+	}
+	if parseErr != nil || ti.Incomplete {
+		_ = cmd.Process.Kill()
+	}
+	err = cmd.Wait()
+	if parseErr != nil {
+		return nil, fmt.Errorf("go test: %w", parseErr)
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) && !ti.Incomplete {
+		return nil, err
+	}
+	return ti, nil
+}
+
+// goTestFailed runs the tests that failed in the report at path, once per
+// package since every package needs its own -run expression.
+func goTestFailed(ctx context.Context, path string, goFlags []string) (*report.TestInfo, error) {
+	prev, err := report.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, patterns := runPatterns(failedItems(prev))
+	if len(pkgs) < 1 {
+		return nil, fmt.Errorf("%s: no failed tests", path)
+	}
+	var ti *report.TestInfo
+	for _, pkg := range pkgs {
+		args := append(append([]string(nil), goFlags...), pkg)
+		if len(patterns[pkg]) > 0 {
+			args = append(args, "-run", patterns[pkg])
+		}
+		re, err := goTest(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		if ti == nil {
+			ti = re
+		} else {
+			ti.AddPackages(re)
+			ti.Incomplete = re.Incomplete
+		}
+		if ti.Incomplete {
+			break
+		}
+	}
+	return ti, nil
+}
+
+func runRun(args []string) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		fs.Var(f.Value, f.Name, f.Usage)
+	})
+	rerunFails := fs.Int("rerun-fails", 0, "rerun failed tests up to `n` times, reporting tests that pass on a rerun as flaky")
+	failedFrom := fs.String("failed-from", "", "only run the tests that failed in this previous report (xml)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-test-report run [flags] [--] [go test flags] [packages]\n       go-test-report run -failed-from report.xml [flags] [--] [go test flags]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if len(*liveAddr) > 0 {
+		fatal(exitUsage, usageError("-live only reads stdin and cannot be used in run mode"))
+	}
+	if *publishTests {
+		fatal(exitUsage, usageError("-publish-tests only reads stdin and cannot be used in run mode"))
+	}
+	if len(*modulesFlag) < 1 {
+		*modulesFlag = "auto"
+	}
+	startPublisher()
+	goArgs := fs.Args()
+	goFlags, pkgs := splitGoTestArgs(goArgs)
+	if !*raceFlag {
+		*raceFlag = goTestRace(goFlags)
+	}
+	if len(*coverProfile) < 1 {
+		*coverProfile = goTestCoverProfile(goFlags)
+	}
+	if *testTimeout == 0 {
+		d, err := goTestTimeout(goFlags)
+		if err != nil {
+			fatal(exitUsage, err)
+		}
+		*testTimeout = d
+	}
+	if len(pkgs) < 1 {
+		goArgs = append(goArgs, "./...")
+	}
+	ctx, stop := interruptContext()
+	defer stop()
+	var ti *report.TestInfo
+	var err error
+	if len(*failedFrom) > 0 {
+		ti, err = goTestFailed(ctx, *failedFrom, goFlags)
+	} else {
+		ti, err = goTest(ctx, goArgs)
+	}
+	if err != nil {
+		fatal(exitInput, err)
+	}
+	for attempt := 0; attempt < *rerunFails && !ti.Incomplete; attempt++ {
+		pkgs, patterns := runPatterns(failedItems(ti))
+		if len(pkgs) < 1 {
+			break
+		}
+		for _, pkg := range pkgs {
+			rerunArgs := append(append([]string(nil), goFlags...), pkg)
+			if len(patterns[pkg]) > 0 {
+				rerunArgs = append(rerunArgs, "-run", patterns[pkg])
+			}
+			re, err := goTest(ctx, rerunArgs)
+			if err != nil {
+				fatal(exitInput, err)
+			}
+			if re.Incomplete {
+				ti.Incomplete = true
+				break
+			}
+			ti.MergeRerun(re)
+			re.Close()
+		}
+	}
+	stop()
+	generate(ti)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+func runSchema(args []string) {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-test-report schema\n\nPrints the JSON Schema of reports written with -format json.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	b, err := report.JSONSchema()
+	if err != nil {
+		fatal(exitOutput, err)
+	}
+	_, err = fmt.Printf("%s\n", b)
+	if err != nil {
+		fatal(exitOutput, err)
+	}
+}
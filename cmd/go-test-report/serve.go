@@ -0,0 +1,418 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/history"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/render"
+)
+
+type server struct {
+	history string
+}
+
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	path := fs.String("history", "", "history file")
+	listen := fs.String("listen", ":8080", "address to listen on")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-test-report serve -history runs.jsonl [-listen :8080]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if len(*path) < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	s := &server{history: *path}
+	log.Println("listening on", *listen)
+	fatal(exitOutput, http.ListenAndServe(*listen, s.handler()))
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleRuns)
+	mux.HandleFunc("/runs/", s.handleRun)
+	mux.HandleFunc("/test", s.handleTest)
+	mux.HandleFunc("/flaky", s.handleFlaky)
+	mux.HandleFunc("/compare", s.handleCompare)
+	mux.HandleFunc("/feed.atom", s.handleFeed)
+	mux.HandleFunc("/api/runs", s.apiRuns)
+	mux.HandleFunc("/api/runs/", s.apiRun)
+	mux.HandleFunc("/api/test", s.apiTest)
+	mux.HandleFunc("/api/flaky", s.apiFlaky)
+	mux.HandleFunc("/api/compare", s.apiCompare)
+	mux.HandleFunc("/grafana/", s.grafanaRoot)
+	mux.HandleFunc("/grafana/search", s.grafanaSearch)
+	mux.HandleFunc("/grafana/metrics", s.grafanaSearch)
+	mux.HandleFunc("/grafana/query", s.grafanaQuery)
+	mux.HandleFunc("/grafana/timeseries", s.grafanaTimeseries)
+	return mux
+}
+
+// runs reads the history again on every request so that runs appended by
+// CI jobs show up without restarting the server.
+func (s *server) runs(r *http.Request) ([]*history.Run, error) {
+	runs, err := history.Read(s.history)
+	if err != nil {
+		return nil, err
+	}
+	runs = history.FilterBranch(runs, r.URL.Query().Get("branch"))
+	if race, err := strconv.ParseBool(r.URL.Query().Get("race")); err == nil {
+		runs = history.FilterRace(runs, race)
+	}
+	return runs, nil
+}
+
+// compareRuns reads the history for a comparison of the branches a and b.
+// Race runs are kept apart as in runs; without a race parameter the runs
+// are compared in the mode of the latest run of a.
+func (s *server) compareRuns(r *http.Request) ([]*history.Run, error) {
+	runs, err := history.Read(s.history)
+	if err != nil {
+		return nil, err
+	}
+	race, err := strconv.ParseBool(r.URL.Query().Get("race"))
+	if err != nil {
+		latest := history.LatestRun(runs, r.URL.Query().Get("a"))
+		if latest == nil {
+			return runs, nil
+		}
+		race = latest.Race
+	}
+	return history.FilterRace(runs, race), nil
+}
+
+func (s *server) render(w http.ResponseWriter, name string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := dashboardTmpl.ExecuteTemplate(w, name, data)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (s *server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	runs, err := s.runs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	list := make([]*history.Run, 0, len(runs))
+	for i := len(runs) - 1; i >= 0; i-- {
+		list = append(list, runs[i])
+	}
+	branch := r.URL.Query().Get("branch")
+	charts, err := render.TrendCharts(history.Trend(runs, branch), branch)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.render(w, "runs", map[string]interface{}{"Runs": list, "Branch": branch, "Charts": charts})
+}
+
+func (s *server) findRun(r *http.Request, id string) (*history.Run, error) {
+	runs, err := s.runs(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		if run.ID == id {
+			return run, nil
+		}
+	}
+	return nil, nil
+}
+
+// handleRun serves /runs/{id} and /runs/{id}/report.
+func (s *server) handleRun(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/runs/")
+	download := strings.HasSuffix(id, "/report")
+	id = strings.TrimSuffix(id, "/report")
+	run, err := s.findRun(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if run == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if download {
+		if len(run.Report) < 1 {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", run.ID+filepath.Ext(run.Report)))
+		http.ServeFile(w, r, run.Report)
+		return
+	}
+	tests := append([]*history.Test(nil), run.Tests...)
+	sort.SliceStable(tests, func(i, j int) bool {
+		return tests[i].Action == events.ActionFail && tests[j].Action != events.ActionFail
+	})
+	s.render(w, "run", map[string]interface{}{"Run": run, "Tests": tests})
+}
+
+func (s *server) handleTest(w http.ResponseWriter, r *http.Request) {
+	runs, err := s.runs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	pkg, name := r.URL.Query().Get("pkg"), r.URL.Query().Get("test")
+	type row struct {
+		Run  *history.Run
+		Test *history.Test
+	}
+	var rows []row
+	for i := len(runs) - 1; i >= 0; i-- {
+		if t := runs[i].Test(pkg, name); t != nil {
+			rows = append(rows, row{Run: runs[i], Test: t})
+		}
+	}
+	s.render(w, "test", map[string]interface{}{"Package": pkg, "Test": name, "Rows": rows})
+}
+
+func (s *server) handleFlaky(w http.ResponseWriter, r *http.Request) {
+	runs, err := s.runs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.render(w, "flaky", map[string]interface{}{"Tests": history.FlakyRanking(runs), "Branch": r.URL.Query().Get("branch")})
+}
+
+func secondsString(elapsed float64) string {
+	return time.Duration(elapsed * float64(time.Second)).Round(time.Millisecond).String()
+}
+
+var dashboardTmpl = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"dur": secondsString,
+}).Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>go-test-report</title>
+<style>
+body{font-family:sans-serif;margin:0 2em;color:#222}
+nav{padding:1em 0;border-bottom:1px solid #ddd;margin-bottom:1em}
+nav a{margin-right:1em}
+table{border-collapse:collapse;width:100%}
+th,td{text-align:left;padding:4px 8px;border-bottom:1px solid #eee}
+.pass{color:#2a7d2a}.fail{color:#c0392b;font-weight:bold}.skip{color:#888}
+.charts{display:flex;flex-wrap:wrap;gap:2em;margin-bottom:1em}.charts figure{margin:0}
+.charts text{font-size:10px;fill:#222}.charts .pass{fill:#2a7d2a}.charts .axis{stroke:#ddd}
+.charts .trend{fill:none;stroke:#2a7d2a;stroke-width:2}
+</style>
+<link rel="alternate" type="application/atom+xml" title="New test failures" href="/feed.atom">
+</head><body>
+<nav><a href="/">Runs</a><a href="/flaky">Flaky tests</a><a href="/compare">Compare branches</a><a href="/feed.atom">Feed</a></nav>
+{{end}}
+{{define "foot"}}</body></html>{{end}}
+
+{{define "runs"}}{{template "head"}}
+<h1>Runs{{if .Branch}} on {{.Branch}}{{end}}</h1>
+{{.Charts}}
+<table><tr><th>Run</th><th>Branch</th><th>Commit</th><th>Total</th><th>Pass</th><th>Fail</th><th>Skip</th><th>Pass rate</th><th>Duration</th><th></th></tr>
+{{range .Runs}}<tr>
+<td><a href="/runs/{{.ID}}">{{.ID}}</a>{{if .Race}} <a href="/?race=1" title="race detector">race</a>{{end}}</td><td><a href="/?branch={{.Branch}}">{{.Branch}}</a></td><td>{{.Commit}}</td>
+<td>{{.Total}}</td><td class="pass">{{.Pass}}</td><td class="fail">{{.Fail}}</td><td class="skip">{{.Skip}}</td>
+<td>{{printf "%.1f%%" .PassRate}}</td><td>{{dur .Elapsed}}</td>
+<td>{{if .Report}}<a href="/runs/{{.ID}}/report">report</a>{{end}}</td>
+</tr>{{end}}
+</table>
+{{template "foot"}}{{end}}
+
+{{define "run"}}{{template "head"}}
+<h1>Run {{.Run.ID}}</h1>
+<p>Branch {{.Run.Branch}} · commit {{.Run.Commit}} · {{.Run.Time.Format "2006-01-02 15:04:05"}}{{if .Run.Race}} · race detector{{end}}
+{{if .Run.Report}} · <a href="/runs/{{.Run.ID}}/report">download report</a>{{end}}</p>
+<table><tr><th>Package</th><th>Test</th><th>Result</th><th>Duration</th></tr>
+{{range .Tests}}<tr>
+<td>{{.Package}}</td><td><a href="/test?pkg={{.Package}}&test={{.Test}}">{{.Test}}</a></td>
+<td class="{{.Action}}">{{.Action}}</td><td>{{dur .Elapsed}}</td>
+</tr>{{end}}
+</table>
+{{template "foot"}}{{end}}
+
+{{define "test"}}{{template "head"}}
+<h1>{{.Test}}</h1><p>{{.Package}}</p>
+<table><tr><th>Run</th><th>Branch</th><th>Commit</th><th>Result</th><th>Duration</th></tr>
+{{range .Rows}}<tr>
+<td><a href="/runs/{{.Run.ID}}">{{.Run.ID}}</a></td><td>{{.Run.Branch}}</td><td>{{.Run.Commit}}</td>
+<td class="{{.Test.Action}}">{{.Test.Action}}</td><td>{{dur .Test.Elapsed}}</td>
+</tr>{{end}}
+</table>
+{{template "foot"}}{{end}}
+
+{{define "flaky"}}{{template "head"}}
+<h1>Flaky tests{{if .Branch}} on {{.Branch}}{{end}}</h1>
+<table><tr><th>Package</th><th>Test</th><th>Runs</th><th>Fails</th><th>Flips</th><th>Score</th></tr>
+{{range .Tests}}<tr>
+<td>{{.Package}}</td><td><a href="/test?pkg={{.Package}}&test={{.Test}}">{{.Test}}</a></td>
+<td>{{.Runs}}</td><td>{{.Fails}}</td><td>{{.Flips}}</td><td>{{printf "%.2f" .Score}}</td>
+</tr>{{end}}
+</table>
+{{template "foot"}}{{end}}
+
+{{define "compare"}}{{template "head"}}
+<h1>Compare branches</h1>
+<form method="get">
+<select name="a">{{range .Branches}}<option{{if eq . $.A}} selected{{end}}>{{.}}</option>{{end}}</select>
+<select name="b">{{range .Branches}}<option{{if eq . $.B}} selected{{end}}>{{.}}</option>{{end}}</select>
+<button type="submit">Compare</button>
+</form>
+{{if .Error}}<p class="fail">{{.Error}}</p>{{end}}
+{{with .Comparison}}
+<p>Latest run of {{.A}}: <a href="/runs/{{.RunA.ID}}">{{.RunA.ID}}</a> · latest run of {{.B}}: <a href="/runs/{{.RunB.ID}}">{{.RunB.ID}}</a> · {{.Matching}} tests agree{{if .RunA.Race}} · race detector{{end}}</p>
+<h2>Failing on {{.A}}, passing on {{.B}}</h2>
+<table><tr><th>Package</th><th>Test</th></tr>
+{{range .FailOnA}}<tr><td>{{.Package}}</td><td><a href="/test?pkg={{.Package}}&test={{.Test}}">{{.Test}}</a></td></tr>{{end}}
+</table>
+<h2>Failing on {{.B}}, passing on {{.A}}</h2>
+<table><tr><th>Package</th><th>Test</th></tr>
+{{range .FailOnB}}<tr><td>{{.Package}}</td><td><a href="/test?pkg={{.Package}}&test={{.Test}}">{{.Test}}</a></td></tr>{{end}}
+</table>
+<h2>Only on {{.A}}</h2>
+<table><tr><th>Package</th><th>Test</th><th>Result</th></tr>
+{{range .OnlyOnA}}<tr><td>{{.Package}}</td><td>{{.Test}}</td><td class="{{.A}}">{{.A}}</td></tr>{{end}}
+</table>
+<h2>Only on {{.B}}</h2>
+<table><tr><th>Package</th><th>Test</th><th>Result</th></tr>
+{{range .OnlyOnB}}<tr><td>{{.Package}}</td><td>{{.Test}}</td><td class="{{.B}}">{{.B}}</td></tr>{{end}}
+</table>
+{{end}}
+{{template "foot"}}{{end}}
+`))
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(v)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func jsonError(w http.ResponseWriter, err error, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// apiRuns lists the runs, newest first, without their per-test results.
+func (s *server) apiRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := s.runs(r)
+	if err != nil {
+		jsonError(w, err, http.StatusInternalServerError)
+		return
+	}
+	list := make([]history.Run, 0, len(runs))
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := *runs[i]
+		run.Packages, run.Tests = nil, nil
+		list = append(list, run)
+	}
+	writeJSON(w, list)
+}
+
+func (s *server) apiRun(w http.ResponseWriter, r *http.Request) {
+	run, err := s.findRun(r, strings.TrimPrefix(r.URL.Path, "/api/runs/"))
+	if err != nil {
+		jsonError(w, err, http.StatusInternalServerError)
+		return
+	}
+	if run == nil {
+		jsonError(w, errors.New("run not found"), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, run)
+}
+
+type testRun struct {
+	ID     string    `json:"id"`
+	Time   time.Time `json:"time"`
+	Branch string    `json:"branch,omitempty"`
+	Commit string    `json:"commit,omitempty"`
+	*history.Test
+}
+
+func (s *server) apiTest(w http.ResponseWriter, r *http.Request) {
+	runs, err := s.runs(r)
+	if err != nil {
+		jsonError(w, err, http.StatusInternalServerError)
+		return
+	}
+	pkg, name := r.URL.Query().Get("pkg"), r.URL.Query().Get("test")
+	list := []*testRun{}
+	for i := len(runs) - 1; i >= 0; i-- {
+		if t := runs[i].Test(pkg, name); t != nil {
+			list = append(list, &testRun{ID: runs[i].ID, Time: runs[i].Time, Branch: runs[i].Branch, Commit: runs[i].Commit, Test: t})
+		}
+	}
+	writeJSON(w, list)
+}
+
+func (s *server) apiFlaky(w http.ResponseWriter, r *http.Request) {
+	runs, err := s.runs(r)
+	if err != nil {
+		jsonError(w, err, http.StatusInternalServerError)
+		return
+	}
+	list := history.FlakyRanking(runs)
+	if list == nil {
+		list = []*history.Flaky{}
+	}
+	writeJSON(w, list)
+}
+
+func (s *server) handleCompare(w http.ResponseWriter, r *http.Request) {
+	runs, err := s.compareRuns(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	q := r.URL.Query()
+	data := map[string]interface{}{"A": q.Get("a"), "B": q.Get("b"), "Branches": history.Branches(runs)}
+	if len(q.Get("a")) > 0 && len(q.Get("b")) > 0 {
+		c, err := history.CompareBranches(runs, q.Get("a"), q.Get("b"))
+		if err != nil {
+			data["Error"] = err.Error()
+		}
+		data["Comparison"] = c
+	}
+	s.render(w, "compare", data)
+}
+
+func (s *server) apiCompare(w http.ResponseWriter, r *http.Request) {
+	runs, err := s.compareRuns(r)
+	if err != nil {
+		jsonError(w, err, http.StatusInternalServerError)
+		return
+	}
+	c, err := history.CompareBranches(runs, r.URL.Query().Get("a"), r.URL.Query().Get("b"))
+	if err != nil {
+		jsonError(w, err, http.StatusNotFound)
+		return
+	}
+	a, b := *c.RunA, *c.RunB
+	a.Packages, a.Tests, b.Packages, b.Tests = nil, nil, nil, nil
+	c.RunA, c.RunB = &a, &b
+	writeJSON(w, c)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/history"
+)
+
+func TestAPICompareRace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.jsonl")
+	now := time.Now()
+	for i, r := range []*history.Run{
+		{ID: "1", Branch: "main"},
+		{ID: "2", Branch: "dev"},
+		{ID: "3", Branch: "main", Race: true},
+		{ID: "4", Branch: "dev", Race: true},
+		{ID: "5", Branch: "dev"},
+	} {
+		r.Time = now.Add(time.Duration(i) * time.Minute)
+		if err := history.Append(path, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &server{history: path}
+	for _, c := range []struct {
+		query, a, b string
+	}{
+		{"a=main&b=dev&race=false", "1", "5"},
+		{"a=main&b=dev&race=true", "3", "4"},
+		// The latest run of main used the race detector.
+		{"a=main&b=dev", "3", "4"},
+	} {
+		w := httptest.NewRecorder()
+		s.handler().ServeHTTP(w, httptest.NewRequest("GET", "/api/compare?"+c.query, nil))
+		var got history.BranchComparison
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: %v: %s", c.query, err, w.Body)
+		}
+		if got.RunA.ID != c.a || got.RunB.ID != c.b {
+			t.Errorf("%s: compared %s with %s, want %s with %s", c.query, got.RunA.ID, got.RunB.ID, c.a, c.b)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/history"
+)
+
+type shardItem struct {
+	Package string `json:"package"`
+	Test    string `json:"test,omitempty"`
+	// Skip is the -skip expression of an item without a test in -by test
+	// mode, which runs the tests of the package not placed in other
+	// shards, see setSkips.
+	Skip    string  `json:"skip,omitempty"`
+	Elapsed float64 `json:"elapsed"`
+}
+
+type shard struct {
+	Index   int          `json:"index"`
+	Elapsed float64      `json:"elapsed"`
+	Items   []*shardItem `json:"items"`
+}
+
+// packageDurations returns the median duration of every package of pkgs
+// over the last samples runs that contain it. Packages missing from the
+// history get the median duration of the others, so they are still
+// placed in a shard.
+func packageDurations(runs []*history.Run, pkgs []string, samples int) []*shardItem {
+	seen := map[string][]float64{}
+	var order []string
+	for i := len(runs) - 1; i >= 0; i-- {
+		for _, p := range runs[i].Packages {
+			list, ok := seen[p.Package]
+			if !ok {
+				order = append(order, p.Package)
+			}
+			if len(list) < samples {
+				seen[p.Package] = append(list, p.Elapsed)
+			}
+		}
+	}
+	current := make(map[string]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		current[pkg] = true
+	}
+	items := make([]*shardItem, 0, len(pkgs))
+	var known []float64
+	for _, pkg := range order {
+		if current[pkg] {
+			items = append(items, &shardItem{Package: pkg, Elapsed: history.Median(seen[pkg])})
+			known = append(known, items[len(items)-1].Elapsed)
+		}
+	}
+	median := history.Median(known)
+	for _, pkg := range pkgs {
+		if _, ok := seen[pkg]; !ok {
+			items = append(items, &shardItem{Package: pkg, Elapsed: median})
+		}
+	}
+	return items
+}
+
+// listPackages resolves the package patterns to import paths with go list,
+// ./... if there are none.
+func listPackages(patterns []string) ([]string, error) {
+	if len(patterns) < 1 {
+		patterns = []string{"./..."}
+	}
+	cmd := exec.Command("go", append([]string{"list", "-e"}, patterns...)...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %w", err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// testDurations is packageDurations for top-level tests; subtests are
+// left out because -run selects them through their parent. Every package
+// also gets an item without a test that runs the tests missing from the
+// history, so new tests run in some shard.
+func testDurations(runs []*history.Run, pkgs []string, samples int) []*shardItem {
+	current := make(map[string]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		current[pkg] = true
+	}
+	seen := map[string][]float64{}
+	var order []*shardItem
+	for i := len(runs) - 1; i >= 0; i-- {
+		for _, t := range runs[i].Tests {
+			if strings.Contains(t.Test, "/") || !current[t.Package] {
+				continue
+			}
+			key := t.Package + "\x00" + t.Test
+			list, ok := seen[key]
+			if !ok {
+				order = append(order, &shardItem{Package: t.Package, Test: t.Test})
+			}
+			if len(list) < samples {
+				seen[key] = append(list, t.Elapsed)
+			}
+		}
+	}
+	for _, item := range order {
+		item.Elapsed = history.Median(seen[item.Package+"\x00"+item.Test])
+	}
+	for _, pkg := range pkgs {
+		order = append(order, &shardItem{Package: pkg})
+	}
+	return order
+}
+
+// setSkips sets the -skip expression of the items without a test to the
+// tests of their package placed in other shards.
+func setSkips(shards []*shard) {
+	for _, s := range shards {
+		for _, rest := range s.Items {
+			if len(rest.Test) > 0 {
+				continue
+			}
+			var tests []string
+			for _, o := range shards {
+				if o == s {
+					continue
+				}
+				for _, item := range o.Items {
+					if item.Package == rest.Package && len(item.Test) > 0 {
+						tests = append(tests, regexp.QuoteMeta(item.Test))
+					}
+				}
+			}
+			if len(tests) > 0 {
+				sort.Strings(tests)
+				rest.Skip = "^(" + strings.Join(tests, "|") + ")$"
+			}
+		}
+	}
+}
+
+// balanceShards assigns the longest item to the least loaded shard until
+// all items are placed. Of equally loaded shards the one with the fewest
+// items is taken, which spreads items without a duration.
+func balanceShards(items []*shardItem, n int) []*shard {
+	sorted := append([]*shardItem(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Elapsed != sorted[j].Elapsed {
+			return sorted[i].Elapsed > sorted[j].Elapsed
+		}
+		if sorted[i].Package != sorted[j].Package {
+			return sorted[i].Package < sorted[j].Package
+		}
+		return sorted[i].Test < sorted[j].Test
+	})
+	shards := make([]*shard, n)
+	for i := range shards {
+		shards[i] = &shard{Index: i + 1, Items: []*shardItem{}}
+	}
+	for _, item := range sorted {
+		min := shards[0]
+		for _, s := range shards[1:] {
+			if s.Elapsed < min.Elapsed || s.Elapsed == min.Elapsed && len(s.Items) < len(min.Items) {
+				min = s
+			}
+		}
+		min.Items = append(min.Items, item)
+		min.Elapsed += item.Elapsed
+	}
+	return shards
+}
+
+// runPatterns groups the tests of items by package and returns a -run
+// regular expression for every package, in package order. Items without
+// a test select the whole package and get an empty expression, less the
+// tests of their Skip, see skipPatterns.
+func runPatterns(items []*shardItem) ([]string, map[string]string) {
+	tests := map[string][]string{}
+	whole := map[string]bool{}
+	var pkgs []string
+	for _, item := range items {
+		if _, ok := tests[item.Package]; !ok {
+			pkgs = append(pkgs, item.Package)
+		}
+		if len(item.Test) < 1 {
+			whole[item.Package] = true
+		}
+		tests[item.Package] = append(tests[item.Package], regexp.QuoteMeta(item.Test))
+	}
+	sort.Strings(pkgs)
+	patterns := make(map[string]string, len(pkgs))
+	for _, pkg := range pkgs {
+		if whole[pkg] {
+			patterns[pkg] = ""
+			continue
+		}
+		sort.Strings(tests[pkg])
+		patterns[pkg] = "^(" + strings.Join(tests[pkg], "|") + ")$"
+	}
+	return pkgs, patterns
+}
+
+// skipPatterns returns the -skip expression of every package of items
+// that has one.
+func skipPatterns(items []*shardItem) map[string]string {
+	skips := map[string]string{}
+	for _, item := range items {
+		if len(item.Test) < 1 && len(item.Skip) > 0 {
+			skips[item.Package] = item.Skip
+		}
+	}
+	return skips
+}
+
+// writeShard prints one shard in a form CI jobs consume directly: a
+// package per line, or a package and its -run expression per line,
+// followed by a -skip expression if the package has one.
+func (s *shard) writeShard(w io.Writer, byTest bool) error {
+	if !byTest {
+		for _, p := range s.Items {
+			if _, err := fmt.Fprintln(w, p.Package); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	pkgs, patterns := runPatterns(s.Items)
+	skips := skipPatterns(s.Items)
+	for _, pkg := range pkgs {
+		line := pkg + "\t" + patterns[pkg]
+		if skip, ok := skips[pkg]; ok {
+			line += "\t" + skip
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeShardsText(w io.Writer, shards []*shard, byTest bool) error {
+	for _, s := range shards {
+		if !byTest {
+			pkgs := make([]string, 0, len(s.Items))
+			for _, p := range s.Items {
+				pkgs = append(pkgs, p.Package)
+			}
+			if _, err := fmt.Fprintf(w, "shard %d (%s): %s\n", s.Index, secondsString(s.Elapsed), strings.Join(pkgs, " ")); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "shard %d (%s):\n", s.Index, secondsString(s.Elapsed)); err != nil {
+			return err
+		}
+		pkgs, patterns := runPatterns(s.Items)
+		skips := skipPatterns(s.Items)
+		for _, pkg := range pkgs {
+			cmd := goTestCommand(pkg, patterns[pkg])
+			if skip, ok := skips[pkg]; ok {
+				cmd += fmt.Sprintf(" -skip '%s'", skip)
+			}
+			if _, err := fmt.Fprintf(w, "\t%s\n", cmd); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func runShard(args []string) {
+	fs := flag.NewFlagSet("shard", flag.ExitOnError)
+	path := fs.String("history", "", "history file")
+	branch := fs.String("branch", "", "only consider runs of this branch")
+	n := fs.Int("n", 2, "number of shards")
+	samples := fs.Int("samples", 10, "number of recent runs used to estimate durations")
+	by := fs.String("by", "package", "balance whole packages or individual top-level tests: package or test")
+	index := fs.Int("index", 0, "print only shard `k` (1-based): one package per line, or package, -run expression and any -skip expression with -by test")
+	format := fs.String("format", "text", "output format: text or json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-test-report shard -history runs.jsonl -n 4 [-by package|test] [-index k] [packages]\n\nShards the packages go list finds for the package patterns, ./... by default.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if len(*path) < 1 || *n < 1 || *samples < 1 || *index < 0 || *index > *n || (*by != "package" && *by != "test") {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	runs, err := history.Read(*path)
+	if err != nil {
+		fatal(exitInput, err)
+	}
+	runs = history.FilterBranch(runs, *branch)
+	pkgs, err := listPackages(fs.Args())
+	if err != nil {
+		fatal(exitInput, err)
+	}
+	byTest := *by == "test"
+	items := packageDurations(runs, pkgs, *samples)
+	if byTest {
+		items = testDurations(runs, pkgs, *samples)
+	}
+	shards := balanceShards(items, *n)
+	if byTest {
+		setSkips(shards)
+	}
+	switch {
+	case *index > 0:
+		err = shards[*index-1].writeShard(os.Stdout, byTest)
+	case *format == "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(shards)
+	case *format == "text":
+		err = writeShardsText(os.Stdout, shards, byTest)
+	default:
+		err = usageError(fmt.Sprintf("unknown shard format %q", *format))
+	}
+	if err != nil {
+		fatal(exitOutput, err)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/history"
+)
+
+func TestPackageDurations(t *testing.T) {
+	runs := []*history.Run{{Packages: []*history.Pkg{
+		{Package: "a", Elapsed: 10},
+		{Package: "b", Elapsed: 2},
+		{Package: "c", Elapsed: 4},
+		{Package: "gone", Elapsed: 50},
+	}}}
+	// "new" is missing from the history and "gone" no longer exists.
+	items := packageDurations(runs, []string{"a", "b", "c", "new"}, 10)
+	got := map[string]float64{}
+	for _, item := range items {
+		got[item.Package] = item.Elapsed
+	}
+	want := map[string]float64{"a": 10, "b": 2, "c": 4, "new": 4}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for pkg, d := range want {
+		if got[pkg] != d {
+			t.Errorf("%s: got %v, want %v", pkg, got[pkg], d)
+		}
+	}
+	shards := balanceShards(items, 2)
+	placed := 0
+	for _, s := range shards {
+		placed += len(s.Items)
+	}
+	if placed != 4 {
+		t.Errorf("placed %d of 4 packages", placed)
+	}
+}
+
+func TestBalanceShardsWithoutHistory(t *testing.T) {
+	items := packageDurations(nil, []string{"a", "b", "c", "d"}, 10)
+	for _, s := range balanceShards(items, 2) {
+		if len(s.Items) != 2 {
+			t.Errorf("shard %d got %d packages, want 2", s.Index, len(s.Items))
+		}
+	}
+}
+
+func TestShardNewTests(t *testing.T) {
+	runs := []*history.Run{{Tests: []*history.Test{
+		{Package: "a", Test: "TestA", Elapsed: 4},
+		{Package: "a", Test: "TestB", Elapsed: 3},
+		{Package: "a", Test: "TestB/sub", Elapsed: 1},
+	}}}
+	items := testDurations(runs, []string{"a", "b"}, 10)
+	shards := balanceShards(items, 2)
+	setSkips(shards)
+	// Each shard runs one known test of a and the lighter one runs the rest
+	// of a, skipping the test of the other shard; b only has new tests.
+	var lines []string
+	for _, s := range shards {
+		pkgs, patterns := runPatterns(s.Items)
+		skips := skipPatterns(s.Items)
+		for _, pkg := range pkgs {
+			lines = append(lines, pkg+" "+patterns[pkg]+" "+skips[pkg])
+		}
+	}
+	sort.Strings(lines)
+	want := []string{"a  ^(TestA)$", "a ^(TestA)$ ", "b  "}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("got %q, want %q", lines, want)
+	}
+}
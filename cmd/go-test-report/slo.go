@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/history"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// slo is an objective such as "pass-rate>=99" evaluated over a time window.
+type slo struct {
+	spec   string
+	metric string
+	op     string
+	value  float64
+}
+
+type sloList []*slo
+
+func (l *sloList) String() string {
+	specs := make([]string, 0, len(*l))
+	for _, s := range *l {
+		specs = append(specs, s.spec)
+	}
+	return strings.Join(specs, ",")
+}
+
+func (l *sloList) Set(spec string) error {
+	s, err := parseSlo(spec)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, s)
+	return nil
+}
+
+var sloPattern = regexp.MustCompile(`^([a-z-]+)\s*(>=|<=|>|<)\s*(\S+)$`)
+
+// sloMetrics computes a metric over the runs of the window.
+var sloMetrics = map[string]func(runs []*history.Run) float64{
+	// pass-rate is the percentage of passed tests among all non-skipped tests of the window, leaving out benchmarks.
+	"pass-rate": func(runs []*history.Run) float64 {
+		c := &report.Count{}
+		for _, r := range runs {
+			c.Total += r.Total
+			c.Pass += r.Pass
+			c.Skip += r.Skip
+			c.Bench += r.Bench
+		}
+		return c.PassRate() * 100
+	},
+	// failures is the largest number of failed tests of a single run.
+	"failures": func(runs []*history.Run) float64 {
+		var n int
+		for _, r := range runs {
+			if r.Fail > n {
+				n = r.Fail
+			}
+		}
+		return float64(n)
+	},
+	// duration is the longest run, in seconds.
+	"duration": func(runs []*history.Run) float64 {
+		var d float64
+		for _, r := range runs {
+			if r.Elapsed > d {
+				d = r.Elapsed
+			}
+		}
+		return d
+	},
+}
+
+func parseSlo(spec string) (*slo, error) {
+	m := sloPattern.FindStringSubmatch(strings.TrimSpace(spec))
+	if m == nil {
+		return nil, fmt.Errorf("invalid slo %q, want <metric><op><value> such as pass-rate>=99", spec)
+	}
+	if _, ok := sloMetrics[m[1]]; !ok {
+		return nil, fmt.Errorf("unknown slo metric %q", m[1])
+	}
+	s := &slo{spec: spec, metric: m[1], op: m[2]}
+	var err error
+	if s.metric == "duration" {
+		var d time.Duration
+		d, err = time.ParseDuration(m[3])
+		s.value = d.Seconds()
+	} else {
+		s.value, err = strconv.ParseFloat(strings.TrimSuffix(m[3], "%"), 64)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid slo value %q: %w", m[3], err)
+	}
+	return s, nil
+}
+
+func (s *slo) met(v float64) bool {
+	switch s.op {
+	case ">=":
+		return v >= s.value
+	case "<=":
+		return v <= s.value
+	case ">":
+		return v > s.value
+	default:
+		return v < s.value
+	}
+}
+
+func (s *slo) format(v float64) string {
+	switch s.metric {
+	case "pass-rate":
+		return fmt.Sprintf("%.2f%%", v)
+	case "duration":
+		return secondsString(v)
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// sloAlert is the message -publish sends when objectives are violated.
+type sloAlert struct {
+	Type       string         `json:"type"`
+	Branch     string         `json:"branch,omitempty"`
+	Window     string         `json:"window"`
+	Runs       int            `json:"runs"`
+	Violations []*sloViolated `json:"violations"`
+}
+
+type sloViolated struct {
+	SLO   string  `json:"slo"`
+	Value float64 `json:"value"`
+}
+
+// publishSloAlert sends alert through the publisher of rawURL.
+func publishSloAlert(rawURL string, alert *sloAlert) error {
+	p, err := openPublisher(rawURL)
+	if err != nil {
+		return err
+	}
+	msg, err := json.Marshal(alert)
+	if err == nil {
+		err = p.publish(msg)
+	}
+	if cerr := p.close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// runSlo evaluates the objectives against the history and exits with 1
+// when any of them is violated, so a scheduled CI job can alert on it.
+// With -publish the violations are also sent to a message broker.
+func runSlo(args []string) {
+	fs := flag.NewFlagSet("slo", flag.ExitOnError)
+	path := fs.String("history", "", "history file")
+	branch := fs.String("branch", "", "only consider runs of this branch")
+	window := fs.Duration("window", 7*24*time.Hour, "evaluate runs newer than this")
+	publishTo := fs.String("publish", "", "publish an alert listing the violated objectives to a NATS subject or Kafka topic, in the URL form of -publish of the report")
+	var slos sloList
+	fs.Var(&slos, "slo", "objective `<metric><op><value>` over pass-rate, failures or duration; repeatable")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-test-report slo -history runs.jsonl [-branch nightly] [-window 168h] [-publish url] -slo 'pass-rate>=99' ...")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if len(*path) < 1 || len(slos) < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	runs, err := history.Read(*path)
+	if err != nil {
+		fatal(exitInput, err)
+	}
+	since := time.Now().Add(-*window)
+	var recent []*history.Run
+	for _, r := range history.FilterBranch(runs, *branch) {
+		if r.Time.After(since) {
+			recent = append(recent, r)
+		}
+	}
+	if len(recent) < 1 {
+		fatal(exitInput, fmt.Errorf("no runs in the last %s", *window))
+	}
+	alert := &sloAlert{Type: "slo", Branch: *branch, Window: window.String(), Runs: len(recent)}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "SLO\tVALUE\tRUNS\tSTATUS\n")
+	for _, s := range slos {
+		v := sloMetrics[s.metric](recent)
+		status := "ok"
+		if !s.met(v) {
+			status = "VIOLATED"
+			alert.Violations = append(alert.Violations, &sloViolated{SLO: s.spec, Value: v})
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", s.spec, s.format(v), len(recent), status)
+	}
+	err = tw.Flush()
+	if err != nil {
+		fatal(exitOutput, err)
+	}
+	if len(alert.Violations) < 1 {
+		return
+	}
+	if len(*publishTo) > 0 {
+		err = publishSloAlert(*publishTo, alert)
+		if err != nil {
+			fatal(exitOutput, err)
+		}
+	}
+	os.Exit(exitFailed)
+}
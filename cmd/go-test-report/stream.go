@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var streamMode = flag.String("stream", "", "while reading the tests, print to stderr a line per finished test and package (progress), the output of the tests as go test prints it without -json (output), or both (all)")
+
+// streamStatus spells out results in progress lines.
+var streamStatus = map[string]string{
+	events.ActionPass:  "PASS",
+	events.ActionFail:  "FAIL",
+	events.ActionSkip:  "SKIP",
+	events.ActionBench: "BENCH",
+}
+
+// streaming reports whether -stream is set, and rejects unknown modes.
+func streaming() bool {
+	switch *streamMode {
+	case "":
+		return false
+	case "progress", "output", "all":
+		return true
+	}
+	fatal(exitUsage, usageError(fmt.Sprintf("unknown -stream %q", *streamMode)))
+	return false
+}
+
+// streamHooks sets the hooks of p that print -stream to w, keeping the
+// ones already set.
+func streamHooks(p *report.Parser, w io.Writer) {
+	if *streamMode == "output" || *streamMode == "all" {
+		p.OnOutput = func(tp *report.TestPkg, u *report.TestUt, output string) {
+			_, _ = io.WriteString(w, output)
+		}
+	}
+	if *streamMode != "progress" && *streamMode != "all" {
+		return
+	}
+	testEnd := p.OnTestEnd
+	p.OnTestEnd = func(tp *report.TestPkg, u *report.TestUt) {
+		fmt.Fprintf(w, "--- %s %s %s (%s)\n", streamAction(u.Action), tp.Package, u.Test, secondsString(u.Elapsed))
+		if testEnd != nil {
+			testEnd(tp, u)
+		}
+	}
+	packageDone := p.OnPackageDone
+	p.OnPackageDone = func(tp *report.TestPkg) {
+		switch {
+		case tp.BuildFailed:
+			fmt.Fprintf(w, "=== FAIL %s: build failed\n", tp.Package)
+		case tp.NoTests:
+			fmt.Fprintf(w, "=== %s %s (%s): no tests to run\n", streamAction(tp.Action), tp.Package, secondsString(tp.Elapsed))
+		default:
+			fmt.Fprintf(w, "=== %s %s (%s): %d passed, %d failed, %d skipped\n", streamAction(tp.Action), tp.Package, secondsString(tp.Elapsed), tp.Pass, tp.Fail, tp.Skip)
+		}
+		if packageDone != nil {
+			packageDone(tp)
+		}
+	}
+}
+
+func streamAction(action string) string {
+	if s, ok := streamStatus[action]; ok {
+		return s
+	}
+	return "?"
+}
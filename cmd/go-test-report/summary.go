@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/render"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+func runSummary(args []string) {
+	fs := flag.NewFlagSet("summary", flag.ExitOnError)
+	slowest := fs.Int("slowest", 10, "list the `n` slowest tests")
+	fs.Var(&statusSymbols, "symbols", symbolsUsage)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-test-report summary [-slowest n] report.xml\n\nPrints the counts, slowest tests and failures of a report written with -format xml or json.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	ti, err := report.Read(fs.Arg(0))
+	if err != nil {
+		fatal(exitInput, err)
+	}
+	err = writeSummary(os.Stdout, ti, *slowest)
+	if err != nil {
+		fatal(exitOutput, err)
+	}
+}
+
+func writeSummary(w io.Writer, ti *report.TestInfo, slowest int) error {
+	var elapsed float64
+	var failed []*report.TestUt
+	for _, tp := range ti.TpList {
+		elapsed += tp.Elapsed
+		if tp.Action == events.ActionFail && tp.Fail < 1 {
+			failed = append(failed, tp.TestUt)
+		}
+		for _, u := range tp.TEList {
+			if u.Action == events.ActionFail {
+				failed = append(failed, u)
+			}
+		}
+	}
+	fmt.Fprintf(w, "%d tests: %d passed, %d failed, %d skipped, pass rate %.1f%%, %s\n", ti.Total, ti.Pass, ti.Fail, ti.Skip,
+		ti.PassRate()*100, time.Duration(elapsed*float64(time.Second)).Round(time.Millisecond))
+	if ti.Incomplete {
+		fmt.Fprintln(w, "The run was interrupted, the report is incomplete.")
+	}
+	ti.SetSlowest(slowest)
+	if tests := ti.Slowest; len(tests) > 0 {
+		fmt.Fprintln(w, "\nSlowest tests:")
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "PACKAGE\tTEST\tRESULT\tDURATION")
+		for _, u := range tests {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", u.Package, u.Test, render.StatusSymbols.Status(u.Action), time.Duration(u.Elapsed*float64(time.Second)).Round(time.Millisecond))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(failed) < 1 {
+		return nil
+	}
+	fmt.Fprintln(w, "\nFailures:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tTEST\tLOCATION\tMESSAGE")
+	for _, u := range failed {
+		test, where, msg := u.Test, "-", ""
+		if len(test) < 1 {
+			test = "-"
+		}
+		if locs := u.Locations(); len(locs) > 0 {
+			where = fmt.Sprintf("%s:%d", locs[0].File, locs[0].Line)
+			msg = strings.SplitN(locs[0].Message, "\n", 2)[0]
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", u.Package, test, where, msg)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/render"
+)
+
+const symbolsUsage = "`set` of symbols marking results in console, Markdown and AsciiDoc output: none, unicode, emoji, ascii, or a list like pass=ok,fail=FAIL,skip=--"
+
+// symbolsFlag sets render.StatusSymbols.
+type symbolsFlag string
+
+func (f *symbolsFlag) String() string {
+	return string(*f)
+}
+
+func (f *symbolsFlag) Set(s string) error {
+	sym, err := render.ParseSymbols(s)
+	if err != nil {
+		return err
+	}
+	render.StatusSymbols = sym
+	*f = symbolsFlag(s)
+	return nil
+}
+
+var statusSymbols = symbolsFlag("none")
+
+func init() {
+	flag.Var(&statusSymbols, "symbols", symbolsUsage)
+}
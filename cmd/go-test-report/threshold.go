@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var (
+	failOnSkip  = flag.Bool("fail-on-skip", false, "exit non-zero when a test was skipped")
+	minPassRate = flag.Float64("min-pass-rate", 0, "exit non-zero when less than this `percent` of the tests that ran passed; 0 means no minimum")
+	maxDuration = flag.Duration("max-duration", 0, "exit non-zero when the run took longer than `d`; 0 means no limit")
+)
+
+// checkFailOn rejects unknown -fail-on values before any work is done.
+func checkFailOn() {
+	switch *failOn {
+	case "none", "any", "new":
+	default:
+		fatal(exitUsage, usageError(fmt.Sprintf("unknown -fail-on %q", *failOn)))
+	}
+}
+
+// runSpan measures the wall-clock time of a run from its packages: from
+// the start of the first to the end of the last. Streams without times
+// fall back to the sum of the package durations.
+type runSpan struct {
+	start, end time.Time
+	sum        float64
+}
+
+func (s *runSpan) add(tp *report.TestPkg) {
+	s.sum += tp.Elapsed
+	if tp.Time == nil {
+		return
+	}
+	start := tp.Time.Add(-time.Duration(tp.Elapsed * float64(time.Second)))
+	if s.start.IsZero() || start.Before(s.start) {
+		s.start = start
+	}
+	if tp.Time.After(s.end) {
+		s.end = *tp.Time
+	}
+}
+
+func (s *runSpan) duration() time.Duration {
+	if s.start.IsZero() {
+		return time.Duration(s.sum * float64(time.Second))
+	}
+	return s.end.Sub(s.start)
+}
+
+// missedThresholds describes the thresholds the run of c, which took d,
+// missed.
+func missedThresholds(c *report.Count, d time.Duration) []string {
+	var missed []string
+	if *failOnSkip && c.Skip > 0 {
+		missed = append(missed, fmt.Sprintf("%d skipped tests fail -fail-on-skip", c.Skip))
+	}
+	if rate := c.PassRate() * 100; *minPassRate > 0 && rate < *minPassRate {
+		missed = append(missed, fmt.Sprintf("pass rate %.1f%% is below -min-pass-rate %g%%", rate, *minPassRate))
+	}
+	if *maxDuration > 0 && d > *maxDuration {
+		missed = append(missed, fmt.Sprintf("the run took %s, more than -max-duration %s", d.Round(time.Millisecond), *maxDuration))
+	}
+	return missed
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var (
+	testTimeout = flag.Duration("test-timeout", 0, "the -timeout go test ran with, to report the share of it every package used; in run mode it is taken from the go test flags")
+	timeoutRisk = flag.Float64("timeout-risk", 0.8, "flag packages that used at least this fraction of -test-timeout as at risk of timing out")
+)
+
+// goTestDefaultTimeout is the -timeout of go test when none is given.
+const goTestDefaultTimeout = 10 * time.Minute
+
+// goTestTimeout returns the -timeout of the go test flags.
+func goTestTimeout(goFlags []string) (time.Duration, error) {
+	d := goTestDefaultTimeout
+	for i := 0; i < len(goFlags); i++ {
+		name := strings.TrimLeft(goFlags[i], "-")
+		value, ok := "", false
+		if j := strings.Index(name, "="); j >= 0 {
+			name, value, ok = name[:j], name[j+1:], true
+		}
+		if name != "timeout" && name != "test.timeout" {
+			continue
+		}
+		if !ok && i+1 < len(goFlags) {
+			i++
+			value = goFlags[i]
+		}
+		var err error
+		d, err = time.ParseDuration(value)
+		if err != nil {
+			return 0, usageError("go test -timeout: " + err.Error())
+		}
+	}
+	return d, nil
+}
+
+// applyTimeout reports the share of -test-timeout used and warns about
+// the packages at risk.
+func applyTimeout(ti *report.TestInfo) {
+	ti.ApplyTimeout(*testTimeout, *timeoutRisk)
+	for _, tp := range ti.TpList {
+		if tp.TimeoutRisk {
+			log.Printf("%s took %.0f%% of the %v timeout", tp.Package, tp.TimeoutUsed, *testTimeout)
+		}
+	}
+}
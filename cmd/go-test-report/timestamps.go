@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var timestamps = flag.String("timestamps", "absolute", "start and end times of packages and tests: absolute clock times, relative to the start of the run, or off. With off the report is not dated either, so reports of the same stream are identical; SOURCE_DATE_EPOCH, when set, dates the report in any case")
+
+var timestampModes = map[string]report.TimestampMode{
+	"absolute": report.TimestampsAbsolute,
+	"relative": report.TimestampsRelative,
+	"off":      report.TimestampsOff,
+}
+
+func timestampMode() report.TimestampMode {
+	mode, ok := timestampModes[*timestamps]
+	if !ok {
+		fatal(exitUsage, usageError(fmt.Sprintf("-timestamps %q: want absolute, relative or off", *timestamps)))
+	}
+	return mode
+}
+
+// sourceDateEpoch returns the time SOURCE_DATE_EPOCH is set to, see
+// https://reproducible-builds.org/specs/source-date-epoch/.
+func sourceDateEpoch() (time.Time, bool) {
+	v, ok := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !ok || len(v) < 1 {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		fatal(exitUsage, usageError(fmt.Sprintf("SOURCE_DATE_EPOCH %q: want seconds since 1970", v)))
+	}
+	return time.Unix(sec, 0).UTC(), true
+}
+
+// applyTimestamps dates ti and writes the times of its packages and
+// tests for -timestamps. With -timestamps off the report is not dated
+// unless SOURCE_DATE_EPOCH is set.
+func applyTimestamps(ti *report.TestInfo, mode report.TimestampMode) {
+	if t, ok := sourceDateEpoch(); ok {
+		ti.Time = &t
+	} else if mode == report.TimestampsOff {
+		ti.Time = nil
+	}
+	ti.SetTimestamps(mode)
+}
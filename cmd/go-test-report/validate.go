@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+func runValidate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-test-report validate report.xml ...\n\nChecks reports written with -format xml or json: json reports against the\nschema, and both for counts matching their tests, valid results and times.\nExits with 1 if a report is invalid.")
+		fs.PrintDefaults()
+	}
+	paths := parseArgs(fs, args)
+	if len(paths) < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	invalid := false
+	for _, path := range paths {
+		problems, err := validateReport(path)
+		if err != nil {
+			fatal(exitInput, err)
+		}
+		for _, p := range problems {
+			fmt.Printf("%s: %s\n", path, p)
+		}
+		if len(problems) > 0 {
+			invalid = true
+			continue
+		}
+		fmt.Printf("%s: ok\n", path)
+	}
+	if invalid {
+		os.Exit(exitFailed)
+	}
+}
+
+func validateReport(path string) ([]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '{' {
+		problems, err = report.ValidateJSON(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	ti, err := report.Read(path)
+	if err != nil && len(problems) > 0 {
+		// The schema problems are why it does not load.
+		return problems, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(problems, ti.Validate()...), nil
+}
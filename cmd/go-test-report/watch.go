@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+func runWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		fs.Var(f.Value, f.Name, f.Usage)
+	})
+	interval := fs.Duration("interval", time.Second, "look for changed files every `d`")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-test-report watch [flags] [--] [go test flags] [packages]\n\nRuns the tests, then reruns the packages affected by every change to the\nfiles of the current directory and rewrites the report, until interrupted.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	goFlags, pkgs := splitGoTestArgs(fs.Args())
+	if len(pkgs) < 1 {
+		pkgs = []string{"./..."}
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		fatal(exitInput, err)
+	}
+	if len(*liveAddr) > 0 {
+		live, err = startLive(*liveAddr)
+		if err != nil {
+			fatal(exitOutput, err)
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	files := watchedFiles(root)
+	ti := watchRun(ctx, goFlags, pkgs, nil)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(*interval):
+		}
+		next := watchedFiles(root)
+		dirs, all := changedDirs(files, next)
+		files = next
+		if len(dirs) < 1 && !all {
+			continue
+		}
+		run := pkgs
+		if !all {
+			run, err = affectedPackages(pkgs, dirs)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+		}
+		if len(run) > 0 {
+			ti = watchRun(ctx, goFlags, run, ti)
+		}
+	}
+}
+
+// watchRun runs the tests of pkgs and writes the report of prev with
+// their packages replaced.
+func watchRun(ctx context.Context, goFlags, pkgs []string, prev *report.TestInfo) *report.TestInfo {
+	log.Printf("testing %s", strings.Join(pkgs, " "))
+	re, err := goTest(ctx, append(append([]string(nil), goFlags...), pkgs...))
+	if err != nil {
+		log.Println(err)
+		return prev
+	}
+	if re.Incomplete {
+		return prev
+	}
+	reports := []*report.TestInfo{re}
+	if prev != nil {
+		reports = []*report.TestInfo{prev, re}
+	}
+	ti := report.Merge(report.MergeLast, reports...)
+	_, err = writeReport(ti)
+	if err != nil {
+		log.Println(err)
+	}
+	if live != nil {
+		live.update(ti)
+	}
+	log.Printf("%d tests: %d passed, %d failed, %d skipped", ti.Total, ti.Pass, ti.Fail, ti.Skip)
+	return ti
+}
+
+// fileState tells whether a file changed between two looks.
+type fileState struct {
+	mod  time.Time
+	size int64
+}
+
+// watchedFiles returns the state of the Go files, go.mod and go.sum and
+// the test data under root, skipping the directories the go command
+// ignores.
+func watchedFiles(root string) map[string]fileState {
+	files := map[string]fileState{}
+	_ = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := info.Name()
+		if info.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(name, ".go") || name == "go.mod" || name == "go.sum" ||
+			strings.Contains(path, string(filepath.Separator)+"testdata"+string(filepath.Separator)) {
+			files[path] = fileState{info.ModTime(), info.Size()}
+		}
+		return nil
+	})
+	return files
+}
+
+// changedDirs returns the directories of the files added, removed or
+// changed from old to cur, and whether go.mod or go.sum changed, which
+// affects every package.
+func changedDirs(old, cur map[string]fileState) (dirs []string, all bool) {
+	seen := map[string]bool{}
+	note := func(path string) {
+		name := filepath.Base(path)
+		all = all || name == "go.mod" || name == "go.sum"
+		if dir := filepath.Dir(path); !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	for path, st := range cur {
+		if o, ok := old[path]; !ok || o != st {
+			note(path)
+		}
+	}
+	for path := range old {
+		if _, ok := cur[path]; !ok {
+			note(path)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, all
+}
+
+// affectedPackages returns the packages of patterns in dirs, or whose
+// test data is in dirs, and the packages that depend on them.
+func affectedPackages(patterns, dirs []string) ([]string, error) {
+	cmd := exec.Command("go", append([]string{"list", "-e", "-f", "{{.ImportPath}}\t{{.Dir}}\t{{join .Deps \" \"}} {{join .TestImports \" \"}} {{join .XTestImports \" \"}}"}, patterns...)...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %w", err)
+	}
+	type pkg struct {
+		path, dir string
+		deps      []string
+	}
+	var list []pkg
+	for _, line := range strings.Split(string(bytes.TrimSpace(out)), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) == 3 {
+			list = append(list, pkg{fields[0], fields[1], strings.Fields(fields[2])})
+		}
+	}
+	changed := map[string]bool{}
+	for _, dir := range dirs {
+		// The package of a file is in the closest directory above it.
+		best := -1
+		for i, p := range list {
+			if (dir == p.dir || strings.HasPrefix(dir, p.dir+string(filepath.Separator))) && (best < 0 || len(p.dir) > len(list[best].dir)) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			changed[list[best].path] = true
+		}
+	}
+	var affected []string
+	for _, p := range list {
+		hit := changed[p.path]
+		for _, d := range p.deps {
+			hit = hit || changed[d]
+		}
+		if hit {
+			affected = append(affected, p.path)
+		}
+	}
+	return affected, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/render"
+)
+
+var (
+	xmlOutput  = flag.String("xml-output", "element", "write the output of packages and tests in the xml format as an element or an attribute")
+	xmlCompact = flag.Bool("xml-compact", false, "deprecated alias of -indent none; -indent wins when both are given")
+	xmlEmpty   = flag.String("xml-empty", "default", "empty values in the xml format: default, omit every empty attribute and element, or keep every attribute")
+	xmlRoot    = flag.String("xml-root", "", "`name` of the root element of the xml format instead of all")
+	xmlNS      = flag.String("xml-namespace", "", "default namespace `uri` declared on the root element of the xml format")
+	subtests   = flag.String("subtests", "tree", "subtests in the xml and json formats: a tree under their parents, or flat next to them")
+	indent     = flag.String("indent", "", "indentation of the xml and json formats: tab, the default, none, or a number of spaces")
+)
+
+type attrList []xml.Attr
+
+func (l *attrList) String() string {
+	var s []string
+	for _, a := range *l {
+		s = append(s, a.Name.Local+"="+a.Value)
+	}
+	return strings.Join(s, ",")
+}
+
+func (l *attrList) Set(v string) error {
+	i := strings.Index(v, "=")
+	if i < 1 {
+		return fmt.Errorf("%q: want name=value", v)
+	}
+	*l = append(*l, xml.Attr{Name: xml.Name{Local: v[:i]}, Value: v[i+1:]})
+	return nil
+}
+
+var xmlRootAttrs attrList
+
+func init() {
+	flag.Var(&xmlRootAttrs, "xml-root-attr", "add the attribute `name=value` to the root element of the xml format; repeatable")
+}
+
+var xmlEmptyModes = map[string]render.XMLEmpty{
+	"default": render.XMLEmptyDefault,
+	"omit":    render.XMLEmptyOmit,
+	"keep":    render.XMLEmptyKeep,
+}
+
+// xmlRenderer returns the renderer of the xml format with the -xml-*
+// options applied.
+func xmlRenderer() (render.XMLRenderer, error) {
+	r := render.XMLRenderer{Root: *xmlRoot, Namespace: *xmlNS, RootAttrs: xmlRootAttrs}
+	in, err := indentation()
+	if err != nil {
+		return r, err
+	}
+	r.Tree, err = subtestTree()
+	if err != nil {
+		return r, err
+	}
+	switch in {
+	case "":
+		r.Compact = true
+	case "\t":
+	default:
+		r.Indent = in
+	}
+	switch *xmlOutput {
+	case "element":
+	case "attribute", "attr":
+		r.OutputAttr = true
+	default:
+		return r, usageError(fmt.Sprintf("-xml-output %q: want element or attribute", *xmlOutput))
+	}
+	empty, ok := xmlEmptyModes[*xmlEmpty]
+	if !ok {
+		return r, usageError(fmt.Sprintf("-xml-empty %q: want default, omit or keep", *xmlEmpty))
+	}
+	r.Empty = empty
+	return r, nil
+}
+
+// indentation returns the indentation -indent, or else -xml-compact, asks
+// for, "" for none.
+func indentation() (string, error) {
+	switch *indent {
+	case "":
+		if *xmlCompact {
+			return "", nil
+		}
+		return "\t", nil
+	case "tab":
+		return "\t", nil
+	case "none":
+		return "", nil
+	}
+	n, err := strconv.Atoi(*indent)
+	if err != nil || n < 0 {
+		return "", usageError(fmt.Sprintf("-indent %q: want tab, none or a number of spaces", *indent))
+	}
+	return strings.Repeat(" ", n), nil
+}
+
+// subtestTree reports whether -subtests asks for a tree.
+func subtestTree() (bool, error) {
+	switch *subtests {
+	case "tree":
+		return true, nil
+	case "flat":
+		return false, nil
+	}
+	return false, usageError(fmt.Sprintf("-subtests %q: want tree or flat", *subtests))
+}
+
+// formatRenderer returns the renderer of format with its options
+// applied, for reports in dir, and the paths of the assets it wrote.
+func formatRenderer(format, dir string) (render.Renderer, []string, error) {
+	switch format {
+	case "html":
+		return htmlRenderer(dir)
+	case "xml":
+		r, err := xmlRenderer()
+		return r, nil, err
+	case "json":
+		in, err := indentation()
+		if err != nil {
+			return nil, nil, err
+		}
+		tree, err := subtestTree()
+		return render.JSONRenderer{Indent: in, Tree: tree}, nil, err
+	}
+	r, ok := render.Lookup(format)
+	if !ok {
+		return nil, nil, usageError(fmt.Sprintf("unknown format %q", format))
+	}
+	return r, nil, nil
+}
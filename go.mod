@@ -1,3 +1,3 @@
-module testlog
+module github.com/jiuliyemingzhi/go-test-report
 
 go 1.17
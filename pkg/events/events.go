@@ -0,0 +1,322 @@
+// Package events decodes the event stream written by go test -json.
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type ActionType int
+
+const (
+	// Dv is the Elapsed of an event that did not report one.
+	Dv float64 = -1
+
+	ActionTypeStart = 0
+
+	ActionTypeEnd = 1
+
+	ActionTypeIng = 2
+
+	ActionPass = "pass"
+
+	ActionSkip = "skip"
+
+	ActionFail = "fail"
+
+	ActionPause = "pause"
+
+	ActionCont = "cont"
+
+	ActionBench = "bench"
+
+	ActionOutput = "output"
+
+	ActionRun = "run"
+
+	// printed by go test from Go 1.20 on before a package starts running.
+	ActionStart = "start"
+
+	// printed by go test from Go 1.24 on for the compiler output of a
+	// package, and once its build failed. They name the package by
+	// ImportPath.
+	ActionBuildOutput = "build-output"
+	ActionBuildFail   = "build-fail"
+
+	// printed by test on successful run.
+	bigPass = "PASS\n"
+
+	// printed by test after a normal test failure.
+	bigFail = "FAIL\n"
+
+	// printed by 'go test' along with an error if the test binary terminates
+	// with an error.
+	bigFailErrorPrefix = "FAIL\t"
+
+	updatesRun   = "=== RUN   "
+	updatesPause = "=== PAUSE "
+	updatesCont  = "=== CONT  "
+
+	reportsPass  = "--- PASS: "
+	reportsFail  = "--- FAIL: "
+	reportsSkip  = "--- SKIP: "
+	reportsBench = "--- BENCH: "
+
+	fourSpace = "    "
+
+	skipLinePrefix = "?   \t"
+	skipLineSuffix = "\t[no test files]\n"
+)
+
+// TestEvent {"Time":"2022-01-23T16:58:49.186901+08:00","Action":"output","Package":"modify","Package":"modify.init.0()\n"}
+type TestEvent struct {
+	Action  string     `json:"Action" xml:"action,attr,omitempty"`
+	Package string     `json:"Package,omitempty" xml:"package,attr,omitempty"`
+	Test    string     `json:"Test,omitempty" xml:"name,attr,omitempty,comment=测试名"`
+	Output  string     `json:"Output,omitempty" xml:"output"`
+	Elapsed float64    `json:"Elapsed,omitempty" xml:"-"`
+	Time    *time.Time `json:"Time,omitempty" xml:"-"`
+	// ImportPath names the package of build events, and FailedBuild the
+	// package whose build failure failed the package of a final event.
+	ImportPath  string `json:"ImportPath,omitempty" xml:"-"`
+	FailedBuild string `json:"FailedBuild,omitempty" xml:"-"`
+	// Index is the position of the event in the stream.
+	Index      int        `json:"-" xml:"-"`
+	ActionType ActionType `json:"-" xml:"-"`
+}
+
+// Decode reads all events of r.
+func Decode(r io.Reader) ([]*TestEvent, error) {
+	d := NewDecoder(r)
+	var tlList []*TestEvent
+	for {
+		e, err := d.Next()
+		if err == io.EOF {
+			return tlList, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		tlList = append(tlList, e)
+	}
+}
+
+// Error describes a line of the stream that is not a valid event.
+type Error struct {
+	Line int
+	// Text is the offending line, shortened to maxErrorText bytes.
+	Text string
+	Err  error
+}
+
+const maxErrorText = 200
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %v: %q", e.Line, e.Err, e.Text)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnknownAction is the error of events whose action is not known,
+// such as those of a newer go test.
+var ErrUnknownAction = errors.New("unknown action")
+
+// Malformed reports whether the line is not a JSON event at all, or not
+// one of a known action, as opposed to an event that could not be
+// handled.
+func (e *Error) Malformed() bool {
+	var se *json.SyntaxError
+	var te *json.UnmarshalTypeError
+	return errors.As(e.Err, &se) || errors.As(e.Err, &te) || errors.Is(e.Err, ErrUnknownAction)
+}
+
+// Decoder reads events one at a time as they are written. The stream
+// holds one event per line; blank lines are ignored.
+type Decoder struct {
+	// Reuse lets NextLine return the same Line, whose Text is only valid
+	// until the next call, sparing an allocation per line.
+	Reuse bool
+
+	r     *bufio.Reader
+	line  int
+	index int
+	l     Line
+	buf   []byte
+}
+
+// decoderBuffer is the size of the read buffer of a Decoder, large enough
+// for a few hundred typical events.
+const decoderBuffer = 64 << 10
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReaderSize(r, decoderBuffer)}
+}
+
+// Buffered returns the number of bytes read from the stream that NextLine
+// has not returned yet. When it is 0, the next call may block on the
+// stream.
+func (d *Decoder) Buffered() int {
+	return d.r.Buffered()
+}
+
+// Next returns the next event of the stream, or io.EOF at its end.
+// Lines that cannot be decoded are reported as *Error.
+func (d *Decoder) Next() (*TestEvent, error) {
+	l, err := d.NextLine()
+	if err != nil {
+		return nil, err
+	}
+	return l.Decode()
+}
+
+// Line is a line of the stream that has not been decoded yet.
+type Line struct {
+	Text []byte
+	// No is the line number, Index the position of the event.
+	No    int
+	Index int
+}
+
+// NextLine returns the next non-blank line without decoding it, so the
+// decoding can happen elsewhere, or io.EOF at the end of the stream.
+func (d *Decoder) NextLine() (*Line, error) {
+	for {
+		text, err := d.readLine()
+		if len(text) < 1 && err != nil {
+			return nil, err
+		}
+		d.line++
+		if len(bytes.TrimSpace(text)) < 1 {
+			continue
+		}
+		l := &d.l
+		if !d.Reuse {
+			l = &Line{Text: append([]byte(nil), text...)}
+		} else {
+			l.Text = text
+		}
+		l.No, l.Index = d.line, d.index
+		d.index++
+		return l, nil
+	}
+}
+
+// readLine returns the next line, which is only valid until the next
+// call.
+func (d *Decoder) readLine() ([]byte, error) {
+	text, err := d.r.ReadSlice('\n')
+	if err != bufio.ErrBufferFull {
+		return text, err
+	}
+	d.buf = append(d.buf[:0], text...)
+	for err == bufio.ErrBufferFull {
+		text, err = d.r.ReadSlice('\n')
+		d.buf = append(d.buf, text...)
+	}
+	return d.buf, err
+}
+
+// Decode decodes the event of l.
+func (l *Line) Decode() (*TestEvent, error) {
+	e := &TestEvent{Elapsed: Dv, Index: l.Index}
+	var err error
+	if !decodeFast(l.Text, e) {
+		*e = TestEvent{Elapsed: Dv, Index: l.Index}
+		err = json.Unmarshal(l.Text, e)
+	}
+	if err == nil {
+		err = e.SetActionType()
+	}
+	if err != nil {
+		text := strings.TrimRight(string(l.Text), "\r\n")
+		if len(text) > maxErrorText {
+			text = text[:maxErrorText] + "..."
+		}
+		return nil, &Error{Line: l.No, Text: text, Err: err}
+	}
+	return e, nil
+}
+
+var packageKey = []byte(`"Package":"`)
+
+// Package returns the package of the event of l without decoding the
+// whole line. Lines that do not look like an event return "".
+func (l *Line) Package() string {
+	return string(l.PackageBytes())
+}
+
+// PackageBytes is like Package, but the result usually aliases l.Text.
+func (l *Line) PackageBytes() []byte {
+	i := bytes.Index(l.Text, packageKey)
+	if i < 0 {
+		return nil
+	}
+	rest := l.Text[i+len(packageKey):]
+	j := bytes.IndexByte(rest, '"')
+	if j < 0 {
+		return nil
+	}
+	if bytes.IndexByte(rest[:j], '\\') < 0 {
+		return rest[:j]
+	}
+	var e struct{ Package string }
+	_ = json.Unmarshal(l.Text, &e)
+	return []byte(e.Package)
+}
+
+var importPathKey = []byte(`"ImportPath":"`)
+
+// BuildPackageBytes returns the package the build event of l was for,
+// see BuildPackage. Other lines return nil.
+func (l *Line) BuildPackageBytes() []byte {
+	i := bytes.Index(l.Text, importPathKey)
+	if i < 0 {
+		return nil
+	}
+	rest := l.Text[i+len(importPathKey):]
+	j := bytes.IndexByte(rest, '"')
+	if j < 0 {
+		return nil
+	}
+	return []byte(BuildPackage(string(rest[:j])))
+}
+
+// BuildPackage returns the package whose tests a build of importPath was
+// for, such as "pkg" for "pkg", "pkg [pkg.test]" and the external test
+// package "pkg_test [pkg.test]".
+func BuildPackage(importPath string) string {
+	i := strings.Index(importPath, " [")
+	if i < 0 {
+		return importPath
+	}
+	if v := importPath[i+2:]; strings.HasSuffix(v, ".test]") {
+		return strings.TrimSuffix(v, ".test]")
+	}
+	return importPath[:i]
+}
+
+func (e *TestEvent) SetActionType() error {
+	switch strings.TrimSpace(e.Action) {
+	case ActionRun:
+		e.ActionType = ActionTypeStart
+	case ActionFail, ActionPass, ActionSkip:
+		e.ActionType = ActionTypeEnd
+	case ActionOutput, ActionPause, ActionCont, ActionBench, ActionStart, ActionBuildOutput, ActionBuildFail:
+		e.ActionType = ActionTypeIng
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownAction, e.Action)
+	}
+	return nil
+}
+
+func (e *TestEvent) HasElapsed() bool {
+	return e.Elapsed != Dv
+}
@@ -0,0 +1,115 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var lines = []string{
+	`{"Time":"2022-01-23T16:58:49.186901+08:00","Action":"output","Package":"modify","Output":"modify.init.0()\n"}`,
+	`{"Time":"2022-01-23T16:58:49.1869Z","Action":"run","Package":"example.com/a","Test":"TestA/sub_case#01"}`,
+	`{"Time":"2022-01-23T16:58:49Z","Action":"pass","Package":"example.com/a","Test":"TestA","Elapsed":0.02}`,
+	`{"Action":"fail","Package":"example.com/a","Elapsed":1e-3}`,
+	`{"Action":"output","Package":"a","Output":"\u003cnil\u003e \u0026 \"quoted\" \\ \/ \t\r\b\f é \ud83d\ude00 日本\n"}`,
+	`{"Action":"output","Package":"a","Output":"日本語 ünïcode"}`,
+	` { "Action" : "pass" , "Package" : "a" } `,
+	`{"Action":"pass","Package":"a","Elapsed":-0}`,
+	`{}`,
+	// Keys of newer versions of go test.
+	`{"Time":"2025-02-11T10:00:00.5Z","Action":"output","Package":"a","Test":"TestA","Output":"    a_test.go:5: x\n","OutputType":"error-continue"}`,
+	`{"Action":"pass","Package":"a","Extra":1,"Source":null,"Cached":true,"Name":"\u00e9"}`,
+	// Shapes the fast path leaves to encoding/json.
+	`{"Action":"pass","Package":"a","Extra":{"x":1}}`,
+	`{"Action":"pass","Package":"a","Extra":[1]}`,
+	`{"action":"pass","Package":"a"}`,
+	`{"Action":"pass","ELAPSED":1}`,
+	`{"Action":null,"Package":"a"}`,
+	`{"Action":"output","Package":"a","Output":"\ud83d lone"}`,
+	`{"Action":"output","Package":"a","Output":"bad \xff utf8"}`,
+	`{"Action":"pass","Package":"a","Elapsed":01}`,
+	`{"Action":"pass","Package":"a",}`,
+	`{"Action":"pass"`,
+	`{"Action":"pass"} trailing`,
+	`not json`,
+}
+
+func TestDecodeFastMatchesJSON(t *testing.T) {
+	for i, line := range lines {
+		fast := &TestEvent{Elapsed: Dv}
+		ok := decodeFast([]byte(line), fast)
+		if i < 11 && !ok {
+			t.Errorf("%s: fast path declined", line)
+		}
+		want := &TestEvent{Elapsed: Dv}
+		err := json.Unmarshal([]byte(line), want)
+		if ok && err != nil {
+			t.Errorf("%s: fast path accepted invalid JSON: %v", line, err)
+			continue
+		}
+		if ok && !reflect.DeepEqual(fast, want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", line, fast, want)
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	log := strings.Join(lines[:8], "\n") + "\n\n" + strings.Repeat("x", 70000) + "\n"
+	for _, reuse := range []bool{false, true} {
+		d := NewDecoder(strings.NewReader(log))
+		d.Reuse = reuse
+		for i := 0; i < 8; i++ {
+			e, err := d.Next()
+			if err != nil {
+				t.Fatalf("line %d: %v", i+1, err)
+			}
+			if e.Index != i {
+				t.Errorf("line %d: index %d", i+1, e.Index)
+			}
+		}
+		_, err := d.Next()
+		de, ok := err.(*Error)
+		if !ok || de.Line != 10 || !de.Malformed() {
+			t.Errorf("got %v, want malformed line 10", err)
+		}
+	}
+}
+
+func TestBuildPackage(t *testing.T) {
+	for _, c := range []struct{ importPath, want string }{
+		{"a", "a"},
+		{"a [a.test]", "a"},
+		{"a_test [a.test]", "a"},
+		{"example.com/x/b [example.com/x/a.test]", "example.com/x/a"},
+	} {
+		if got := BuildPackage(c.importPath); got != c.want {
+			t.Errorf("%s: got %s, want %s", c.importPath, got, c.want)
+		}
+		l := &Line{Text: []byte(`{"ImportPath":"` + c.importPath + `","Action":"build-output","Output":"x\n"}`)}
+		if got := string(l.BuildPackageBytes()); got != c.want {
+			t.Errorf("%s: BuildPackageBytes got %s, want %s", c.importPath, got, c.want)
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	var buf bytes.Buffer
+	for i := 0; i < 1000; i++ {
+		buf.WriteString(lines[i%5])
+		buf.WriteByte('\n')
+	}
+	log := buf.Bytes()
+	b.SetBytes(int64(len(log)))
+	for i := 0; i < b.N; i++ {
+		d := NewDecoder(bytes.NewReader(log))
+		d.Reuse = true
+		for {
+			_, err := d.Next()
+			if err != nil {
+				break
+			}
+		}
+	}
+}
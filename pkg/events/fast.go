@@ -0,0 +1,314 @@
+package events
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// decodeFast decodes lines of the shape go test -json writes without
+// reflection. It reports false on anything else, including invalid
+// input, and the caller falls back to encoding/json.
+func decodeFast(b []byte, e *TestEvent) bool {
+	i := skipSpace(b, 0)
+	if i >= len(b) || b[i] != '{' {
+		return false
+	}
+	i = skipSpace(b, i+1)
+	if i < len(b) && b[i] == '}' {
+		return skipSpace(b, i+1) == len(b)
+	}
+	for {
+		key, j, ok := rawString(b, i)
+		if !ok {
+			return false
+		}
+		i = skipSpace(b, j)
+		if i >= len(b) || b[i] != ':' {
+			return false
+		}
+		i = skipSpace(b, i+1)
+		switch string(key) {
+		case "Time":
+			var s string
+			s, i, ok = fastString(b, i)
+			if !ok {
+				return false
+			}
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return false
+			}
+			e.Time = &t
+		case "Action":
+			var raw []byte
+			if raw, j, ok = rawString(b, i); ok {
+				e.Action, i = action(raw), j
+			} else {
+				e.Action, i, ok = fastString(b, i)
+			}
+		case "Package":
+			e.Package, i, ok = fastString(b, i)
+		case "Test":
+			e.Test, i, ok = fastString(b, i)
+		case "Output":
+			e.Output, i, ok = fastString(b, i)
+		case "Elapsed":
+			e.Elapsed, i, ok = fastNumber(b, i)
+		case "ImportPath":
+			e.ImportPath, i, ok = fastString(b, i)
+		case "FailedBuild":
+			e.FailedBuild, i, ok = fastString(b, i)
+		default:
+			// encoding/json matches keys regardless of case, so only
+			// the keys of no field, such as OutputType, are skipped.
+			if eventKey(key) {
+				return false
+			}
+			i, ok = skipScalar(b, i)
+		}
+		if !ok {
+			return false
+		}
+		i = skipSpace(b, i)
+		if i >= len(b) {
+			return false
+		}
+		switch b[i] {
+		case ',':
+			i = skipSpace(b, i+1)
+		case '}':
+			return skipSpace(b, i+1) == len(b)
+		default:
+			return false
+		}
+	}
+}
+
+// eventKeys are the keys decodeFast decodes.
+var eventKeys = [][]byte{[]byte("Time"), []byte("Action"), []byte("Package"), []byte("Test"),
+	[]byte("Output"), []byte("Elapsed"), []byte("ImportPath"), []byte("FailedBuild")}
+
+// eventKey tells a key encoding/json decodes into a field of TestEvent.
+func eventKey(key []byte) bool {
+	for _, k := range eventKeys {
+		if bytes.EqualFold(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipScalar skips the string, number or literal starting at b[i].
+// Objects and arrays are left to encoding/json.
+func skipScalar(b []byte, i int) (int, bool) {
+	if i < len(b) && b[i] == '"' {
+		if _, j, ok := rawString(b, i); ok {
+			return j, true
+		}
+		_, j, ok := fastString(b, i)
+		return j, ok
+	}
+	for _, lit := range []string{"true", "false", "null"} {
+		if bytes.HasPrefix(b[i:], []byte(lit)) {
+			return i + len(lit), true
+		}
+	}
+	_, j, ok := fastNumber(b, i)
+	return j, ok
+}
+
+// action returns the constant for known actions, sparing an allocation.
+func action(raw []byte) string {
+	switch string(raw) {
+	case ActionOutput:
+		return ActionOutput
+	case ActionRun:
+		return ActionRun
+	case ActionPass:
+		return ActionPass
+	case ActionFail:
+		return ActionFail
+	case ActionSkip:
+		return ActionSkip
+	case ActionPause:
+		return ActionPause
+	case ActionCont:
+		return ActionCont
+	case ActionStart:
+		return ActionStart
+	case ActionBench:
+		return ActionBench
+	case ActionBuildOutput:
+		return ActionBuildOutput
+	}
+	return string(raw)
+}
+
+func skipSpace(b []byte, i int) int {
+	for i < len(b) && (b[i] == ' ' || b[i] == '\t' || b[i] == '\r' || b[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+// rawString returns the bytes of the string starting at b[i] if it has
+// no escapes.
+func rawString(b []byte, i int) ([]byte, int, bool) {
+	if i >= len(b) || b[i] != '"' {
+		return nil, i, false
+	}
+	for j := i + 1; j < len(b); j++ {
+		switch c := b[j]; {
+		case c == '"':
+			return b[i+1 : j], j + 1, true
+		case c == '\\' || c < 0x20:
+			return nil, i, false
+		}
+	}
+	return nil, i, false
+}
+
+// fastString decodes the string starting at b[i].
+func fastString(b []byte, i int) (string, int, bool) {
+	if i >= len(b) || b[i] != '"' {
+		return "", i, false
+	}
+	start := i + 1
+	j := start
+	ascii := true
+	for ; j < len(b); j++ {
+		c := b[j]
+		if c == '"' {
+			s := b[start:j]
+			if !ascii && !utf8.Valid(s) {
+				return "", i, false
+			}
+			return string(s), j + 1, true
+		}
+		if c == '\\' {
+			break
+		}
+		if c < 0x20 {
+			return "", i, false
+		}
+		if c >= utf8.RuneSelf {
+			ascii = false
+		}
+	}
+	if j >= len(b) {
+		return "", i, false
+	}
+	out := make([]byte, j-start, len(b)-start)
+	copy(out, b[start:j])
+	for j < len(b) {
+		c := b[j]
+		switch {
+		case c == '"':
+			if !utf8.Valid(out) {
+				return "", i, false
+			}
+			return string(out), j + 1, true
+		case c < 0x20:
+			return "", i, false
+		case c != '\\':
+			out = append(out, c)
+			j++
+			continue
+		}
+		if j+1 >= len(b) {
+			return "", i, false
+		}
+		switch b[j+1] {
+		case '"', '\\', '/':
+			out = append(out, b[j+1])
+		case 'b':
+			out = append(out, '\b')
+		case 'f':
+			out = append(out, '\f')
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 't':
+			out = append(out, '\t')
+		case 'u':
+			r, ok := hex4(b, j+2)
+			if !ok {
+				return "", i, false
+			}
+			if utf16.IsSurrogate(r) {
+				r2, ok := hex4(b, j+8)
+				if !ok || b[j+6] != '\\' || b[j+7] != 'u' {
+					return "", i, false
+				}
+				r = utf16.DecodeRune(r, r2)
+				if r == utf8.RuneError {
+					return "", i, false
+				}
+				j += 6
+			}
+			var rb [utf8.UTFMax]byte
+			out = append(out, rb[:utf8.EncodeRune(rb[:], r)]...)
+			j += 6
+			continue
+		default:
+			return "", i, false
+		}
+		j += 2
+	}
+	return "", i, false
+}
+
+func hex4(b []byte, i int) (rune, bool) {
+	if i+4 > len(b) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(string(b[i:i+4]), 16, 32)
+	return rune(n), err == nil
+}
+
+// fastNumber decodes the number starting at b[i].
+func fastNumber(b []byte, i int) (float64, int, bool) {
+	j := i
+	if j < len(b) && b[j] == '-' {
+		j++
+	}
+	switch {
+	case j < len(b) && b[j] == '0':
+		j++
+	case j < len(b) && b[j] >= '1' && b[j] <= '9':
+		j = digits(b, j)
+	default:
+		return 0, i, false
+	}
+	if j < len(b) && b[j] == '.' {
+		if k := digits(b, j+1); k > j+1 {
+			j = k
+		} else {
+			return 0, i, false
+		}
+	}
+	if j < len(b) && (b[j] == 'e' || b[j] == 'E') {
+		k := j + 1
+		if k < len(b) && (b[k] == '+' || b[k] == '-') {
+			k++
+		}
+		if l := digits(b, k); l > k {
+			j = l
+		} else {
+			return 0, i, false
+		}
+	}
+	f, err := strconv.ParseFloat(string(b[i:j]), 64)
+	return f, j, err == nil
+}
+
+func digits(b []byte, i int) int {
+	for i < len(b) && b[i] >= '0' && b[i] <= '9' {
+		i++
+	}
+	return i
+}
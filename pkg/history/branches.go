@@ -0,0 +1,82 @@
+package history
+
+import (
+	"fmt"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+type BranchTest struct {
+	Package string `json:"package"`
+	Test    string `json:"test"`
+	A       string `json:"a"`
+	B       string `json:"b"`
+}
+
+// BranchComparison compares the latest runs of two branches.
+type BranchComparison struct {
+	A        string        `json:"a"`
+	B        string        `json:"b"`
+	RunA     *Run          `json:"runA"`
+	RunB     *Run          `json:"runB"`
+	FailOnA  []*BranchTest `json:"failOnA"`
+	FailOnB  []*BranchTest `json:"failOnB"`
+	OnlyOnA  []*BranchTest `json:"onlyOnA"`
+	OnlyOnB  []*BranchTest `json:"onlyOnB"`
+	Matching int           `json:"matching"`
+}
+
+// LatestRun returns the most recent run of branch, or nil.
+func LatestRun(runs []*Run, branch string) *Run {
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].Branch == branch {
+			return runs[i]
+		}
+	}
+	return nil
+}
+
+// CompareBranches compares the latest runs of branches a and b.
+func CompareBranches(runs []*Run, a, b string) (*BranchComparison, error) {
+	c := &BranchComparison{A: a, B: b, FailOnA: []*BranchTest{}, FailOnB: []*BranchTest{}, OnlyOnA: []*BranchTest{}, OnlyOnB: []*BranchTest{}}
+	c.RunA, c.RunB = LatestRun(runs, a), LatestRun(runs, b)
+	if c.RunA == nil || c.RunB == nil {
+		return nil, fmt.Errorf("no runs recorded for both %q and %q", a, b)
+	}
+	for _, ta := range c.RunA.Tests {
+		tb := c.RunB.Test(ta.Package, ta.Test)
+		if tb == nil {
+			c.OnlyOnA = append(c.OnlyOnA, &BranchTest{Package: ta.Package, Test: ta.Test, A: ta.Action})
+			continue
+		}
+		e := &BranchTest{Package: ta.Package, Test: ta.Test, A: ta.Action, B: tb.Action}
+		switch {
+		case ta.Action == events.ActionFail && tb.Action == events.ActionPass:
+			c.FailOnA = append(c.FailOnA, e)
+		case ta.Action == events.ActionPass && tb.Action == events.ActionFail:
+			c.FailOnB = append(c.FailOnB, e)
+		default:
+			c.Matching++
+		}
+	}
+	for _, tb := range c.RunB.Tests {
+		if c.RunA.Test(tb.Package, tb.Test) == nil {
+			c.OnlyOnB = append(c.OnlyOnB, &BranchTest{Package: tb.Package, Test: tb.Test, B: tb.Action})
+		}
+	}
+	return c, nil
+}
+
+// Branches returns the branch names of runs in order of first appearance.
+func Branches(runs []*Run) []string {
+	seen := map[string]bool{}
+	var list []string
+	for _, r := range runs {
+		if len(r.Branch) < 1 || seen[r.Branch] {
+			continue
+		}
+		seen[r.Branch] = true
+		list = append(list, r.Branch)
+	}
+	return list
+}
@@ -0,0 +1,388 @@
+// Package history stores per-run test results in an append-only
+// JSON-lines file and analyses them across runs.
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// DriftSamples is the number of historical durations needed before a
+// median is considered meaningful.
+const DriftSamples = 3
+
+// TrendRuns is the number of previous runs of a branch Trend returns,
+// and TrendBranches the number of branches.
+const (
+	TrendRuns     = 30
+	TrendBranches = 5
+)
+
+// Run is one line of the history file.
+type Run struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Branch   string    `json:"branch,omitempty"`
+	Commit   string    `json:"commit,omitempty"`
+	Race     bool      `json:"race,omitempty"`
+	Report   string    `json:"report,omitempty"`
+	Total    int       `json:"total"`
+	Pass     int       `json:"pass"`
+	Skip     int       `json:"skip"`
+	Fail     int       `json:"fail"`
+	Bench    int       `json:"bench,omitempty"`
+	Elapsed  float64   `json:"elapsed"`
+	Packages []*Pkg    `json:"packages,omitempty"`
+	Tests    []*Test   `json:"tests,omitempty"`
+	tests    map[string]*Test
+}
+
+type Pkg struct {
+	Package string  `json:"package"`
+	Action  string  `json:"action"`
+	Elapsed float64 `json:"elapsed"`
+}
+
+type Test struct {
+	Package string  `json:"package"`
+	Test    string  `json:"test"`
+	Action  string  `json:"action"`
+	Elapsed float64 `json:"elapsed"`
+}
+
+// RunID is the id of a run created at t.
+func RunID(t time.Time) string {
+	return t.UTC().Format("20060102T150405.000")
+}
+
+// NewRun summarises ti for the history; path is where the report was
+// written. The run is dated now and keeps ti.RunID, or gets its id from
+// the clock when ti has none, since the time of the report may be pinned
+// by SOURCE_DATE_EPOCH.
+func NewRun(ti *report.TestInfo, branch, commit, path string) *Run {
+	now := time.Now()
+	id := ti.RunID
+	if len(id) < 1 {
+		id = RunID(now)
+	}
+	r := &Run{
+		ID:     id,
+		Time:   now,
+		Branch: branch,
+		Commit: commit,
+		Race:   ti.Race,
+		Report: path,
+		Total:  ti.Total,
+		Pass:   ti.Pass,
+		Skip:   ti.Skip,
+		Fail:   ti.Fail,
+		Bench:  ti.Bench,
+	}
+	for _, tp := range ti.TpList {
+		r.Elapsed += tp.Elapsed
+		r.Packages = append(r.Packages, &Pkg{Package: tp.Package, Action: tp.Action, Elapsed: tp.Elapsed})
+		for _, u := range tp.TEList {
+			r.Tests = append(r.Tests, &Test{Package: tp.Package, Test: u.Test, Action: u.Action, Elapsed: u.Elapsed})
+		}
+	}
+	return r
+}
+
+// Append adds r as a new line to the history file at path. It waits for
+// a prune of the file by another process to finish, so the run is not
+// lost when the file is replaced.
+func Append(path string, r *Run) error {
+	bts, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	unlock, err := lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(bts, '\n'))
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
+
+// Read returns the runs of path in the order they were appended.
+func Read(path string) ([]*Run, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var runs []*Run
+	decoder := json.NewDecoder(f)
+	for decoder.More() {
+		r := &Run{}
+		err := decoder.Decode(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: run %d: %w", path, len(runs)+1, err)
+		}
+		runs = append(runs, r)
+	}
+	return runs, nil
+}
+
+// FilterBranch returns the runs of branch, or all runs if branch is empty.
+func FilterBranch(runs []*Run, branch string) []*Run {
+	if len(branch) < 1 {
+		return runs
+	}
+	var list []*Run
+	for _, r := range runs {
+		if r.Branch == branch {
+			list = append(list, r)
+		}
+	}
+	return list
+}
+
+// FilterRace returns the runs of runs that did, or did not, use the race
+// detector, whose durations and failures are not comparable with the
+// others.
+func FilterRace(runs []*Run, race bool) []*Run {
+	var list []*Run
+	for _, r := range runs {
+		if r.Race == race {
+			list = append(list, r)
+		}
+	}
+	return list
+}
+
+// Test returns the result of a test in r, or nil if it did not run.
+func (r *Run) Test(pkg, name string) *Test {
+	if r.tests == nil {
+		r.tests = make(map[string]*Test, len(r.Tests))
+		for _, t := range r.Tests {
+			r.tests[t.Package+"\x00"+t.Test] = t
+		}
+	}
+	return r.tests[pkg+"\x00"+name]
+}
+
+// TestInfo rebuilds the results of r as a report, without output, so it
+// can be compared with report.Compare.
+func (r *Run) TestInfo() *report.TestInfo {
+	ti := &report.TestInfo{Count: &report.Count{Total: r.Total, Pass: r.Pass, Skip: r.Skip, Fail: r.Fail, Bench: r.Bench}, Time: &r.Time, RunID: r.ID}
+	pkgs := map[string]*report.TestPkg{}
+	for _, p := range r.Packages {
+		tp := &report.TestPkg{TestUt: &report.TestUt{}, Count: &report.Count{}}
+		tp.Package, tp.Action, tp.Elapsed = p.Package, p.Action, p.Elapsed
+		pkgs[p.Package] = tp
+		ti.TpList = append(ti.TpList, tp)
+	}
+	for _, t := range r.Tests {
+		tp := pkgs[t.Package]
+		if tp == nil {
+			continue
+		}
+		u := &report.TestUt{Dur: time.Duration(t.Elapsed * float64(time.Second)).String()}
+		u.Package, u.Test, u.Action, u.Elapsed = t.Package, t.Test, t.Action, t.Elapsed
+		tp.TEList = append(tp.TEList, u)
+	}
+	return ti
+}
+
+// PassRate is the pass rate of r in percent.
+func (r *Run) PassRate() float64 {
+	return (&report.Count{Total: r.Total, Pass: r.Pass, Skip: r.Skip, Bench: r.Bench}).PassRate() * 100
+}
+
+// FirstFailed walks runs backwards and returns the oldest run of the
+// failure streak of the given test, or nil if it did not fail in the last run.
+func FirstFailed(runs []*Run, pkg, name string) *Run {
+	var first *Run
+	for i := len(runs) - 1; i >= 0; i-- {
+		t := runs[i].Test(pkg, name)
+		if t == nil || t.Action != events.ActionFail {
+			break
+		}
+		first = runs[i]
+	}
+	return first
+}
+
+// Breakage is a test that failed in Run while it had not failed in its
+// previous run on the same branch.
+type Breakage struct {
+	Run  *Run
+	Test *Test
+}
+
+// Breakages returns the tests that started failing in runs, oldest
+// first. Each branch is followed on its own.
+func Breakages(runs []*Run) []*Breakage {
+	var list []*Breakage
+	last := map[string]string{}
+	for _, r := range runs {
+		for _, t := range r.Tests {
+			key := r.Branch + "\x00" + t.Package + "\x00" + t.Test
+			if t.Action == events.ActionFail && last[key] != events.ActionFail {
+				list = append(list, &Breakage{Run: r, Test: t})
+			}
+			last[key] = t.Action
+		}
+	}
+	return list
+}
+
+func Median(list []float64) float64 {
+	if len(list) < 1 {
+		return 0
+	}
+	sorted := append([]float64(nil), list...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// MedianElapsed is the median duration of the passing runs of a test.
+func MedianElapsed(runs []*Run, pkg, name string) (float64, bool) {
+	var list []float64
+	for _, r := range runs {
+		if t := r.Test(pkg, name); t != nil && t.Action == events.ActionPass {
+			list = append(list, t.Elapsed)
+		}
+	}
+	if len(list) < DriftSamples {
+		return 0, false
+	}
+	return Median(list), true
+}
+
+// Options control how Annotate compares a report with its history.
+type Options struct {
+	// Commit is recorded as first failure for tests that just started failing.
+	Commit string
+	// DriftFactor is how many times slower than its median a test must be to be flagged.
+	DriftFactor float64
+	// DriftMin is the duration below which drift is ignored.
+	DriftMin time.Duration
+}
+
+// Drifted reports whether elapsed is significantly slower than med.
+func (o *Options) Drifted(elapsed, med float64) bool {
+	return elapsed >= o.DriftMin.Seconds() && med > 0 && elapsed >= med*o.DriftFactor
+}
+
+// Annotate marks the tests of ti with what the previous runs know about
+// them. Tests failing for the first time get ti.RunID as FirstFailed.
+func Annotate(ti *report.TestInfo, runs []*Run, o *Options) {
+	for _, tp := range ti.TpList {
+		for _, u := range tp.TEList {
+			if u.Action == events.ActionPass {
+				med, ok := MedianElapsed(runs, tp.Package, u.Test)
+				if ok && o.Drifted(u.Elapsed, med) {
+					u.Median = time.Duration(med * float64(time.Second)).String()
+					u.Drift = fmt.Sprintf("%.1fx", u.Elapsed/med)
+					tp.Drifted++
+				}
+			}
+			if u.Action != events.ActionFail {
+				continue
+			}
+			if r := FirstFailed(runs, tp.Package, u.Test); r != nil {
+				u.FirstFailed, u.FirstFailedCommit = r.ID, r.Commit
+				continue
+			}
+			u.FirstFailed, u.FirstFailedCommit = ti.RunID, o.Commit
+		}
+	}
+}
+
+// Trend returns the last TrendRuns runs of branch and of the other
+// branches that ran most recently, up to TrendBranches in all, oldest
+// first.
+func Trend(runs []*Run, branch string) []*report.TrendRun {
+	branches := map[string]bool{branch: true}
+	for i := len(runs) - 1; i >= 0 && len(branches) < TrendBranches; i-- {
+		branches[runs[i].Branch] = true
+	}
+	left := map[string]int{}
+	var list []*report.TrendRun
+	for i := len(runs) - 1; i >= 0; i-- {
+		r := runs[i]
+		if !branches[r.Branch] || left[r.Branch] >= TrendRuns {
+			continue
+		}
+		left[r.Branch]++
+		list = append(list, &report.TrendRun{ID: r.ID, Branch: r.Branch, PassRate: r.PassRate(), Fail: r.Fail, Elapsed: r.Elapsed})
+	}
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list
+}
+
+type Flaky struct {
+	Package string  `json:"package"`
+	Test    string  `json:"test"`
+	Runs    int     `json:"runs"`
+	Fails   int     `json:"fails"`
+	Flips   int     `json:"flips"`
+	Score   float64 `json:"score"`
+}
+
+// FlakyRanking ranks tests by how often their status flipped between
+// pass and fail across consecutive runs.
+func FlakyRanking(runs []*Run) []*Flaky {
+	m := map[string]*Flaky{}
+	last := map[string]string{}
+	var list []*Flaky
+	for _, r := range runs {
+		for _, t := range r.Tests {
+			if t.Action != events.ActionPass && t.Action != events.ActionFail {
+				continue
+			}
+			key := t.Package + "\x00" + t.Test
+			f, ok := m[key]
+			if !ok {
+				f = &Flaky{Package: t.Package, Test: t.Test}
+				m[key] = f
+				list = append(list, f)
+			}
+			f.Runs++
+			if t.Action == events.ActionFail {
+				f.Fails++
+			}
+			if prev, ok := last[key]; ok && prev != t.Action {
+				f.Flips++
+			}
+			last[key] = t.Action
+		}
+	}
+	ranked := list[:0]
+	for _, f := range list {
+		if f.Flips < 1 {
+			continue
+		}
+		f.Score = float64(f.Flips) / float64(f.Runs-1)
+		ranked = append(ranked, f)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Flips > ranked[j].Flips
+	})
+	return ranked
+}
@@ -0,0 +1,309 @@
+package history
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var now = time.Date(2022, 1, 23, 16, 58, 49, 0, time.UTC)
+
+// run returns a run of branch that started daysAgo days before now.
+func run(id, branch string, daysAgo int, tests ...*Test) *Run {
+	return &Run{ID: id, Branch: branch, Time: now.AddDate(0, 0, -daysAgo), Tests: tests}
+}
+
+func ids(runs []*Run) []string {
+	var list []string
+	for _, r := range runs {
+		list = append(list, r.ID)
+	}
+	return list
+}
+
+func TestPrune(t *testing.T) {
+	runs := []*Run{
+		run("1", "main", 40),
+		run("2", "dev", 20),
+		run("3", "main", 10),
+		run("4", "main", 5),
+		run("5", "dev", 1),
+	}
+	for _, c := range []struct {
+		name string
+		r    Retention
+		want []string
+	}{
+		{"all", Retention{}, []string{"1", "2", "3", "4", "5"}},
+		{"per branch", Retention{PerBranch: 1}, []string{"4", "5"}},
+		{"max age", Retention{MaxAge: 15 * 24 * time.Hour}, []string{"3", "4", "5"}},
+		{"both", Retention{PerBranch: 2, MaxAge: 30 * 24 * time.Hour}, []string{"2", "3", "4", "5"}},
+		{"one branch", Retention{PerBranch: 1, Branch: "main"}, []string{"2", "4", "5"}},
+	} {
+		if got := ids(Prune(runs, c.r, now)); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestNewRun(t *testing.T) {
+	// A report dated by SOURCE_DATE_EPOCH has the same time every run.
+	ti := &report.TestInfo{Time: &now, Count: &report.Count{}}
+	r := NewRun(ti, "main", "abc", "")
+	if r.ID == RunID(now) || !r.Time.After(now) {
+		t.Errorf("got run %s at %v from the time of the report", r.ID, r.Time)
+	}
+	ti.RunID = "4"
+	if r := NewRun(ti, "main", "abc", ""); r.ID != "4" {
+		t.Errorf("got run %s, want the id of the report", r.ID)
+	}
+}
+
+func TestNewRunBenchmarks(t *testing.T) {
+	// One passed test and a package running only benchmarks.
+	ti := &report.TestInfo{Count: &report.Count{Total: 3, Pass: 1, Bench: 2}}
+	r := NewRun(ti, "main", "abc", "")
+	if got := r.PassRate(); got != 100 {
+		t.Errorf("got pass rate %v, want 100", got)
+	}
+	if got := r.TestInfo().PassRate(); got != 1 {
+		t.Errorf("got report pass rate %v, want 1", got)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.jsonl")
+	runs := []*Run{run("1", "main", 2), run("2", "dev", 1)}
+	for _, r := range runs {
+		if err := Append(path, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Rewrite(path, runs[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := Append(path, run("3", "main", 0)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"2", "3"}; !reflect.DeepEqual(ids(got), want) {
+		t.Errorf("got %v, want %v", ids(got), want)
+	}
+	if entries, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*")); len(entries) != 1 {
+		t.Errorf("left %v behind", entries)
+	}
+}
+
+func TestPruneFileKeepsAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.jsonl")
+	for i := 0; i < 5; i++ {
+		if err := Append(path, run(fmt.Sprint("old", i), "main", 40)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			if err := Append(path, run(fmt.Sprint(i), "main", 0)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := PruneFile(path, Retention{MaxAge: 24 * time.Hour}, now); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := PruneFile(path, Retention{MaxAge: 24 * time.Hour}, now); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 20 {
+		t.Errorf("got runs %v, want the 20 appended", ids(got))
+	}
+}
+
+func TestTrend(t *testing.T) {
+	var runs []*Run
+	for i := 0; i < TrendRuns+2; i++ {
+		runs = append(runs, run(fmt.Sprintf("main%d", i), "main", 0))
+	}
+	for i := 0; i < TrendBranches+1; i++ {
+		runs = append(runs, run(fmt.Sprintf("b%d", i), fmt.Sprintf("b%d", i), 0))
+	}
+	branches := map[string]int{}
+	for _, r := range Trend(runs, "main") {
+		branches[r.Branch]++
+	}
+	if len(branches) != TrendBranches || branches["main"] != TrendRuns || branches["b0"] > 0 {
+		t.Errorf("got %v", branches)
+	}
+	if list := Trend(runs, "main"); list[0].ID != "main2" || list[len(list)-1].ID != fmt.Sprintf("b%d", TrendBranches) {
+		t.Errorf("got %s to %s", list[0].ID, list[len(list)-1].ID)
+	}
+}
+
+// test returns the result of TestX of package a.
+func test(action string, elapsed float64) *Test {
+	return &Test{Package: "a", Test: "TestX", Action: action, Elapsed: elapsed}
+}
+
+func TestFirstFailed(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		actions []string
+		want    string
+	}{
+		{"passing", []string{"fail", "pass"}, ""},
+		{"new failure", []string{"pass", "fail"}, "2"},
+		{"streak", []string{"fail", "pass", "fail", "fail", "fail"}, "3"},
+		{"always failed", []string{"fail", "fail"}, "1"},
+		{"did not run", []string{"fail", "", "fail"}, "3"},
+		{"no runs", nil, ""},
+	} {
+		var runs []*Run
+		for i, a := range c.actions {
+			r := run(fmt.Sprint(i+1), "main", 0)
+			if len(a) > 0 {
+				r.Tests = []*Test{test(a, 1)}
+			}
+			runs = append(runs, r)
+		}
+		got := ""
+		if r := FirstFailed(runs, "a", "TestX"); r != nil {
+			got = r.ID
+		}
+		if got != c.want {
+			t.Errorf("%s: got run %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestBreakages(t *testing.T) {
+	runs := []*Run{
+		run("1", "main", 0, test("fail", 1)),
+		run("2", "dev", 0, test("pass", 1)),
+		run("3", "main", 0, test("fail", 1)),
+		run("4", "dev", 0, test("fail", 1)),
+		run("5", "main", 0, test("pass", 1)),
+		run("6", "main", 0, test("fail", 1)),
+	}
+	var got []string
+	for _, b := range Breakages(runs) {
+		got = append(got, b.Run.ID)
+	}
+	if want := []string{"1", "4", "6"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFlakyRanking(t *testing.T) {
+	other := func(action string) *Test { return &Test{Package: "a", Test: "TestY", Action: action} }
+	runs := []*Run{
+		run("1", "main", 0, test("pass", 1), other("pass")),
+		run("2", "main", 0, test("fail", 1), other("pass")),
+		run("3", "main", 0, test("pass", 1), other("fail")),
+		run("4", "main", 0, test("skip", 1), other("fail")),
+		run("5", "main", 0, test("fail", 1), other("fail")),
+	}
+	got := FlakyRanking(runs)
+	want := []*Flaky{
+		{Package: "a", Test: "TestX", Runs: 4, Fails: 2, Flips: 3, Score: 1},
+		{Package: "a", Test: "TestY", Runs: 5, Fails: 3, Flips: 1, Score: 0.25},
+	}
+	if !reflect.DeepEqual(got, want) {
+		for _, f := range got {
+			t.Logf("%+v", f)
+		}
+		t.Errorf("got %d flaky tests, want %d", len(got), len(want))
+	}
+	if got := FlakyRanking(runs[:1]); len(got) > 0 {
+		t.Errorf("single run: got %d flaky tests", len(got))
+	}
+}
+
+func TestMedianElapsed(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		list []*Test
+		want float64
+		ok   bool
+	}{
+		{"too few", []*Test{test("pass", 1), test("pass", 2)}, 0, false},
+		{"odd", []*Test{test("pass", 3), test("pass", 1), test("pass", 2)}, 2, true},
+		{"even", []*Test{test("pass", 4), test("pass", 1), test("pass", 2), test("pass", 3)}, 2.5, true},
+		{"failures ignored", []*Test{test("pass", 1), test("fail", 9), test("pass", 1), test("pass", 1)}, 1, true},
+		{"not run", []*Test{nil, test("pass", 1), test("pass", 1)}, 0, false},
+	} {
+		var runs []*Run
+		for i, u := range c.list {
+			r := run(fmt.Sprint(i+1), "main", 0)
+			if u != nil {
+				r.Tests = []*Test{u}
+			}
+			runs = append(runs, r)
+		}
+		got, ok := MedianElapsed(runs, "a", "TestX")
+		if got != c.want || ok != c.ok {
+			t.Errorf("%s: got %g, %v, want %g, %v", c.name, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	runs := []*Run{
+		run("1", "main", 3, test("pass", 1), &Test{Package: "a", Test: "TestOld", Action: "fail"}),
+		run("2", "main", 2, test("pass", 1), &Test{Package: "a", Test: "TestOld", Action: "fail"}),
+		run("3", "main", 1, test("pass", 1), &Test{Package: "a", Test: "TestOld", Action: "fail"}),
+	}
+	ti := &report.TestInfo{Time: &now, RunID: "4"}
+	tp := &report.TestPkg{TestUt: &report.TestUt{}, Count: &report.Count{}}
+	tp.Package = "a"
+	for _, c := range []struct {
+		name, action string
+		elapsed      float64
+	}{
+		{"TestX", "pass", 3},
+		{"TestOld", "fail", 0},
+		{"TestNew", "fail", 0},
+		{"TestFast", "pass", 0.01},
+	} {
+		u := &report.TestUt{}
+		u.Package, u.Test, u.Action, u.Elapsed = "a", c.name, c.action, c.elapsed
+		tp.TEList = append(tp.TEList, u)
+	}
+	ti.TpList = []*report.TestPkg{tp}
+	Annotate(ti, runs, &Options{Commit: "abc", DriftFactor: 2, DriftMin: 100 * time.Millisecond})
+
+	for _, c := range []struct {
+		u                                     *report.TestUt
+		drift, firstFailed, firstFailedCommit string
+	}{
+		{tp.TEList[0], "3.0x", "", ""},
+		{tp.TEList[1], "", "1", ""},
+		{tp.TEList[2], "", "4", "abc"},
+		{tp.TEList[3], "", "", ""},
+	} {
+		if c.u.Drift != c.drift || c.u.FirstFailed != c.firstFailed || c.u.FirstFailedCommit != c.firstFailedCommit {
+			t.Errorf("%s: got drift %q, first failed %q at %q", c.u.Test, c.u.Drift, c.u.FirstFailed, c.u.FirstFailedCommit)
+		}
+	}
+	if tp.Drifted != 1 {
+		t.Errorf("got %d drifted", tp.Drifted)
+	}
+}
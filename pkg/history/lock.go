@@ -0,0 +1,42 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// The lock of a history file is a file next to it that only one process
+// can create. Locks older than lockStale were left by a process that
+// died and are broken.
+const (
+	lockWait  = 10 * time.Second
+	lockPoll  = 10 * time.Millisecond
+	lockStale = time.Minute
+)
+
+// lock takes the lock of the history file at path, waiting for other
+// processes to release it, and returns the function releasing it.
+func lock(path string) (unlock func(), err error) {
+	name := path + ".lock"
+	deadline := time.Now().Add(lockWait)
+	for {
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(name) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if fi, err := os.Stat(name); err == nil && time.Since(fi.ModTime()) > lockStale {
+			os.Remove(name)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s: locked by another process; remove %s if none is running", path, name)
+		}
+		time.Sleep(lockPoll)
+	}
+}
@@ -0,0 +1,109 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Retention limits the runs kept in a history file. Zero fields keep all
+// runs.
+type Retention struct {
+	// PerBranch is the number of latest runs kept of every branch.
+	PerBranch int
+	// MaxAge drops the runs older than it.
+	MaxAge time.Duration
+	// Branch limits the pruning to the runs of one branch.
+	Branch string
+}
+
+// Prune returns the runs r keeps as of now, in their order.
+func Prune(runs []*Run, r Retention, now time.Time) []*Run {
+	left := map[string]int{}
+	keep := make([]bool, len(runs))
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		if len(r.Branch) > 0 && run.Branch != r.Branch {
+			keep[i] = true
+			continue
+		}
+		if r.MaxAge > 0 && now.Sub(run.Time) > r.MaxAge {
+			continue
+		}
+		if r.PerBranch > 0 {
+			if left[run.Branch] >= r.PerBranch {
+				continue
+			}
+			left[run.Branch]++
+		}
+		keep[i] = true
+	}
+	var list []*Run
+	for i, run := range runs {
+		if keep[i] {
+			list = append(list, run)
+		}
+	}
+	return list
+}
+
+// PruneFile applies r as of now to the history file at path and returns
+// the number of runs dropped. The file is locked from reading it to
+// replacing it, so runs other processes append meanwhile are kept.
+func PruneFile(path string, r Retention, now time.Time) (int, error) {
+	unlock, err := lock(path)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	runs, err := Read(path)
+	if err != nil {
+		return 0, err
+	}
+	kept := Prune(runs, r, now)
+	if len(kept) == len(runs) {
+		return 0, nil
+	}
+	return len(runs) - len(kept), rewrite(path, kept)
+}
+
+// Rewrite replaces the history file at path with runs. The file is
+// locked, as by Append, and renamed into place so it is never read half
+// written.
+func Rewrite(path string, runs []*Run) error {
+	unlock, err := lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return rewrite(path, runs)
+}
+
+func rewrite(path string, runs []*Run) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	for _, r := range runs {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(b.Bytes())
+	if err == nil {
+		err = f.Chmod(0o644)
+	}
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,93 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// AsciiDoc writes ti as an AsciiDoc document: the totals and a table of
+// packages, followed by a collapsible block with the output of every
+// failure.
+func AsciiDoc(w io.Writer, ti *report.TestInfo) error {
+	b := &strings.Builder{}
+	b.WriteString("= Test report\n:toc:\n\n")
+	if ti.Time != nil {
+		fmt.Fprintf(b, "Generated %s.\n", ti.Time.Format("2006-01-02 15:04:05 MST"))
+	}
+	if ti.Incomplete {
+		b.WriteString("\nWARNING: The run was interrupted, the report is incomplete.\n")
+	}
+	if ti.MalformedLines > 0 {
+		fmt.Fprintf(b, "\nNOTE: %d lines of the stream were not JSON.\n", ti.MalformedLines)
+	}
+	b.WriteString("\n[%header,cols=\"5*\"]\n|===\n|Tests |Passed |Failed |Skipped |Pass rate\n")
+	fmt.Fprintf(b, "|%d |%d |%d |%d |%.1f%%\n|===\n", ti.Total, ti.Pass, ti.Fail, ti.Skip, ti.PassRate()*100)
+
+	b.WriteString("\n== Packages\n\n[%header,cols=\"4,1,1,1,1,1,1\"]\n|===\n|Package |Result |Tests |Passed |Failed |Skipped |Duration\n")
+	for _, tp := range ti.TpList {
+		name := adocLiteral(tp.Package)
+		if len(tp.File) > 0 {
+			name = "link:" + adocTarget(tp.File) + "[" + name + "]"
+		}
+		status := StatusSymbols.Status(tp.Action)
+		if tp.NoTests {
+			status = StatusSymbols.Status(events.ActionSkip) + " (no tests to run)"
+		}
+		if tp.BuildFailed {
+			status += " (build failed)"
+		}
+		fmt.Fprintf(b, "|%s |%s |%d |%d |%d |%d |%s\n", adocCell(name), adocCell(status), tp.Total, tp.Pass, tp.Fail, tp.Skip, seconds(tp.Elapsed))
+	}
+	b.WriteString("|===\n")
+
+	var failures strings.Builder
+	for _, tp := range ti.TpList {
+		if tp.Action == events.ActionFail && tp.Fail < 1 {
+			adocFailure(&failures, adocLiteral(tp.Package), tp.Output.String())
+		}
+		for _, u := range tp.TEList {
+			if u.Action == events.ActionFail {
+				adocFailure(&failures, adocLiteral(tp.Package)+" "+adocLiteral(u.Test), u.Output.String())
+			}
+		}
+	}
+	if failures.Len() > 0 {
+		b.WriteString("\n== Failures\n")
+		b.WriteString(failures.String())
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// adocFailure writes a collapsible block titled title with output in a
+// listing whose delimiter is longer than any dash line of the output.
+func adocFailure(b *strings.Builder, title, output string) {
+	delim := "----"
+	for _, l := range strings.Split(output, "\n") {
+		if strings.Trim(l, "-") == "" && len(l) >= len(delim) {
+			delim = l + "-"
+		}
+	}
+	fmt.Fprintf(b, "\n.%s\n[%%collapsible]\n====\n%s\n%s\n%s\n====\n", title, delim, strings.TrimSuffix(output, "\n"), delim)
+}
+
+// adocLiteral shows s in monospace without interpreting it as markup.
+func adocLiteral(s string) string {
+	if !strings.Contains(s, "+") {
+		return "`+" + s + "+`"
+	}
+	return "`pass:c[" + strings.ReplaceAll(s, "]", `\]`) + "]`"
+}
+
+func adocTarget(s string) string {
+	return strings.NewReplacer(" ", "%20", "[", "%5B", "]", "%5D").Replace(s)
+}
+
+// adocCell keeps | from ending a table cell.
+func adocCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,186 @@
+package render
+
+import (
+	"regexp"
+	"strings"
+)
+
+// textDiff is an expected/actual difference found in the output of a
+// test, shown colored above the raw output.
+type textDiff struct {
+	Title string
+	Lines []diffLine
+}
+
+// diffLine is a line of a textDiff; Kind is add, del, hunk or ctx.
+type diffLine struct {
+	Kind string
+	Text string
+}
+
+var cmpHeader = regexp.MustCompile(`\(-\w+ \+\w+\):\s*$`)
+
+// maxDiffLines bounds the got and want blocks of examples compared line
+// by line.
+const maxDiffLines = 1000
+
+// diffs extracts the unified diffs testify and similar libraries print,
+// the -want +got reports of go-cmp and the got/want blocks of failed
+// examples from s.
+func diffs(s string) []textDiff {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	var ds []textDiff
+	for i := 0; i < len(lines); i++ {
+		var d *textDiff
+		var n int
+		switch {
+		case unifiedStart(lines, i):
+			d, n = unified(lines, i)
+		case cmpHeader.MatchString(lines[i]):
+			d, n = cmpDiff(lines, i)
+		case lines[i] == "got:":
+			d, n = exampleDiff(lines, i)
+		}
+		if d != nil {
+			ds = append(ds, *d)
+			i += n - 1
+		}
+	}
+	return ds
+}
+
+// unifiedStart reports whether a --- line at lines[i] is followed by a
+// +++ line at the same column.
+func unifiedStart(lines []string, i int) bool {
+	col := strings.Index(lines[i], "--- ")
+	if col < 0 || strings.TrimSpace(lines[i][:col]) != "" || i+1 >= len(lines) {
+		return false
+	}
+	next := lines[i+1]
+	return len(next) > col+4 && next[col:col+4] == "+++ " && strings.TrimSpace(next[:col]) == ""
+}
+
+// unified reads the diff starting at lines[i] up to the first line that
+// is not indented to its column.
+func unified(lines []string, i int) (*textDiff, int) {
+	col := strings.Index(lines[i], "--- ")
+	d := &textDiff{Title: strings.TrimSpace(lines[i][col+4:]) + " / " + strings.TrimSpace(lines[i+1][col+4:])}
+	n := 2
+	for ; i+n < len(lines); n++ {
+		l := lines[i+n]
+		if len(l) <= col || strings.TrimSpace(l[:col]) != "" {
+			break
+		}
+		d.Lines = append(d.Lines, diffLine{Kind: lineKind(l[col:], true), Text: l[col:]})
+	}
+	return d, n
+}
+
+// cmpDiff reads the lines indented under a go-cmp header at lines[i].
+func cmpDiff(lines []string, i int) (*textDiff, int) {
+	indent := len(lines[i]) - len(strings.TrimLeft(lines[i], " \t"))
+	var body []string
+	for _, l := range lines[i+1:] {
+		if len(l)-len(strings.TrimLeft(l, " \t")) <= indent {
+			break
+		}
+		body = append(body, l)
+	}
+	// Lines with a - or + marker end the common indentation.
+	common := -1
+	for _, l := range body {
+		w := len(l) - len(strings.TrimLeft(l, " \t"))
+		if common < 0 || w < common {
+			common = w
+		}
+	}
+	d := &textDiff{Title: strings.TrimSpace(lines[i])}
+	changed := false
+	for _, l := range body {
+		kind := lineKind(l[common:], false)
+		changed = changed || kind != "ctx"
+		d.Lines = append(d.Lines, diffLine{Kind: kind, Text: l[common:]})
+	}
+	if !changed {
+		return nil, 1
+	}
+	return d, len(body) + 1
+}
+
+// exampleDiff compares the got and want blocks go test prints for a
+// failed example, starting with the got: line at lines[i].
+func exampleDiff(lines []string, i int) (*textDiff, int) {
+	j := i + 1
+	for j < len(lines) && lines[j] != "want:" {
+		j++
+	}
+	if j >= len(lines) {
+		return nil, 1
+	}
+	k := j + 1
+	for k < len(lines) && !strings.HasPrefix(lines[k], "--- ") && !strings.HasPrefix(lines[k], "=== ") {
+		k++
+	}
+	got, want := lines[i+1:j], lines[j+1:k]
+	d := &textDiff{Title: "-want +got"}
+	d.Lines = lineDiff(want, got)
+	return d, k - i
+}
+
+func lineKind(l string, hunks bool) string {
+	switch {
+	case hunks && strings.HasPrefix(l, "@@"):
+		return "hunk"
+	case strings.HasPrefix(l, "-"):
+		return "del"
+	case strings.HasPrefix(l, "+"):
+		return "add"
+	}
+	return "ctx"
+}
+
+// lineDiff returns the lines of a turned into b, using the longest
+// common subsequence of their lines unless either is too long.
+func lineDiff(a, b []string) []diffLine {
+	var out []diffLine
+	if len(a) > maxDiffLines || len(b) > maxDiffLines {
+		for _, l := range a {
+			out = append(out, diffLine{Kind: "del", Text: "-" + l})
+		}
+		for _, l := range b {
+			out = append(out, diffLine{Kind: "add", Text: "+" + l})
+		}
+		return out
+	}
+	// lcs[i][j] is the length of the common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, diffLine{Kind: "ctx", Text: " " + a[i]})
+			i, j = i+1, j+1
+		case j >= len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, diffLine{Kind: "del", Text: "-" + a[i]})
+			i++
+		default:
+			out = append(out, diffLine{Kind: "add", Text: "+" + b[j]})
+			j++
+		}
+	}
+	return out
+}
@@ -0,0 +1,287 @@
+// Filters the tests by a search of their name, package and output, and
+// by status. Subtests are collapsed under their parent, and the tests of
+// passing packages under the package, unless a filter is set, which
+// shows the tests that match and their parents. Large
+// outputs are shown in a log viewer. The theme button overrides the light
+// or dark theme of the system. Live pages reload when told to.
+(function () {
+	// t translates key, replacing each %d with the next of args.
+	const messages = JSON.parse(document.getElementById("messages").textContent);
+	function t(key, ...args) {
+		return (messages[key] || key).replace(/%d/g, () => args.shift());
+	}
+
+	const filter = document.getElementById("filter");
+	const status = document.getElementById("status");
+	const slow = document.getElementById("slow");
+	const slowLabel = document.getElementById("slow-label");
+	const shown = document.getElementById("shown");
+	const rows = document.querySelectorAll(".ut");
+
+	// The lower case text searched for each package and test: its name
+	// and output, built on first use.
+	const text = new Map();
+	function search(el, name, output) {
+		let s = text.get(el);
+		if (s === undefined) {
+			s = (name + "\n" + (output ? logText(output) : "")).toLowerCase();
+			text.set(el, s);
+		}
+		return s;
+	}
+
+	// logText returns the text of a pre, or of the JSON string of a large
+	// output.
+	function logText(el) {
+		return el.tagName === "SCRIPT" ? JSON.parse(el.textContent) : el.textContent;
+	}
+
+	function matches(row) {
+		switch (status.value) {
+		case "fail":
+		case "skip":
+			return row.dataset.action === status.value;
+		case "slow":
+			return "drift" in row.dataset || Number(row.dataset.elapsed) >= Number(slow.value);
+		case "flaky":
+			return "flaky" in row.dataset;
+		}
+		return true;
+	}
+
+	// expanded holds the rows whose subtests are shown.
+	const expanded = new Set();
+
+	function apply() {
+		const q = filter.value.toLowerCase();
+		const filtering = q !== "" || status.value !== "";
+		slowLabel.classList.toggle("hidden", status.value !== "slow");
+		document.body.classList.toggle("filtering", filtering);
+		let n = 0;
+		let kept = 0;
+		const pkgs = document.querySelectorAll(".pkg");
+		for (const pkg of pkgs) {
+			const pkgMatch = !q || search(pkg, pkg.dataset.package, pkg.querySelector(":scope > details > pre, :scope > .log > script")).includes(q);
+			const byTest = new Map();
+			const show = new Set();
+			for (const row of pkg.querySelectorAll(".ut")) {
+				byTest.set(row.dataset.test, row);
+				if (filtering && matches(row) && (pkgMatch || search(row, row.dataset.test, row.querySelector("details > pre, .log script")).includes(q))) {
+					n++;
+					for (let r = row; r && !show.has(r); r = byTest.get(r.dataset.parent)) {
+						show.add(r);
+					}
+				}
+			}
+			for (const row of pkg.querySelectorAll(".ut")) {
+				const parent = byTest.get(row.dataset.parent);
+				const hide = filtering ? !show.has(row) : parent && (!expanded.has(parent) || parent.classList.contains("collapsed"));
+				row.classList.toggle("collapsed", Boolean(hide));
+			}
+			// Keep packages without tests, such as build failures, that match.
+			const keep = !filtering || show.size > 0 || (pkgMatch && !pkg.querySelector(".ut") && (!status.value || pkg.dataset.action === status.value));
+			pkg.classList.toggle("hidden", !keep);
+			if (keep) {
+				kept++;
+			}
+		}
+		if (!filtering) {
+			shown.textContent = "";
+		} else if (rows.length > 0) {
+			shown.textContent = t("%d of %d tests", n, rows.length);
+		} else {
+			shown.textContent = t("%d of %d packages", kept, pkgs.length);
+		}
+	}
+
+	// fold shows or hides the tests and output of pkg.
+	function fold(pkg, folded) {
+		pkg.classList.toggle("folded", folded);
+		const button = pkg.querySelector(".fold");
+		button.setAttribute("aria-expanded", !folded);
+		button.textContent = folded ? "▸" : "▾";
+	}
+
+	document.addEventListener("click", e => {
+		const folder = e.target.closest(".fold");
+		if (folder) {
+			const pkg = folder.closest(".pkg");
+			fold(pkg, !pkg.classList.contains("folded"));
+			return;
+		}
+		const button = e.target.closest(".toggle");
+		if (!button) {
+			return;
+		}
+		const row = button.closest(".ut");
+		const open = !expanded.has(row);
+		if (open) {
+			expanded.add(row);
+		} else {
+			expanded.delete(row);
+		}
+		button.setAttribute("aria-expanded", open);
+		button.textContent = open ? "▾" : "▸";
+		apply();
+	});
+
+	// Links to a test, whose id is package:test, expand its parents and
+	// output and clear the filters hiding it.
+	function reveal() {
+		const el = document.getElementById(decodeURIComponent(location.hash.slice(1)));
+		if (!el) {
+			return;
+		}
+		if (el.classList.contains("pkg") && el.querySelector(".fold")) {
+			fold(el, false);
+		}
+		if (el.classList.contains("ut")) {
+			const pkg = el.closest(".pkg");
+			fold(pkg, false);
+			for (let row = el; row && row.dataset.parent; ) {
+				row = document.getElementById(pkg.dataset.package + ":" + row.dataset.parent);
+				if (row) {
+					expanded.add(row);
+					const button = row.querySelector(".toggle");
+					button.setAttribute("aria-expanded", true);
+					button.textContent = "▾";
+				}
+			}
+			const output = el.querySelector("details");
+			if (output) {
+				output.open = true;
+			}
+		}
+		filter.value = "";
+		status.value = "";
+		apply();
+		el.scrollIntoView();
+	}
+	window.addEventListener("hashchange", reveal);
+
+	// The breadcrumbs of a package link to the index filtered by ?q=.
+	const q = new URLSearchParams(location.search).get("q");
+	if (q) {
+		filter.value = q;
+		apply();
+	}
+	reveal();
+
+	// Large outputs are kept as JSON strings and only the lines scrolled
+	// to are put into the document, once the output is opened.
+	const lineHeight = 16;
+	const overscan = 20;
+	function logViewer(details) {
+		const lines = logText(details.querySelector("script")).replace(/\n$/, "").split("\n");
+		const view = document.createElement("div");
+		view.className = "logview";
+		const find = document.createElement("input");
+		find.type = "search";
+		find.placeholder = t("Search output");
+		const found = document.createElement("span");
+		const scroll = document.createElement("div");
+		scroll.className = "logscroll";
+		const body = document.createElement("div");
+		body.style.height = lines.length * lineHeight + "px";
+		const visible = document.createElement("div");
+		visible.className = "loglines";
+		body.append(visible);
+		scroll.append(body);
+		view.append(find, found, scroll);
+		details.append(view);
+
+		let hits = [];
+		let current = -1;
+		function draw() {
+			const first = Math.max(0, Math.floor(scroll.scrollTop / lineHeight) - overscan);
+			const last = Math.min(lines.length, Math.ceil((scroll.scrollTop + scroll.clientHeight) / lineHeight) + overscan);
+			const hit = new Set(hits);
+			visible.style.top = first * lineHeight + "px";
+			visible.textContent = "";
+			for (let i = first; i < last; i++) {
+				const line = document.createElement("div");
+				if (hit.has(i)) {
+					line.className = i === hits[current] ? "hit current" : "hit";
+				}
+				const no = document.createElement("span");
+				no.className = "lineno";
+				no.textContent = i + 1;
+				line.append(no, lines[i]);
+				visible.append(line);
+			}
+		}
+		function show(i) {
+			current = (i + hits.length) % hits.length;
+			found.textContent = t("%d of %d", current + 1, hits.length);
+			scroll.scrollTop = hits[current] * lineHeight - scroll.clientHeight / 2;
+			draw();
+		}
+		let frame = 0;
+		scroll.addEventListener("scroll", () => {
+			cancelAnimationFrame(frame);
+			frame = requestAnimationFrame(draw);
+		});
+		find.addEventListener("input", () => {
+			const q = find.value.toLowerCase();
+			hits = [];
+			if (q) {
+				lines.forEach((line, i) => {
+					if (line.toLowerCase().includes(q)) {
+						hits.push(i);
+					}
+				});
+			}
+			current = -1;
+			found.textContent = q ? t("%d lines", hits.length) : "";
+			if (hits.length > 0) {
+				show(0);
+			} else {
+				draw();
+			}
+		});
+		find.addEventListener("keydown", e => {
+			if (e.key === "Enter" && hits.length > 0) {
+				show(current + (e.shiftKey ? -1 : 1));
+			}
+		});
+		draw();
+	}
+	for (const details of document.querySelectorAll("details.log")) {
+		details.addEventListener("toggle", () => {
+			if (details.open && !details.querySelector(".logview")) {
+				logViewer(details);
+			}
+		});
+	}
+
+	// Live pages reload as packages finish.
+	if (document.body.dataset.live) {
+		const events = new EventSource(document.body.dataset.live);
+		events.onmessage = () => location.reload();
+		events.addEventListener("done", () => {
+			events.close();
+			location.reload();
+		});
+	}
+
+	const root = document.documentElement;
+	document.getElementById("theme").addEventListener("click", () => {
+		const dark = root.dataset.theme === "dark" || (!root.dataset.theme && matchMedia("(prefers-color-scheme: dark)").matches);
+		root.dataset.theme = dark ? "light" : "dark";
+		try {
+			localStorage.setItem("go-test-report-theme", root.dataset.theme);
+		} catch (e) {
+			// Storage is unavailable on some file:// pages.
+		}
+	});
+
+	let timer;
+	function later() {
+		clearTimeout(timer);
+		timer = setTimeout(apply, 150);
+	}
+
+	filter.addEventListener("input", later);
+	status.addEventListener("change", apply);
+	slow.addEventListener("input", later);
+})();
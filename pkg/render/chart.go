@@ -0,0 +1,324 @@
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// The charts of the HTML page are drawn as inline SVG, so the page needs
+// no script to show them. Sizes are in SVG user units.
+const (
+	pieRadius   = 50
+	barWidth    = 300
+	barRow      = 28
+	maxBars     = 10
+	histHeight  = 100
+	histBinSize = 46
+	trendWidth  = 270
+)
+
+type htmlCharts struct {
+	Pie []pieSlice
+	// Bars are the packages with the most failures.
+	Bars      []chartBar
+	BarHeight int
+	Hist      []chartBar
+	// Trends are the charts of the previous runs of the history, one set
+	// per branch, the branch of this run first.
+	Trends []branchTrend
+}
+
+// branchTrend are the pass rate, duration and failure charts of the
+// previous runs of a branch, and of this run if it is of the branch.
+type branchTrend struct {
+	Branch string
+	Charts []trendChart
+}
+
+// trendChart draws the values of Points as the polyline Line from 0 at
+// the bottom to Max at the top.
+type trendChart struct {
+	Title  string
+	Max    string
+	Min    string
+	Points []trendPoint
+	Line   string
+}
+
+type trendPoint struct {
+	ID    string
+	Value string
+	X, Y  float64
+}
+
+type pieSlice struct {
+	Class, Label string
+	N            int
+	// Path is empty for a slice that is the whole pie.
+	Path string
+}
+
+type chartBar struct {
+	Label string
+	N     int
+	// X and Y place the bar, W and H size it. LX, LY place the label and
+	// NX, NY the count.
+	X, Y, W, H     float64
+	LX, LY, NX, NY float64
+}
+
+// durationBins are the upper bounds of the bins of the duration
+// histogram, in seconds; the last bin is unbounded.
+var durationBins = []struct {
+	max   float64
+	label string
+}{
+	{0.01, "<10ms"},
+	{0.1, "<100ms"},
+	{1, "<1s"},
+	{10, "<10s"},
+	{60, "<1m"},
+	{math.Inf(1), "≥1m"},
+}
+
+func charts(ti *report.TestInfo) *htmlCharts {
+	c := &htmlCharts{}
+	var pass, fail, skip int
+	for _, tp := range ti.TpList {
+		pass += tp.Pass
+		fail += tp.Fail
+		skip += tp.Skip
+	}
+	c.Pie = pie([]pieSlice{
+		{Class: "pass", Label: "passed", N: pass},
+		{Class: "fail", Label: "failed", N: fail},
+		{Class: "skip", Label: "skipped", N: skip},
+	})
+
+	failed := make([]*report.TestPkg, 0, len(ti.TpList))
+	for _, tp := range ti.TpList {
+		if tp.Fail > 0 {
+			failed = append(failed, tp)
+		}
+	}
+	sort.SliceStable(failed, func(i, j int) bool {
+		return failed[i].Fail > failed[j].Fail
+	})
+	if len(failed) > maxBars {
+		failed = failed[:maxBars]
+	}
+	for i, tp := range failed {
+		b := chartBar{
+			Label: tp.Package,
+			N:     tp.Fail,
+			Y:     float64(i*barRow + 14),
+			W:     round(barWidth * float64(tp.Fail) / float64(failed[0].Fail)),
+			H:     10,
+		}
+		b.LY = b.Y - 3
+		b.NX, b.NY = b.W+4, b.Y+9
+		c.Bars = append(c.Bars, b)
+	}
+	c.BarHeight = len(c.Bars) * barRow
+
+	c.trends(ti)
+
+	counts := make([]int, len(durationBins))
+	timed := false
+	for _, tp := range ti.TpList {
+		for _, u := range tp.TEList {
+			for i, bin := range durationBins {
+				if u.Elapsed < bin.max {
+					counts[i]++
+					timed = true
+					break
+				}
+			}
+		}
+	}
+	if !timed {
+		return c
+	}
+	most := 0
+	for _, n := range counts {
+		if n > most {
+			most = n
+		}
+	}
+	for i, n := range counts {
+		h := round(histHeight * float64(n) / float64(most))
+		b := chartBar{
+			Label: durationBins[i].label,
+			N:     n,
+			X:     float64(i * histBinSize),
+			Y:     histHeight - h + 14,
+			W:     histBinSize - 6,
+			H:     h,
+		}
+		b.LX, b.LY = b.X+b.W/2, histHeight+28
+		b.NX, b.NY = b.LX, b.Y-3
+		c.Hist = append(c.Hist, b)
+	}
+	return c
+}
+
+// trends groups the runs of the trend by branch, adding ti to its own.
+func (c *htmlCharts) trends(ti *report.TestInfo) {
+	groups := trendGroups(ti.Trend, ti.Branch)
+	if len(groups) > 0 {
+		var elapsed float64
+		for _, tp := range ti.TpList {
+			elapsed += tp.Elapsed
+		}
+		groups[0] = append(groups[0], &report.TrendRun{PassRate: ti.PassRate() * 100, Fail: ti.Fail, Elapsed: elapsed})
+	}
+	c.Trends = branchTrends(groups, ti.Branch)
+}
+
+// trendGroups splits runs by branch, in the order of their first run, but
+// with branch first.
+func trendGroups(runs []*report.TrendRun, branch string) [][]*report.TrendRun {
+	if len(runs) < 1 {
+		return nil
+	}
+	groups := [][]*report.TrendRun{nil}
+	index := map[string]int{branch: 0}
+	for _, r := range runs {
+		i, ok := index[r.Branch]
+		if !ok {
+			i = len(groups)
+			index[r.Branch] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], r)
+	}
+	return groups
+}
+
+// branchTrends charts the groups of trendGroups, the first one being
+// of branch.
+func branchTrends(groups [][]*report.TrendRun, branch string) []branchTrend {
+	var list []branchTrend
+	for i, runs := range groups {
+		if len(runs) < 1 {
+			continue
+		}
+		name := runs[0].Branch
+		if i == 0 {
+			name = branch
+		}
+		list = append(list, branchTrend{Branch: name, Charts: []trendChart{
+			trendLine("Pass rate of the last runs", runs, 100, "%", func(r *report.TrendRun) float64 { return r.PassRate }),
+			trendLine("Duration of the last runs", runs, 0, "s", func(r *report.TrendRun) float64 { return r.Elapsed }),
+			trendLine("Failures of the last runs", runs, 0, "", func(r *report.TrendRun) float64 { return float64(r.Fail) }),
+		}})
+	}
+	return list
+}
+
+// TrendCharts draws the pass rate, duration and failure charts of runs,
+// as history.Trend returns them, for pages other than the report, such
+// as the history dashboard. There is a set of charts per branch, those
+// of branch first. The charts are inline SVG in sections of the class
+// charts, styled by the page.
+func TrendCharts(runs []*report.TrendRun, branch string) (template.HTML, error) {
+	err := loadHTML()
+	if err != nil {
+		return "", err
+	}
+	// Executing the shared templates would keep them from being cloned.
+	tmpl, err := htmlTmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	b := &strings.Builder{}
+	err = tmpl.ExecuteTemplate(b, "trends", branchTrends(trendGroups(runs, branch), branch))
+	return template.HTML(b.String()), err
+}
+
+// trendLine places the values of runs from left to right, top at the
+// top; a top of 0 is the largest value. The run without an ID is this
+// one.
+func trendLine(title string, runs []*report.TrendRun, top float64, unit string, value func(*report.TrendRun) float64) trendChart {
+	if top <= 0 {
+		for _, r := range runs {
+			if v := value(r); v > top {
+				top = v
+			}
+		}
+		if top <= 0 {
+			top = 1
+		}
+	}
+	label := func(v float64) string {
+		switch unit {
+		case "%":
+			return fmt.Sprintf("%.1f%%", v)
+		case "s":
+			return seconds(v)
+		}
+		return fmt.Sprintf("%g", v)
+	}
+	c := trendChart{Title: title, Max: label(top), Min: label(0), Points: make([]trendPoint, len(runs))}
+	if unit == "%" {
+		c.Max, c.Min = fmt.Sprintf("%g%%", top), "0%"
+	}
+	line := make([]string, len(runs))
+	for i, r := range runs {
+		p := &c.Points[i]
+		p.ID, p.Value = r.ID, label(value(r))
+		p.X = round(3 + trendWidth/2)
+		if len(runs) > 1 {
+			p.X = round(3 + trendWidth*float64(i)/float64(len(runs)-1))
+		}
+		p.Y = round(14 + histHeight*(1-value(r)/top))
+		line[i] = fmt.Sprintf("%g,%g", p.X, p.Y)
+	}
+	c.Line = strings.Join(line, " ")
+	return c
+}
+
+// pie drops the empty slices and draws the others clockwise from the top.
+func pie(slices []pieSlice) []pieSlice {
+	total := 0
+	for _, s := range slices {
+		total += s.N
+	}
+	var out []pieSlice
+	angle := 0.0
+	for _, s := range slices {
+		if s.N < 1 {
+			continue
+		}
+		if s.N == total {
+			out = append(out, s)
+			continue
+		}
+		next := angle + 2*math.Pi*float64(s.N)/float64(total)
+		large := 0
+		if next-angle > math.Pi {
+			large = 1
+		}
+		x0, y0 := piePoint(angle)
+		x1, y1 := piePoint(next)
+		s.Path = fmt.Sprintf("M%d,%d L%.2f,%.2f A%d,%d 0 %d 1 %.2f,%.2f Z",
+			pieRadius, pieRadius, x0, y0, pieRadius, pieRadius, large, x1, y1)
+		out = append(out, s)
+		angle = next
+	}
+	return out
+}
+
+func piePoint(angle float64) (x, y float64) {
+	return pieRadius + pieRadius*math.Sin(angle), pieRadius - pieRadius*math.Cos(angle)
+}
+
+// round rounds to tenths, which is precise enough for the charts.
+func round(v float64) float64 {
+	return math.Round(v*10) / 10
+}
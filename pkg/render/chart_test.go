@@ -0,0 +1,33 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+func TestTrendCharts(t *testing.T) {
+	runs := []*report.TrendRun{
+		{ID: "1", Branch: "dev", PassRate: 50, Fail: 1, Elapsed: 2},
+		{ID: "2", Branch: "main", PassRate: 100, Elapsed: 1},
+		{ID: "3", Branch: "main", PassRate: 90, Fail: 1, Elapsed: 3},
+	}
+	groups := branchTrends(trendGroups(runs, "main"), "main")
+	if len(groups) != 2 || groups[0].Branch != "main" || groups[1].Branch != "dev" {
+		t.Fatalf("got %+v", groups)
+	}
+	if p := groups[0].Charts[1].Points; len(p) != 2 || p[1].ID != "3" || p[1].Value != "3s" {
+		t.Errorf("main duration: got %+v", p)
+	}
+	html, err := TrendCharts(runs, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(html), "<svg"); n != 6 || strings.Index(string(html), "branch main") > strings.Index(string(html), "branch dev") {
+		t.Errorf("got %d charts in\n%s", n, html)
+	}
+	if html, err := TrendCharts(nil, ""); err != nil || len(html) > 0 {
+		t.Errorf("no runs: got %q, %v", html, err)
+	}
+}
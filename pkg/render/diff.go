@@ -0,0 +1,49 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// DiffJSON writes d as indented JSON.
+func DiffJSON(w io.Writer, d *report.Diff) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// DiffMarkdown writes d as Markdown tables suitable for PR comments.
+func DiffMarkdown(w io.Writer, d *report.Diff) error {
+	b := &strings.Builder{}
+	b.WriteString("## Test report diff\n\n")
+	fmt.Fprintf(b, "| New failures | Fixed | Newly skipped | Duration changes |\n|---|---|---|---|\n| %d | %d | %d | %d |\n",
+		len(d.NewFailures), len(d.Fixed), len(d.NewlySkipped), len(d.DurationChanges))
+	writeSection := func(title string, list []*report.DiffEntry) {
+		if len(list) < 1 {
+			return
+		}
+		fmt.Fprintf(b, "\n### %s\n\n| Package | Test | Before | After |\n|---|---|---|---|\n", title)
+		for _, e := range list {
+			old := StatusSymbols.Status(e.Old)
+			if len(old) < 1 {
+				old = "-"
+			}
+			fmt.Fprintf(b, "| `%s` | `%s` | %s | %s |\n", e.Package, e.Test, old, StatusSymbols.Status(e.New))
+		}
+	}
+	writeSection("New failures", d.NewFailures)
+	writeSection("Fixed", d.Fixed)
+	writeSection("Newly skipped", d.NewlySkipped)
+	if len(d.DurationChanges) > 0 {
+		b.WriteString("\n### Duration changes\n\n| Package | Test | Before | After | Change |\n|---|---|---|---|---|\n")
+		for _, e := range d.DurationChanges {
+			fmt.Fprintf(b, "| `%s` | `%s` | %s | %s | %+.1f%% |\n", e.Package, e.Test, e.OldDur, e.NewDur, e.Change)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,43 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// Digest writes one line per package of ti with its result, counts,
+// duration, coverage and timeout risk, for commit statuses and chat messages.
+func Digest(w io.Writer, ti *report.TestInfo) error {
+	b := &strings.Builder{}
+	for _, tp := range ti.TpList {
+		if tp.NoTests {
+			fmt.Fprintf(b, "%s %s no tests to run\n", StatusSymbols.Status(events.ActionSkip), tp.Package)
+			continue
+		}
+		if tp.BuildFailed {
+			fmt.Fprintf(b, "%s %s build failed\n", StatusSymbols.Status(events.ActionFail), tp.Package)
+			continue
+		}
+		fmt.Fprintf(b, "%s %s %d/%d passed", StatusSymbols.Status(tp.Action), tp.Package, tp.Pass, tp.Total)
+		if tp.Fail > 0 {
+			fmt.Fprintf(b, ", %d failed", tp.Fail)
+		}
+		if tp.Skip > 0 {
+			fmt.Fprintf(b, ", %d skipped", tp.Skip)
+		}
+		fmt.Fprintf(b, " in %s", seconds(tp.Elapsed))
+		if cov, ok := tp.Coverage(); ok {
+			fmt.Fprintf(b, ", coverage %.1f%%", cov)
+		}
+		if tp.TimeoutRisk {
+			fmt.Fprintf(b, ", %.0f%% of the %s timeout", tp.TimeoutUsed, ti.Timeout)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,287 @@
+package render
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+//go:embed templates assets
+var htmlFS embed.FS
+
+// HTMLRenderer writes ti as an HTML page. The page is self-contained
+// unless AssetURL is set, in which case its stylesheet and script are
+// linked from there; see WriteAssets.
+type HTMLRenderer struct {
+	AssetURL string
+	// Stylesheet is added after the default styles to override them.
+	Stylesheet string
+	// Logo is the URL of an image shown in the header. Use a data URL to
+	// keep the page self-contained.
+	Logo string
+	// Index is the URL of the index of a split report, linked from the
+	// breadcrumbs of its package reports.
+	Index string
+	// Lang is the language of the page, see Languages. The default is en.
+	Lang string
+	// Live is the URL of an event stream, sending a message whenever the
+	// page should be reloaded and a done event once it is final.
+	Live string
+}
+
+func (h HTMLRenderer) WithIndex(index string) Renderer {
+	h.Index = index
+	return h
+}
+
+// HTML writes ti as a self-contained HTML page.
+func HTML(w io.Writer, ti *report.TestInfo) error {
+	return HTMLRenderer{}.Render(w, ti)
+}
+
+func (h HTMLRenderer) Render(w io.Writer, ti *report.TestInfo) error {
+	err := loadHTML()
+	if err != nil {
+		return err
+	}
+	lang := h.Lang
+	if len(lang) < 1 {
+		lang = "en"
+	}
+	if _, ok := messages[lang]; !ok {
+		return fmt.Errorf("unknown language %q", lang)
+	}
+	t := translator(lang)
+	script := map[string]string{}
+	for _, key := range scriptMessages {
+		script[key] = t(key)
+	}
+	tmpl, err := htmlTmpl.Clone()
+	if err != nil {
+		return err
+	}
+	tmpl.Funcs(template.FuncMap{"t": t})
+	pkgs := make([]htmlPackage, len(ti.TpList))
+	index := len(ti.TpList) > 0
+	for i, tp := range ti.TpList {
+		pkgs[i] = htmlPackage{TestPkg: tp, Tests: testTree(tp.TEList), Folded: len(ti.TpList) > 1 && tp.Action == events.ActionPass}
+		index = index && len(tp.File) > 0
+	}
+	// The slow filter starts at the slow threshold of the run, if any.
+	slow := 1.0
+	if d, err := time.ParseDuration(ti.SlowThreshold); err == nil && d > 0 {
+		slow = d.Seconds()
+	}
+	var crumbs []crumb
+	if len(h.Index) > 0 && len(ti.TpList) == 1 {
+		crumbs = breadcrumbs(h.Index, ti.TpList[0].Package)
+	}
+	return tmpl.ExecuteTemplate(w, "report.html", struct {
+		Lang        string
+		Live        string
+		Messages    map[string]string
+		Report      *report.TestInfo
+		Packages    []htmlPackage
+		IsIndex     bool
+		Crumbs      []crumb
+		Charts      *htmlCharts
+		SlowSeconds float64
+		AssetURL    string
+		CSS         template.CSS
+		JS          template.JS
+		Stylesheet  template.CSS
+		Logo        template.URL
+	}{
+		lang, h.Live, script, ti, pkgs, index, crumbs, charts(ti), slow, h.AssetURL,
+		template.CSS(htmlAssets["report.css"]), template.JS(htmlAssets["report.js"]),
+		template.CSS(h.Stylesheet), template.URL(h.Logo),
+	})
+}
+
+// crumb is an element of the breadcrumbs of a package report. Elements
+// of the package path link to the index filtered by that path.
+type crumb struct {
+	Label, URL string
+}
+
+func breadcrumbs(index, pkg string) []crumb {
+	crumbs := []crumb{{"All packages", index}}
+	elems := strings.Split(pkg, "/")
+	for i, elem := range elems {
+		c := crumb{Label: elem}
+		if i < len(elems)-1 {
+			c.URL = index + "?q=" + url.QueryEscape(strings.Join(elems[:i+1], "/"))
+		}
+		crumbs = append(crumbs, c)
+	}
+	return crumbs
+}
+
+type htmlPackage struct {
+	*report.TestPkg
+	Tests []*htmlTest
+	// Folded hides the tests of passing packages of a report with more.
+	Folded bool
+}
+
+// htmlTest is a test in the subtest tree of its package.
+type htmlTest struct {
+	*report.TestUt
+	// Name is the last element of the test name, Parent the test it is a
+	// subtest of, if that ran.
+	Name   string
+	Parent string
+	Depth  int
+	// Sub counts the subtests, recursively; nil without subtests.
+	Sub      *report.Count
+	children []*htmlTest
+}
+
+// testTree orders tests depth first, each followed by its subtests.
+func testTree(tests []*report.TestUt) []*htmlTest {
+	nodes := map[string]*htmlTest{}
+	for _, u := range tests {
+		nodes[u.Test] = &htmlTest{TestUt: u, Name: u.Test}
+	}
+	var roots []*htmlTest
+	for _, u := range tests {
+		n := nodes[u.Test]
+		var parent *htmlTest
+		for i := strings.LastIndex(u.Test, "/"); i > 0 && parent == nil; i = strings.LastIndex(u.Test[:i], "/") {
+			parent = nodes[u.Test[:i]]
+		}
+		if parent == nil {
+			roots = append(roots, n)
+			continue
+		}
+		n.Parent, n.Name = parent.Test, u.Test[len(parent.Test)+1:]
+		parent.children = append(parent.children, n)
+	}
+	var list []*htmlTest
+	var walk func(n *htmlTest, depth int) *report.Count
+	walk = func(n *htmlTest, depth int) *report.Count {
+		n.Depth = depth
+		list = append(list, n)
+		c := &report.Count{}
+		for _, child := range n.children {
+			sub := walk(child, depth+1)
+			c.Total += sub.Total + 1
+			c.Pass += sub.Pass
+			c.Fail += sub.Fail
+			c.Skip += sub.Skip
+			switch child.Action {
+			case events.ActionPass:
+				c.Pass++
+			case events.ActionFail:
+				c.Fail++
+			case events.ActionSkip:
+				c.Skip++
+			}
+		}
+		if len(n.children) > 0 {
+			n.Sub = c
+		}
+		return c
+	}
+	for _, n := range roots {
+		walk(n, 0)
+	}
+	return list
+}
+
+// WriteAssets writes the minified stylesheet and script of the HTML page
+// to dir, for pages rendered with AssetURL pointing at it. It returns the
+// paths written.
+func WriteAssets(dir string) ([]string, error) {
+	err := loadHTML()
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for name, b := range htmlAssets {
+		path := filepath.Join(dir, name)
+		err := ioutil.WriteFile(path, b, 0666)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+var (
+	htmlOnce   sync.Once
+	htmlErr    error
+	htmlTmpl   *template.Template
+	htmlAssets map[string][]byte
+)
+
+// loadHTML parses the templates and minifies the assets once.
+func loadHTML() error {
+	htmlOnce.Do(func() {
+		htmlTmpl, htmlErr = template.New("").Funcs(template.FuncMap{
+			"dur":    seconds,
+			"output": output,
+			"t":      translator("en"),
+		}).ParseFS(htmlFS, "templates/*")
+		if htmlErr != nil {
+			return
+		}
+		entries, err := htmlFS.ReadDir("assets")
+		if err != nil {
+			htmlErr = err
+			return
+		}
+		htmlAssets = map[string][]byte{}
+		for _, entry := range entries {
+			b, err := htmlFS.ReadFile("assets/" + entry.Name())
+			if err != nil {
+				htmlErr = err
+				return
+			}
+			htmlAssets[entry.Name()] = minifiers[path.Ext(entry.Name())](b)
+		}
+	})
+	return htmlErr
+}
+
+// bigOutput is the size from which output is shown in the log viewer of
+// the page, which only puts the lines scrolled to into the document.
+const bigOutput = 64 << 10
+
+type htmlOutput struct {
+	Text  string
+	Big   bool
+	Lines int
+	Diffs []textDiff
+}
+
+func output(o *report.Output) htmlOutput {
+	s := o.String()
+	if len(s) < bigOutput {
+		return htmlOutput{Text: s, Diffs: diffs(s)}
+	}
+	return htmlOutput{Text: s, Big: true, Lines: strings.Count(strings.TrimSuffix(s, "\n"), "\n") + 1}
+}
+
+func seconds(elapsed float64) string {
+	return time.Duration(elapsed * float64(time.Second)).Round(time.Millisecond).String()
+}
@@ -0,0 +1,124 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+)
+
+// messages translates the text of the HTML page, keyed by the English
+// text, which is used as is for English and for missing translations.
+// Keys may be fmt formats.
+var messages = map[string]map[string]string{
+	"en": {},
+	"zh": {
+		"Switch between light and dark":     "切换浅色/深色主题",
+		"All packages":                      "全部包",
+		"Search tests, packages and output": "搜索测试、包和输出",
+		"all":                               "全部",
+		"failed":                            "失败",
+		"skipped":                           "跳过",
+		"passed":                            "通过",
+		"slow":                              "慢",
+		"flaky":                             "不稳定",
+		"slower than":                       "慢于",
+		"s":                                 "秒",
+		"Package":                           "包",
+		"Module":                            "模块",
+		"Test":                              "测试",
+		"Result":                            "结果",
+		"Passed":                            "通过",
+		"Failed":                            "失败",
+		"Skipped":                           "跳过",
+		"Duration":                          "耗时",
+		"Output":                            "输出",
+		"pass":                              "通过",
+		"fail":                              "失败",
+		"skip":                              "跳过",
+		"new":                               "新增",
+		"no tests to run":                   "没有可运行的测试",
+		"build failed":                      "构建失败",
+		"regression":                        "回归",
+		"panic":                             "panic",
+		"race":                              "数据竞争",
+		"timeout":                           "超时",
+		"package":                           "包",
+		"Crashes":                           "崩溃",
+		"Kind":                              "类型",
+		"%d panics":                         "%d 次 panic",
+		"%d data races":                     "%d 个数据竞争",
+		"%d timeouts":                       "%d 次超时",
+		"race detector":                     "竞态检测",
+		"%d tests:":                         "%d 个测试：",
+		"%d passed":                         "%d 通过",
+		"%d failed":                         "%d 失败",
+		"%d skipped":                        "%d 跳过",
+		"%d regressions":                    "%d 个回归",
+		"%d flaky":                          "%d 个不稳定",
+		"%d/%d passed":                      "%d/%d 通过",
+		"%d subtests":                       "%d 个子测试",
+		"Benchmark":                         "基准测试",
+		"Iterations":                        "迭代次数",
+		"Change":                            "变化",
+		"%.1f%% of statements covered":      "语句覆盖率 %.1f%%",
+		"coverage per file":                 "各文件的覆盖率",
+		"File":                              "文件",
+		"Statements":                        "语句",
+		"Covered":                           "已覆盖",
+		"Coverage":                          "覆盖率",
+		"Pass rate of the last runs":        "最近几次运行的通过率",
+		"Duration of the last runs":         "最近几次运行的耗时",
+		"Failures of the last runs":         "最近几次运行的失败数",
+		"branch %s":                         "分支 %s",
+		"this run":                          "本次运行",
+		"%d slower than %s":                 "%d 个慢于 %s",
+		"%d slowest tests":                  "最慢的 %d 个测试",
+		"%d attempts":                       "%d 次运行",
+		"%.0f%% of the timeout":             "占超时的 %.0f%%",
+		"shard %s":                          "分片 %s",
+		"The run was interrupted; this report is partial.": "运行被中断，本报告不完整。",
+		"%d lines of the stream were not JSON.":            "输入流中有 %d 行不是 JSON。",
+		"Link to this package":                             "此包的链接",
+		"Show or hide the tests of this package":           "显示或隐藏此包的测试",
+		"Link to this test":                                "此测试的链接",
+		"output":                                           "输出",
+		"output (%d lines)":                                "输出（%d 行）",
+		"Results":                                          "结果",
+		"Failures per package":                             "各包失败数",
+		"Test durations":                                   "测试耗时",
+		"Live: showing the packages finished so far":       "实时：显示目前已完成的包",
+		// Used by report.js.
+		"Search output":     "搜索输出",
+		"%d of %d tests":    "%d / %d 个测试",
+		"%d of %d packages": "%d / %d 个包",
+		"%d of %d":          "%d / %d",
+		"%d lines":          "%d 行",
+	},
+}
+
+// scriptMessages are the keys of messages used by report.js.
+var scriptMessages = []string{"Search output", "%d of %d tests", "%d of %d packages", "%d of %d", "%d lines"}
+
+// Languages returns the languages of the HTML page.
+func Languages() []string {
+	var langs []string
+	for lang := range messages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// translator returns the function translating the text of the page to
+// lang.
+func translator(lang string) func(key string, args ...interface{}) string {
+	m := messages[lang]
+	return func(key string, args ...interface{}) string {
+		if s, ok := m[key]; ok {
+			key = s
+		}
+		if len(args) < 1 {
+			return key
+		}
+		return fmt.Sprintf(key, args...)
+	}
+}
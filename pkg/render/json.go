@@ -0,0 +1,30 @@
+package render
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// JSON writes ti as an indented JSON document.
+func JSON(w io.Writer, ti *report.TestInfo) error {
+	return JSONRenderer{Indent: "\t"}.Render(w, ti)
+}
+
+// JSONRenderer writes ti as a JSON document indented by Indent, on one
+// line if it is empty.
+type JSONRenderer struct {
+	Indent string
+	// Tree nests subtests under their parents, see report.TestInfo.Tree.
+	Tree bool
+}
+
+func (r JSONRenderer) Render(w io.Writer, ti *report.TestInfo) error {
+	if r.Tree {
+		ti = ti.Tree()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", r.Indent)
+	return enc.Encode(ti)
+}
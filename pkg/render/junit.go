@@ -0,0 +1,169 @@
+package render
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// JUnit report elements, as read by Jenkins, GitLab and CircleCI. Reruns
+// follow the Maven Surefire conventions of the Jenkins Flaky Test Handler.
+type junitSuites struct {
+	XMLName  xml.Name      `xml:"testsuites"`
+	Tests    int           `xml:"tests,attr"`
+	Failures int           `xml:"failures,attr"`
+	Errors   int           `xml:"errors,attr"`
+	Skipped  int           `xml:"skipped,attr"`
+	Time     string        `xml:"time,attr"`
+	Suites   []*junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string       `xml:"name,attr"`
+	Tests     int          `xml:"tests,attr"`
+	Failures  int          `xml:"failures,attr"`
+	Errors    int          `xml:"errors,attr"`
+	Skipped   int          `xml:"skipped,attr"`
+	Time      string       `xml:"time,attr"`
+	Timestamp string       `xml:"timestamp,attr,omitempty"`
+	Cases     []*junitCase `xml:"testcase"`
+	SystemOut string       `xml:"system-out,omitempty"`
+}
+
+type junitCase struct {
+	Name      string          `xml:"name,attr"`
+	Classname string          `xml:"classname,attr"`
+	Time      string          `xml:"time,attr"`
+	Failure   *junitFailure   `xml:"failure"`
+	Error     *junitFailure   `xml:"error"`
+	Skipped   *junitFailure   `xml:"skipped"`
+	Flaky     []*junitFailure `xml:"flakyFailure"`
+	Rerun     []*junitFailure `xml:"rerunFailure"`
+	SystemOut string          `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message   string `xml:"message,attr"`
+	Type      string `xml:"type,attr,omitempty"`
+	Text      string `xml:",chardata"`
+	SystemOut string `xml:"system-out,omitempty"`
+}
+
+// JUnit writes ti as JUnit XML: packages become test suites and tests
+// test cases. A package that failed without a failed test, as on a
+// build failure or a panic, gets an error case named after the package.
+func JUnit(w io.Writer, ti *report.TestInfo) error {
+	all := &junitSuites{}
+	var elapsed float64
+	for _, tp := range ti.TpList {
+		s := &junitSuite{Name: tp.Package, Time: junitTime(tp.Elapsed)}
+		if tp.Time != nil {
+			s.Timestamp = tp.Time.Add(-time.Duration(tp.Elapsed * float64(time.Second))).Format("2006-01-02T15:04:05")
+		}
+		for _, u := range tp.TEList {
+			s.Cases = append(s.Cases, junitTestCase(tp.Package, u))
+		}
+		if tp.Action == events.ActionFail && tp.Fail < 1 {
+			out, msg := tp.Output.String(), "package failed"
+			if tp.BuildFailed {
+				msg = "build failed"
+			}
+			typ := "error"
+			if len(tp.FailureKind) > 0 {
+				typ = tp.FailureKind
+			}
+			s.Cases = append(s.Cases, &junitCase{Name: tp.Package, Classname: tp.Package, Time: junitTime(tp.Elapsed),
+				Error: &junitFailure{Message: msg, Type: typ, Text: out}})
+		} else {
+			s.SystemOut = tp.Output.String()
+		}
+		for _, c := range s.Cases {
+			s.Tests++
+			switch {
+			case c.Error != nil:
+				s.Errors++
+			case c.Failure != nil:
+				s.Failures++
+			case c.Skipped != nil:
+				s.Skipped++
+			}
+		}
+		all.Tests += s.Tests
+		all.Failures += s.Failures
+		all.Errors += s.Errors
+		all.Skipped += s.Skipped
+		elapsed += tp.Elapsed
+		all.Suites = append(all.Suites, s)
+	}
+	all.Time = junitTime(elapsed)
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	err = enc.Encode(all)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func junitTestCase(pkg string, u *report.TestUt) *junitCase {
+	c := &junitCase{Name: u.Test, Classname: pkg, Time: junitTime(u.Elapsed), SystemOut: u.Output.String()}
+	switch u.Action {
+	case events.ActionFail:
+		c.Failure = &junitFailure{Message: junitMessage(u, "Failed"), Type: "failure", Text: c.SystemOut}
+	case events.ActionSkip:
+		c.Skipped = &junitFailure{Message: junitMessage(u, "Skipped")}
+	case events.ActionPass, events.ActionBench:
+	default:
+		// Tests that never finished, such as in an interrupted run.
+		c.Error = &junitFailure{Message: "did not finish", Type: "error"}
+	}
+	// Of a test that failed in the end, the first failed attempt is the
+	// failure and the later ones reruns. Those of a test that passed in
+	// the end are flaky failures.
+	first := true
+	for _, a := range u.Attempts {
+		if a.Action != events.ActionFail {
+			continue
+		}
+		au := &report.TestUt{}
+		_, _ = au.Output.WriteString(a.Output)
+		f := &junitFailure{Message: junitMessage(au, "Failed"), Type: "failure", Text: a.Output, SystemOut: a.Output}
+		switch {
+		case u.Action != events.ActionFail:
+			c.Flaky = append(c.Flaky, f)
+		case first:
+			c.Failure = &junitFailure{Message: f.Message, Type: f.Type, Text: f.Text}
+			first = false
+		default:
+			c.Rerun = append(c.Rerun, f)
+		}
+	}
+	if c.Failure != nil && len(u.FailureKind) > 0 {
+		c.Failure.Type = u.FailureKind
+	}
+	return c
+}
+
+// junitMessage is the first message logged with a location, or def.
+func junitMessage(u *report.TestUt, def string) string {
+	if locs := u.Locations(); len(locs) > 0 {
+		if msg := strings.SplitN(locs[0].Message, "\n", 2)[0]; len(msg) > 0 {
+			return msg
+		}
+	}
+	return def
+}
+
+func junitTime(elapsed float64) string {
+	return strconv.FormatFloat(elapsed, 'f', 3, 64)
+}
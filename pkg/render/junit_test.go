@@ -0,0 +1,54 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+func TestJUnitReruns(t *testing.T) {
+	attempts := func(actions ...string) []*report.Attempt {
+		var as []*report.Attempt
+		for _, a := range actions {
+			as = append(as, &report.Attempt{Action: a, Output: "    a_test.go:7: " + a + "ed\n"})
+		}
+		return as
+	}
+	for _, tc := range []struct {
+		name                string
+		action              string
+		attempts            []*report.Attempt
+		failure             bool
+		flaky, rerun        int
+		wantInXML, notInXML string
+	}{
+		{"flaky", events.ActionPass, attempts("fail", "fail", "pass"), false, 2, 0, "<flakyFailure", "<rerunFailure"},
+		{"failed", events.ActionFail, attempts("fail", "fail", "fail"), true, 0, 2, "<rerunFailure", "<flakyFailure"},
+		{"once", events.ActionFail, nil, true, 0, 0, "<failure", "<flakyFailure"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			u := &report.TestUt{Attempts: tc.attempts}
+			u.Test, u.Action = "TestA", tc.action
+			c := junitTestCase("example.com/a", u)
+			if got := c.Failure != nil; got != tc.failure {
+				t.Errorf("failure = %v, want %v", got, tc.failure)
+			}
+			if len(c.Flaky) != tc.flaky || len(c.Rerun) != tc.rerun {
+				t.Errorf("got %d flaky and %d rerun failures, want %d and %d", len(c.Flaky), len(c.Rerun), tc.flaky, tc.rerun)
+			}
+			tp := &report.TestPkg{TestUt: &report.TestUt{}, TEList: []*report.TestUt{u}, Count: &report.Count{}}
+			tp.Package, tp.Action = "example.com/a", tc.action
+			b := &bytes.Buffer{}
+			err := JUnit(b, &report.TestInfo{TpList: []*report.TestPkg{tp}, Count: &report.Count{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(b.String(), tc.wantInXML) || strings.Contains(b.String(), tc.notInXML) {
+				t.Errorf("want %s and no %s in\n%s", tc.wantInXML, tc.notInXML, b)
+			}
+		})
+	}
+}
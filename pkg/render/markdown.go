@@ -0,0 +1,112 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// MarkdownOutputLines is the number of output lines Markdown shows for a
+// failure; earlier lines are left out.
+var MarkdownOutputLines = 50
+
+// Markdown writes ti as a compact GitHub flavored Markdown summary, for
+// $GITHUB_STEP_SUMMARY or a pull request comment: the totals, a table of
+// packages, and a collapsible block with the trimmed output of every
+// failure.
+func Markdown(w io.Writer, ti *report.TestInfo) error {
+	b := &strings.Builder{}
+	b.WriteString("## Test report\n\n")
+	if ti.Incomplete {
+		b.WriteString("> **Warning:** the run was interrupted, the report is incomplete.\n\n")
+	}
+	if ti.MalformedLines > 0 {
+		fmt.Fprintf(b, "> **Note:** %d lines of the stream were not JSON.\n\n", ti.MalformedLines)
+	}
+	var elapsed float64
+	for _, tp := range ti.TpList {
+		elapsed += tp.Elapsed
+	}
+	b.WriteString("| Tests | Passed | Failed | Skipped | Flaky | Pass rate | Duration |\n|---|---|---|---|---|---|---|\n")
+	fmt.Fprintf(b, "| %d | %d | %d | %d | %d | %.1f%% | %s |\n", ti.Total, ti.Pass, ti.Fail, ti.Skip, ti.Flakes, ti.PassRate()*100, seconds(elapsed))
+
+	if crashes := crashSummary(ti.Count); len(crashes) > 0 {
+		fmt.Fprintf(b, "\n**Crashes:** %s.\n", crashes)
+	}
+
+	b.WriteString("\n| Result | Package | Passed | Failed | Skipped | Duration |\n|---|---|---|---|---|---|\n")
+	for _, tp := range ti.TpList {
+		status := StatusSymbols.Status(tp.Action)
+		if tp.NoTests {
+			status = StatusSymbols.Status(events.ActionSkip) + " (no tests to run)"
+		}
+		if tp.BuildFailed {
+			status += " (build failed)"
+		}
+		fmt.Fprintf(b, "| %s | %s | %d | %d | %d | %s |\n", mdCell(status), mdCode(tp.Package), tp.Pass, tp.Fail, tp.Skip, seconds(tp.Elapsed))
+	}
+
+	var failures strings.Builder
+	for _, tp := range ti.TpList {
+		if tp.Action == events.ActionFail && tp.Fail < 1 {
+			title := tp.Package
+			if tp.BuildFailed {
+				title += " (build failed)"
+			}
+			mdFailure(&failures, title, tp.TestUt)
+		}
+		for _, u := range tp.TEList {
+			if u.Action == events.ActionFail {
+				mdFailure(&failures, tp.Package+" "+u.Test, u)
+			}
+		}
+	}
+	if failures.Len() > 0 {
+		b.WriteString("\n### Failures\n")
+		b.WriteString(failures.String())
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mdFailure writes a <details> block titled title with the last
+// MarkdownOutputLines lines of the output of u, in a fence longer than
+// any backtick run of the output.
+func mdFailure(b *strings.Builder, title string, u *report.TestUt) {
+	out := ansiEscape.ReplaceAllString(u.Output.String(), "")
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	omitted := 0
+	if MarkdownOutputLines > 0 && len(lines) > MarkdownOutputLines {
+		omitted = len(lines) - MarkdownOutputLines
+		lines = lines[omitted:]
+	}
+	fence := "```"
+	for _, l := range lines {
+		for run := strings.Repeat("`", len(fence)); strings.Contains(l, run); run += "`" {
+			fence = run + "`"
+		}
+	}
+	kind := ""
+	if len(u.FailureKind) > 0 {
+		kind = " <b>" + u.FailureKind + "</b>"
+	}
+	fmt.Fprintf(b, "\n<details><summary><code>%s</code>%s (%s)</summary>\n\n", html.EscapeString(title), kind, seconds(u.Elapsed))
+	if omitted > 0 {
+		fmt.Fprintf(b, "%d earlier lines omitted.\n\n", omitted)
+	}
+	fmt.Fprintf(b, "%s\n%s\n%s\n\n</details>\n", fence, strings.Join(lines, "\n"), fence)
+}
+
+// mdCode shows s as inline code in a table cell.
+func mdCode(s string) string {
+	return "`" + mdCell(strings.ReplaceAll(s, "`", "'")) + "`"
+}
+
+// mdCell keeps | from ending a table cell.
+func mdCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,43 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Metrics writes the run summary of ti in the Prometheus text exposition format.
+func Metrics(w io.Writer, ti *report.TestInfo) error {
+	b := &bytes.Buffer{}
+	b.WriteString("# HELP go_test_report_tests Number of tests by result.\n# TYPE go_test_report_tests gauge\n")
+	for _, s := range []struct {
+		status string
+		n      int
+	}{{events.ActionPass, ti.Pass}, {events.ActionFail, ti.Fail}, {events.ActionSkip, ti.Skip}, {"total", ti.Total}} {
+		fmt.Fprintf(b, "go_test_report_tests{status=%q} %d\n", s.status, s.n)
+	}
+	var elapsed float64
+	for _, tp := range ti.TpList {
+		elapsed += tp.Elapsed
+	}
+	b.WriteString("# HELP go_test_report_duration_seconds Sum of package durations.\n# TYPE go_test_report_duration_seconds gauge\n")
+	fmt.Fprintf(b, "go_test_report_duration_seconds %g\n", elapsed)
+	b.WriteString("# HELP go_test_report_pass_rate Passed tests divided by tests that were not skipped.\n# TYPE go_test_report_pass_rate gauge\n")
+	fmt.Fprintf(b, "go_test_report_pass_rate %g\n", ti.PassRate())
+	b.WriteString("# HELP go_test_report_package_failures Failed tests per package.\n# TYPE go_test_report_package_failures gauge\n")
+	for _, tp := range ti.TpList {
+		fmt.Fprintf(b, "go_test_report_package_failures{package=\"%s\"} %d\n", labelEscaper.Replace(tp.Package), tp.Fail)
+	}
+	if ti.Time != nil {
+		b.WriteString("# HELP go_test_report_timestamp_seconds Time the report was generated.\n# TYPE go_test_report_timestamp_seconds gauge\n")
+		fmt.Fprintf(b, "go_test_report_timestamp_seconds %d\n", ti.Time.Unix())
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,43 @@
+package render
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// minifiers shrink the assets of the HTML page. They only handle what
+// those assets use: no strings containing comment markers or significant
+// whitespace.
+var minifiers = map[string]func([]byte) []byte{
+	".css": minifyCSS,
+	".js":  minifyJS,
+}
+
+var (
+	cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssSpace   = regexp.MustCompile(`\s+`)
+	cssPunct   = regexp.MustCompile(` ?([{}:;,>]) ?`)
+)
+
+func minifyCSS(b []byte) []byte {
+	b = cssComment.ReplaceAll(b, nil)
+	b = cssSpace.ReplaceAll(b, []byte(" "))
+	b = cssPunct.ReplaceAll(b, []byte("$1"))
+	b = bytes.ReplaceAll(b, []byte(";}"), []byte("}"))
+	return bytes.TrimSpace(b)
+}
+
+// minifyJS drops comment lines, indentation and blank lines. Line breaks
+// are kept, so automatic semicolon insertion is unaffected.
+func minifyJS(b []byte) []byte {
+	var out []byte
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) < 1 || bytes.HasPrefix(line, []byte("//")) {
+			continue
+		}
+		out = append(out, line...)
+		out = append(out, '\n')
+	}
+	return out
+}
@@ -0,0 +1,201 @@
+package render
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// Namespaces of the Open Test Reporting formats of the JUnit team.
+const (
+	otrCore      = "https://schemas.opentest4j.org/reporting/core/0.2.0"
+	otrHierarchy = "https://schemas.opentest4j.org/reporting/hierarchy/0.2.0"
+	otrEvents    = "https://schemas.opentest4j.org/reporting/events/0.2.0"
+)
+
+// otrNode is a package or test in the Open Test Reporting tree.
+// Subtests are children of their parent test.
+type otrNode struct {
+	name       string
+	start, end time.Time
+	status     string
+	output     string
+	children   []*otrNode
+}
+
+var otrStatus = map[string]string{
+	events.ActionPass:  "SUCCESSFUL",
+	events.ActionBench: "SUCCESSFUL",
+	events.ActionFail:  "FAILED",
+	events.ActionSkip:  "SKIPPED",
+}
+
+func newOTRNode(name string, u *report.TestUt, fallback time.Time) *otrNode {
+	n := &otrNode{name: name, end: fallback, status: otrStatus[u.Action], output: u.Output.String()}
+	if u.Time != nil {
+		n.end = *u.Time
+	}
+	n.start = n.end.Add(-time.Duration(u.Elapsed * float64(time.Second)))
+	if len(n.status) < 1 {
+		// Tests that never finished, such as in an interrupted run.
+		n.status = "ABORTED"
+	}
+	return n
+}
+
+func otrTree(ti *report.TestInfo) []*otrNode {
+	var roots []*otrNode
+	var generated time.Time
+	if ti.Time != nil {
+		generated = *ti.Time
+	}
+	for _, tp := range ti.TpList {
+		root := newOTRNode(tp.Package, tp.TestUt, generated)
+		nodes := map[string]*otrNode{}
+		for _, u := range tp.TEList {
+			n := newOTRNode(u.Test, u, root.end)
+			nodes[u.Test] = n
+			parent := root
+			for i := len(u.Test) - 1; i >= 0; i-- {
+				if u.Test[i] == '/' {
+					if p := nodes[u.Test[:i]]; p != nil {
+						parent = p
+						break
+					}
+				}
+			}
+			parent.children = append(parent.children, n)
+		}
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+func otrTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// otrDuration formats d as an ISO 8601 duration.
+func otrDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return "PT" + strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "S"
+}
+
+func otrElement(name string, attrs ...string) xml.StartElement {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: attrs[i]}, Value: attrs[i+1]})
+	}
+	return start
+}
+
+// otrWriter encodes elements, keeping the first error.
+type otrWriter struct {
+	enc *xml.Encoder
+	err error
+}
+
+func (w *otrWriter) start(name string, attrs ...string) {
+	if w.err == nil {
+		w.err = w.enc.EncodeToken(otrElement(name, attrs...))
+	}
+}
+
+func (w *otrWriter) end(name string) {
+	if w.err == nil {
+		w.err = w.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}})
+	}
+}
+
+func (w *otrWriter) text(name, s string, attrs ...string) {
+	if w.err == nil {
+		w.err = w.enc.EncodeElement(s, otrElement(name, attrs...))
+	}
+}
+
+func (w *otrWriter) header(out io.Writer) {
+	_, w.err = io.WriteString(out, xml.Header)
+}
+
+func (w *otrWriter) flush() error {
+	if w.err == nil {
+		w.err = w.enc.Flush()
+	}
+	return w.err
+}
+
+func newOTRWriter(out io.Writer) *otrWriter {
+	w := &otrWriter{enc: xml.NewEncoder(out)}
+	w.enc.Indent("", "  ")
+	w.header(out)
+	return w
+}
+
+// result writes the output of n, if any, and its status.
+func (w *otrWriter) result(n *otrNode) {
+	if len(n.output) > 0 {
+		w.start("c:attachments")
+		w.text("c:output", n.output, "source", "stdout", "time", otrTime(n.end))
+		w.end("c:attachments")
+	}
+	w.start("c:result", "status", n.status)
+	w.end("c:result")
+}
+
+// OTR writes ti in the hierarchical Open Test Reporting format, with a
+// root per package.
+func OTR(out io.Writer, ti *report.TestInfo) error {
+	w := newOTRWriter(out)
+	w.start("h:execution", "xmlns:h", otrHierarchy, "xmlns:c", otrCore)
+	var node func(tag string, n *otrNode)
+	node = func(tag string, n *otrNode) {
+		w.start(tag, "name", n.name, "start", otrTime(n.start), "duration", otrDuration(n.end.Sub(n.start)))
+		w.result(n)
+		for _, c := range n.children {
+			node("h:child", c)
+		}
+		w.end(tag)
+	}
+	for _, root := range otrTree(ti) {
+		node("h:root", root)
+	}
+	w.end("h:execution")
+	return w.flush()
+}
+
+// OTREvents writes ti in the event based Open Test Reporting format: a
+// started and a finished event for every package and test. Children
+// start and finish between the events of their parent.
+func OTREvents(out io.Writer, ti *report.TestInfo) error {
+	w := newOTRWriter(out)
+	w.start("e:events", "xmlns:e", otrEvents, "xmlns:c", otrCore)
+	id := 0
+	var walk func(n *otrNode, parent string)
+	walk = func(n *otrNode, parent string) {
+		id++
+		nid := strconv.Itoa(id)
+		attrs := []string{"id", nid, "name", n.name, "time", otrTime(n.start)}
+		if len(parent) > 0 {
+			attrs = append(attrs, "parentId", parent)
+		}
+		w.start("e:started", attrs...)
+		w.end("e:started")
+		for _, c := range n.children {
+			walk(c, nid)
+		}
+		w.start("e:finished", "id", nid, "time", otrTime(n.end))
+		w.result(n)
+		w.end("e:finished")
+	}
+	for _, root := range otrTree(ti) {
+		walk(root, "")
+	}
+	w.end("e:events")
+	return w.flush()
+}
@@ -0,0 +1,69 @@
+// Package render writes reports in the supported output formats, such
+// as XML, JUnit and HTML, and lets other formats be registered.
+package render
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// Renderer writes a report in one output format.
+type Renderer interface {
+	Render(w io.Writer, ti *report.TestInfo) error
+}
+
+// RendererFunc adapts a function to the Renderer interface.
+type RendererFunc func(w io.Writer, ti *report.TestInfo) error
+
+func (f RendererFunc) Render(w io.Writer, ti *report.TestInfo) error {
+	return f(w, ti)
+}
+
+var (
+	mu        sync.RWMutex
+	renderers = map[string]Renderer{
+		"xml":        XMLRenderer{},
+		"json":       RendererFunc(JSON),
+		"metrics":    RendererFunc(Metrics),
+		"adoc":       RendererFunc(AsciiDoc),
+		"markdown":   RendererFunc(Markdown),
+		"md":         RendererFunc(Markdown),
+		"digest":     RendererFunc(Digest),
+		"txt":        RendererFunc(Text),
+		"junit":      RendererFunc(JUnit),
+		"otr":        RendererFunc(OTR),
+		"otr-events": RendererFunc(OTREvents),
+		"html":       HTMLRenderer{},
+	}
+)
+
+// Register makes r available under name, replacing any renderer
+// previously registered with that name.
+func Register(name string, r Renderer) {
+	mu.Lock()
+	defer mu.Unlock()
+	renderers[name] = r
+}
+
+// Lookup returns the renderer registered under name.
+func Lookup(name string) (Renderer, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	r, ok := renderers[name]
+	return r, ok
+}
+
+// Names returns the registered format names in sorted order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	var names []string
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
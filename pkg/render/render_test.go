@@ -0,0 +1,38 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// TestUndated checks that a report without a time, as with -timestamps
+// off, is not dated at the zero time.
+func TestUndated(t *testing.T) {
+	ti, err := report.Parse(strings.NewReader(`{"Action":"run","Package":"a","Test":"TestA"}
+{"Action":"pass","Package":"a","Test":"TestA"}
+{"Action":"pass","Package":"a"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti.SetCount()
+	ti.Time = nil
+	for _, name := range []string{"xml", "json", "txt", "adoc", "html", "metrics"} {
+		r, ok := Lookup(name)
+		if !ok {
+			t.Fatalf("no %s renderer", name)
+		}
+		b := &bytes.Buffer{}
+		if err := r.Render(b, ti); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, s := range []string{"0001-01-01", "Generated", "xml-create-time"} {
+			if strings.Contains(b.String(), s) {
+				t.Errorf("%s: got %q in the report", name, s)
+			}
+		}
+	}
+}
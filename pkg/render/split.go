@@ -0,0 +1,108 @@
+package render
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// IndexLinker is implemented by renderers that link the package reports
+// of a split report back to its index.
+type IndexLinker interface {
+	// WithIndex returns a renderer for package reports whose index is at
+	// the relative URL index.
+	WithIndex(index string) Renderer
+}
+
+// WriteSplit writes one report per package of ti to dir, plus an index
+// listing the packages with their counts and the file of their report.
+// Files are named after the package and end in ext. It returns the paths
+// written, the index last.
+func WriteSplit(dir, ext string, r Renderer, ti *report.TestInfo) ([]string, error) {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	pr := r
+	if l, ok := r.(IndexLinker); ok {
+		pr = l.WithIndex("index" + ext)
+	}
+	var paths []string
+	index := *ti
+	index.TpList = make([]*report.TestPkg, 0, len(ti.TpList))
+	used := map[string]bool{"index": true}
+	for _, tp := range ti.TpList {
+		name := fileName(tp.Package, used)
+		path := filepath.Join(dir, name+ext)
+		err := WriteFile(path, pr, &report.TestInfo{
+			TpList: []*report.TestPkg{tp},
+			Time:   ti.Time,
+			Count:  tp.Count,
+		})
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+		u := *tp.TestUt
+		u.Output = report.Output{}
+		idx := *tp
+		idx.TestUt, idx.TEList, idx.File = &u, nil, name+ext
+		index.TpList = append(index.TpList, &idx)
+	}
+	path := filepath.Join(dir, "index"+ext)
+	err = WriteFile(path, r, &index)
+	if err != nil {
+		return nil, err
+	}
+	return append(paths, path), nil
+}
+
+// fileName returns a file name for pkg not in used, and adds it to used.
+// Names are compared ignoring case for case-insensitive file systems.
+func fileName(pkg string, used map[string]bool) string {
+	base := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(pkg)
+	if len(base) < 1 || reservedNames[strings.ToLower(base)] {
+		base += "_"
+	}
+	name := base
+	for i := 2; used[strings.ToLower(name)]; i++ {
+		name = base + "-" + strconv.Itoa(i)
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
+
+// reservedNames are device names Windows does not allow as file names.
+var reservedNames = map[string]bool{}
+
+func init() {
+	for _, name := range []string{"con", "prn", "aux", "nul"} {
+		reservedNames[name] = true
+	}
+	for i := 1; i <= 9; i++ {
+		reservedNames["com"+strconv.Itoa(i)] = true
+		reservedNames["lpt"+strconv.Itoa(i)] = true
+	}
+}
+
+// WriteFile renders ti with r to the file at path.
+func WriteFile(path string, r Renderer, ti *report.TestInfo) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	err = r.Render(w, ti)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+// Symbols mark test results in the text outputs. Empty symbols show the
+// result alone.
+type Symbols struct {
+	Pass, Fail, Skip string
+}
+
+// SymbolSets are the predefined Symbols by name.
+var SymbolSets = map[string]Symbols{
+	"none":    {},
+	"unicode": {Pass: "✓", Fail: "✗", Skip: "↷"},
+	"emoji":   {Pass: "✅", Fail: "❌", Skip: "⏭️"},
+	"ascii":   {Pass: "[+]", Fail: "[x]", Skip: "[-]"},
+}
+
+// StatusSymbols are used by the console, Markdown and AsciiDoc outputs.
+var StatusSymbols Symbols
+
+// ParseSymbols returns the set named s, or the symbols of a list such as
+// "pass=ok,fail=FAIL,skip=--".
+func ParseSymbols(s string) (Symbols, error) {
+	if set, ok := SymbolSets[s]; ok {
+		return set, nil
+	}
+	var sym Symbols
+	for _, kv := range strings.Split(s, ",") {
+		i := strings.Index(kv, "=")
+		if i < 0 {
+			var names []string
+			for name := range SymbolSets {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return Symbols{}, fmt.Errorf("symbols %q: want one of %s or pass=,fail=,skip=", s, strings.Join(names, ", "))
+		}
+		switch v := kv[i+1:]; kv[:i] {
+		case events.ActionPass:
+			sym.Pass = v
+		case events.ActionFail:
+			sym.Fail = v
+		case events.ActionSkip:
+			sym.Skip = v
+		default:
+			return Symbols{}, fmt.Errorf("symbols %q: unknown result %q", s, kv[:i])
+		}
+	}
+	return sym, nil
+}
+
+// Status returns action preceded by its symbol, if it has one.
+func (s Symbols) Status(action string) string {
+	var sym string
+	switch action {
+	case events.ActionPass:
+		sym = s.Pass
+	case events.ActionFail:
+		sym = s.Fail
+	case events.ActionSkip:
+		sym = s.Skip
+	}
+	if len(sym) < 1 {
+		return action
+	}
+	return sym + " " + action
+}
@@ -0,0 +1,214 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// TextSlowest is the number of slowest tests Text lists when the report
+// has no list of them, see report.TestInfo.SetSlowest.
+var TextSlowest = 10
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// textStatus spells out results, so they read the same everywhere.
+var textStatus = map[string]string{
+	events.ActionPass:  "PASS",
+	events.ActionFail:  "FAIL",
+	events.ActionSkip:  "SKIP",
+	events.ActionBench: "BENCH",
+}
+
+// Text writes ti as plain text for screen readers and ticket systems
+// that strip formatting: the totals, a fixed-width table of packages,
+// every failure with its output, and the slowest tests. It uses no
+// color, markup or symbols.
+func Text(w io.Writer, ti *report.TestInfo) error {
+	b := &strings.Builder{}
+	b.WriteString("Test report\n")
+	if ti.Time != nil {
+		fmt.Fprintf(b, "Generated %s.\n", ti.Time.Format("2006-01-02 15:04:05 MST"))
+	}
+	if ti.Incomplete {
+		b.WriteString("Warning: the run was interrupted, the report is incomplete.\n")
+	}
+	if ti.MalformedLines > 0 {
+		fmt.Fprintf(b, "Note: %d lines of the stream were not JSON.\n", ti.MalformedLines)
+	}
+	flaky := ""
+	if ti.Flakes > 0 {
+		flaky = fmt.Sprintf(", %d flaky", ti.Flakes)
+	}
+	fmt.Fprintf(b, "\nSummary: %d tests, %d passed, %d failed, %d skipped%s, pass rate %.1f%%.\n", ti.Total, ti.Pass, ti.Fail, ti.Skip, flaky, ti.PassRate()*100)
+	if len(ti.SlowThreshold) > 0 {
+		fmt.Fprintf(b, "Slow tests: %d took longer than %s.\n", ti.SlowTests, ti.SlowThreshold)
+	}
+	if crashes := crashSummary(ti.Count); len(crashes) > 0 {
+		fmt.Fprintf(b, "Crashes: %s.\n", crashes)
+	}
+	if ti.Statements > 0 {
+		fmt.Fprintf(b, "Coverage: %.1f%% of statements.\n", ti.StatementCoverage)
+	}
+
+	b.WriteString("\nPackages\n\n")
+	tw := tabwriter.NewWriter(b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESULT\tPACKAGE\tTESTS\tPASSED\tFAILED\tSKIPPED\tDURATION")
+	for _, tp := range ti.TpList {
+		status := textAction(tp.Action)
+		if tp.NoTests {
+			status = "NO TESTS"
+		}
+		if tp.BuildFailed {
+			status = "BUILD FAILED"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%s\n", status, tp.Package, tp.Total, tp.Pass, tp.Fail, tp.Skip, textSeconds(tp.Elapsed))
+	}
+	_ = tw.Flush()
+
+	var tests []*report.TestUt
+	failures := 0
+	for _, tp := range ti.TpList {
+		if tp.Action == events.ActionFail && tp.Fail < 1 {
+			title := tp.Package
+			if tp.BuildFailed {
+				title += ", build failed"
+			}
+			textFailure(b, &failures, title, tp.TestUt)
+		}
+		for _, u := range tp.TEList {
+			tests = append(tests, u)
+			if u.Action == events.ActionFail {
+				textFailure(b, &failures, tp.Package+" "+u.Test, u)
+			}
+		}
+	}
+
+	if ti.Flakes > 0 {
+		b.WriteString("\nFlaky tests\n\n")
+		tw = tabwriter.NewWriter(b, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "PACKAGE\tTEST\tATTEMPTS")
+		for _, u := range tests {
+			if !u.Flaky {
+				continue
+			}
+			var attempts []string
+			for _, a := range u.Attempts {
+				attempts = append(attempts, textAction(a.Action))
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", u.Package, u.Test, strings.Join(attempts, ", "))
+		}
+		_ = tw.Flush()
+	}
+
+	slowest := ti.Slowest
+	if slowest == nil {
+		sort.SliceStable(tests, func(i, j int) bool {
+			return tests[i].Elapsed > tests[j].Elapsed
+		})
+		if len(tests) > TextSlowest {
+			tests = tests[:TextSlowest]
+		}
+		for _, u := range tests {
+			slowest = append(slowest, &report.SlowTest{Package: u.Package, Test: u.Test, Action: u.Action, Elapsed: u.Elapsed})
+		}
+	}
+	if len(slowest) > 0 {
+		b.WriteString("\nSlowest tests\n\n")
+		tw = tabwriter.NewWriter(b, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "DURATION\tRESULT\tPACKAGE\tTEST")
+		for _, u := range slowest {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", textSeconds(u.Elapsed), textAction(u.Action), u.Package, u.Test)
+		}
+		_ = tw.Flush()
+	}
+	benchmarks := false
+	for _, tp := range ti.TpList {
+		if len(tp.Benchmarks) < 1 {
+			continue
+		}
+		if !benchmarks {
+			b.WriteString("\nBenchmarks\n\n")
+			tw = tabwriter.NewWriter(b, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "PACKAGE\tBENCHMARK\tITERATIONS\tNS/OP\tB/OP\tALLOCS/OP\tCHANGE")
+			benchmarks = true
+		}
+		for _, bm := range tp.Benchmarks {
+			bytes, allocs, change := "-", "-", "-"
+			if bm.BytesPerOp != nil {
+				bytes = fmt.Sprint(*bm.BytesPerOp)
+			}
+			if bm.AllocsPerOp != nil {
+				allocs = fmt.Sprint(*bm.AllocsPerOp)
+			}
+			if bm.BaselineNsPerOp > 0 {
+				change = fmt.Sprintf("%+.1f%%", bm.Change)
+				if bm.Slower {
+					change += " SLOWER"
+				}
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%g\t%s\t%s\t%s\n", tp.Package, bm.Name, bm.Iterations, bm.NsPerOp, bytes, allocs, change)
+		}
+	}
+	if benchmarks {
+		_ = tw.Flush()
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// textFailure writes the output of the failure title, indented, under a
+// numbered heading.
+func textFailure(b *strings.Builder, n *int, title string, u *report.TestUt) {
+	if *n == 0 {
+		b.WriteString("\nFailures\n")
+	}
+	*n++
+	if len(u.FailureKind) > 0 {
+		title += " (" + u.FailureKind + ")"
+	}
+	fmt.Fprintf(b, "\nFailure %d: %s, %s\n\n", *n, title, textSeconds(u.Elapsed))
+	out := ansiEscape.ReplaceAllString(u.Output.String(), "")
+	for _, l := range strings.Split(strings.TrimRight(out, "\n"), "\n") {
+		b.WriteString("    " + strings.ReplaceAll(l, "\t", "    ") + "\n")
+	}
+}
+
+// crashSummary counts the failures of c by kind, leaving out the kinds
+// that did not occur.
+func crashSummary(c *report.Count) string {
+	var kinds []string
+	for _, k := range []struct {
+		n            int
+		one, several string
+	}{{c.Panics, "panic", "panics"}, {c.Races, "data race", "data races"}, {c.Timeouts, "timeout", "timeouts"}} {
+		switch {
+		case k.n == 1:
+			kinds = append(kinds, "1 "+k.one)
+		case k.n > 1:
+			kinds = append(kinds, fmt.Sprintf("%d %s", k.n, k.several))
+		}
+	}
+	return strings.Join(kinds, ", ")
+}
+
+func textAction(action string) string {
+	if s, ok := textStatus[action]; ok {
+		return s
+	}
+	if len(action) < 1 {
+		return "UNKNOWN"
+	}
+	return strings.ToUpper(action)
+}
+
+// textSeconds is seconds in ASCII.
+func textSeconds(elapsed float64) string {
+	return strings.ReplaceAll(seconds(elapsed), "µ", "u")
+}
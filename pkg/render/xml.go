@@ -0,0 +1,266 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// XML writes ti in the native <all>/<pkg>/<ut> format.
+func XML(w io.Writer, ti *report.TestInfo) error {
+	return XMLRenderer{}.Render(w, ti)
+}
+
+// XMLEmpty says how XMLRenderer writes attributes and elements without
+// a value.
+type XMLEmpty int
+
+const (
+	// XMLEmptyDefault leaves out the empty values of optional fields.
+	XMLEmptyDefault XMLEmpty = iota
+	// XMLEmptyOmit leaves out every empty attribute and element.
+	XMLEmptyOmit
+	// XMLEmptyKeep writes every attribute, with its zero value if empty.
+	XMLEmptyKeep
+)
+
+// XMLRenderer writes the native format in the layout downstream parsers
+// expect. The zero value writes the default layout.
+type XMLRenderer struct {
+	// OutputAttr writes the output of packages and tests as an output
+	// attribute instead of an element.
+	OutputAttr bool
+	// Compact leaves out the indentation.
+	Compact bool
+	// Indent is the indentation of nested elements, a tab if empty.
+	Indent string
+	Empty  XMLEmpty
+	// Root renames the root element, <all> by default.
+	Root string
+	// Namespace is declared as the default namespace of the root element.
+	Namespace string
+	// RootAttrs are added to the root element.
+	RootAttrs []xml.Attr
+	// Tree nests subtests under their parents, see report.TestInfo.Tree.
+	Tree bool
+}
+
+// Default reports whether r writes the default layout.
+func (r XMLRenderer) Default() bool {
+	return !r.OutputAttr && !r.Compact && !r.Tree && len(r.Indent) < 1 && r.Empty == XMLEmptyDefault && len(r.Root) < 1 && len(r.Namespace) < 1 && len(r.RootAttrs) < 1
+}
+
+func (r XMLRenderer) Render(w io.Writer, ti *report.TestInfo) error {
+	if r.Tree {
+		ti = ti.Tree()
+	}
+	_, err := io.WriteString(w, xml.Header+"\n")
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if !r.Compact {
+		indent := r.Indent
+		if len(indent) < 1 {
+			indent = "\t"
+		}
+		enc.Indent("", indent)
+	}
+	if !r.OutputAttr && r.Empty == XMLEmptyDefault && !r.customRoot() {
+		err = enc.Encode(ti)
+	} else {
+		var b []byte
+		b, err = xml.Marshal(ti)
+		if err == nil {
+			err = r.rewrite(enc, xml.NewDecoder(bytes.NewReader(b)))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func (r XMLRenderer) customRoot() bool {
+	return len(r.Root) > 0 || len(r.Namespace) > 0 || len(r.RootAttrs) > 0
+}
+
+// root applies the root options of r to the root element start.
+func (r XMLRenderer) root(start *xml.StartElement) {
+	if len(r.Root) > 0 {
+		start.Name.Local = r.Root
+	}
+	if len(r.Namespace) > 0 {
+		start.Attr = append([]xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: r.Namespace}}, start.Attr...)
+	}
+	start.Attr = append(start.Attr, r.RootAttrs...)
+}
+
+// rewrite copies the tokens of dec to enc in the layout of r. A start
+// element is held back until its first child is known, so an output
+// element can still become one of its attributes.
+func (r XMLRenderer) rewrite(enc *xml.Encoder, dec *xml.Decoder) error {
+	var pending *xml.StartElement
+	depth := 0
+	flush := func() error {
+		if pending == nil {
+			return nil
+		}
+		start := *pending
+		pending = nil
+		return enc.EncodeToken(start)
+	}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if r.OutputAttr && t.Name.Local == "output" && pending != nil {
+				var text string
+				err = dec.DecodeElement(&text, &t)
+				if err != nil {
+					return err
+				}
+				if len(text) > 0 || r.Empty != XMLEmptyOmit {
+					pending.Attr = append(pending.Attr, xml.Attr{Name: xml.Name{Local: "output"}, Value: text})
+				}
+				continue
+			}
+			if err = flush(); err != nil {
+				return err
+			}
+			start := t.Copy()
+			start.Attr = r.attrs(start.Name.Local, start.Attr)
+			if depth == 0 {
+				r.root(&start)
+			}
+			depth++
+			pending = &start
+		case xml.EndElement:
+			depth--
+			if depth == 0 && len(r.Root) > 0 {
+				t.Name.Local = r.Root
+			}
+			if pending != nil && r.Empty == XMLEmptyOmit && len(pending.Attr) < 1 {
+				pending = nil
+				continue
+			}
+			if err = flush(); err != nil {
+				return err
+			}
+			err = enc.EncodeToken(t)
+		default:
+			if err = flush(); err != nil {
+				return err
+			}
+			err = enc.EncodeToken(xml.CopyToken(tok))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return enc.Flush()
+}
+
+// attrs applies r.Empty to the attributes of the element name.
+func (r XMLRenderer) attrs(name string, attrs []xml.Attr) []xml.Attr {
+	switch r.Empty {
+	case XMLEmptyOmit:
+		kept := attrs[:0]
+		for _, a := range attrs {
+			if len(a.Value) > 0 {
+				kept = append(kept, a)
+			}
+		}
+		return kept
+	case XMLEmptyKeep:
+		all := xmlAttrs()[name]
+		if len(all) < 1 {
+			return attrs
+		}
+		values := map[string]string{}
+		for _, a := range attrs {
+			values[a.Name.Local] = a.Value
+		}
+		kept := make([]xml.Attr, 0, len(all))
+		for _, a := range all {
+			if v, ok := values[a.Name.Local]; ok {
+				a.Value = v
+			}
+			kept = append(kept, a)
+		}
+		return kept
+	}
+	return attrs
+}
+
+var (
+	xmlAttrsOnce sync.Once
+	xmlAttrList  map[string][]xml.Attr
+)
+
+// xmlAttrs returns every attribute of the elements of the native format
+// in the order they are written, with their zero values.
+func xmlAttrs() map[string][]xml.Attr {
+	xmlAttrsOnce.Do(func() {
+		xmlAttrList = map[string][]xml.Attr{}
+		for name, v := range map[string]interface{}{
+			"all":     report.TestInfo{},
+			"pkg":     report.TestPkg{},
+			"ut":      report.TestUt{},
+			"deleted": report.DeletedTest{},
+			"module":  report.Module{},
+			"attempt": report.Attempt{},
+		} {
+			seen := map[string]bool{}
+			var walk func(t reflect.Type)
+			walk = func(t reflect.Type) {
+				for i := 0; i < t.NumField(); i++ {
+					f := t.Field(i)
+					ft := f.Type
+					if ft.Kind() == reflect.Ptr {
+						ft = ft.Elem()
+					}
+					parts := strings.Split(f.Tag.Get("xml"), ",")
+					if f.Anonymous && len(parts[0]) < 1 && ft.Kind() == reflect.Struct {
+						walk(ft)
+						continue
+					}
+					isAttr := false
+					for _, p := range parts[1:] {
+						isAttr = isAttr || p == "attr"
+					}
+					attr := parts[0]
+					if len(attr) < 1 {
+						attr = f.Name
+					}
+					if !isAttr || len(f.PkgPath) > 0 || seen[attr] {
+						continue
+					}
+					seen[attr] = true
+					zero := ""
+					switch ft.Kind() {
+					case reflect.Bool:
+						zero = "false"
+					case reflect.Int, reflect.Int64, reflect.Float64:
+						zero = "0"
+					}
+					xmlAttrList[name] = append(xmlAttrList[name], xml.Attr{Name: xml.Name{Local: attr}, Value: zero})
+				}
+			}
+			walk(reflect.TypeOf(v))
+		}
+	})
+	return xmlAttrList
+}
@@ -0,0 +1,101 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/report"
+)
+
+// XMLStream writes the native XML format package by package, keeping
+// only the running counts in memory. The root element carries the
+// totals, so packages are spooled to a temporary file until Close.
+type XMLStream struct {
+	// Tree nests subtests under their parents, see report.TestInfo.Tree.
+	Tree  bool
+	w     io.Writer
+	spool *os.File
+	enc   *xml.Encoder
+	count report.Count
+	n     int
+}
+
+func NewXMLStream(w io.Writer) (*XMLStream, error) {
+	spool, err := ioutil.TempFile("", "go-test-report-*.xml")
+	if err != nil {
+		return nil, err
+	}
+	enc := xml.NewEncoder(spool)
+	enc.Indent("\t", "\t")
+	return &XMLStream{w: w, spool: spool, enc: enc}, nil
+}
+
+// Package writes tp, whose counts must be final.
+func (s *XMLStream) Package(tp *report.TestPkg) error {
+	s.count.Add(tp.Count)
+	s.n++
+	if s.Tree {
+		tp = tp.Tree()
+	}
+	return s.enc.EncodeElement(tp, xml.StartElement{Name: xml.Name{Local: "pkg"}})
+}
+
+// Count returns the totals of the packages written so far.
+func (s *XMLStream) Count() report.Count {
+	return s.count
+}
+
+// Discard removes the spool file without writing the report. It does
+// nothing once the stream is closed.
+func (s *XMLStream) Discard() {
+	s.spool.Close()
+	os.Remove(s.spool.Name())
+}
+
+// Close writes the root element with the creation time and state of ti
+// and the totals of the streamed packages, followed by the packages, and removes
+// the spool file.
+func (s *XMLStream) Close(ti *report.TestInfo) error {
+	defer s.Discard()
+	err := s.enc.Flush()
+	if err != nil {
+		return err
+	}
+	count := s.count
+	root, err := xml.MarshalIndent(&report.TestInfo{
+		Time:           ti.Time,
+		Incomplete:     ti.Incomplete,
+		MalformedLines: ti.MalformedLines,
+		Race:           ti.Race,
+		Count:          &count,
+	}, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(s.w, xml.Header+"\n")
+	if err != nil {
+		return err
+	}
+	if s.n < 1 {
+		_, err = s.w.Write(append(root, '\n'))
+		return err
+	}
+	end := []byte("</all>")
+	_, err = s.w.Write(append(bytes.TrimSuffix(root, end), '\n'))
+	if err != nil {
+		return err
+	}
+	_, err = s.spool.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(s.w, s.spool)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(append(append([]byte("\n"), end...), '\n'))
+	return err
+}
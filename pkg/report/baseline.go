@@ -0,0 +1,68 @@
+package report
+
+import "github.com/jiuliyemingzhi/go-test-report/pkg/events"
+
+const (
+	RegressionPreExisting = "pre-existing"
+	RegressionNew         = "new"
+)
+
+// ApplyBaseline marks the tests of ti that do not appear in base, tags
+// every failure as either pre-existing or new in this run and records
+// the baseline tests that no longer ran. The baseline packages and tests
+// that f leaves out are not reported as deleted, nor, when f keeps only
+// failures, the baseline tests of packages that ran; f may be nil.
+func (ti *TestInfo) ApplyBaseline(base *TestInfo, f *Filter) {
+	baseMap := base.utMap()
+	curMap := ti.utMap()
+	pkgs := map[string]*TestPkg{}
+	for _, tp := range ti.TpList {
+		pkgs[tp.Package] = tp
+		for _, u := range tp.TEList {
+			b, ok := baseMap[tp.Package+"\x00"+u.Test]
+			if !ok {
+				u.New = true
+				tp.Added++
+			}
+			if u.Action != events.ActionFail {
+				continue
+			}
+			if ok && b.Action == events.ActionFail {
+				u.Regression = RegressionPreExisting
+				continue
+			}
+			u.Regression = RegressionNew
+			tp.Regressions++
+		}
+	}
+	for _, btp := range base.TpList {
+		if f != nil && !f.Package(btp.Package) {
+			continue
+		}
+		tp, ok := pkgs[btp.Package]
+		if ok && f != nil && f.FailuresOnly {
+			// The tests that passed were dropped, so a missing one may
+			// still have run.
+			continue
+		}
+		deleted := 0
+		for _, b := range btp.TEList {
+			if f != nil && !f.Test(b.Test) {
+				continue
+			}
+			if !ok {
+				deleted++
+				continue
+			}
+			if _, ok := curMap[btp.Package+"\x00"+b.Test]; ok {
+				continue
+			}
+			tp.DeletedTests = append(tp.DeletedTests, &DeletedTest{Test: b.Test, Action: b.Action})
+			tp.Deleted++
+		}
+		if !ok {
+			ti.DeletedPkgs = append(ti.DeletedPkgs, btp.Package)
+			ti.Deleted += deleted
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package report
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+// Benchmark is a result line a benchmark printed. A benchmark run with
+// -count n has n of them.
+type Benchmark struct {
+	Name string `xml:"name,attr"`
+	// Procs is the GOMAXPROCS suffix of the name, 0 if there was none.
+	Procs      int     `json:",omitempty" xml:"procs,attr,omitempty"`
+	Iterations int64   `xml:"iterations,attr"`
+	NsPerOp    float64 `xml:"ns-per-op,attr"`
+	// BytesPerOp and AllocsPerOp are set with -benchmem or b.ReportAllocs.
+	BytesPerOp  *int64  `json:",omitempty" xml:"bytes-per-op,attr,omitempty"`
+	AllocsPerOp *int64  `json:",omitempty" xml:"allocs-per-op,attr,omitempty"`
+	MBPerSec    float64 `json:",omitempty" xml:"mb-per-s,attr,omitempty"`
+	// BaselineNsPerOp is the ns/op of the benchmark in the baseline and
+	// Change the percentage it changed by, see CompareBenchmarks.
+	BaselineNsPerOp float64 `json:",omitempty" xml:"baseline-ns-per-op,attr,omitempty"`
+	Change          float64 `json:",omitempty" xml:"change,attr,omitempty"`
+	Slower          bool    `json:",omitempty" xml:"slower,attr,omitempty"`
+}
+
+const benchPrefix = "Benchmark"
+
+var benchLine = regexp.MustCompile(`^(Benchmark\S*?)(?:-(\d+))?\s+(\d+)\s+(\S.*)$`)
+
+// isBenchHeader tells the lines describing the machine go test prints
+// before the first benchmark.
+func isBenchHeader(line string) bool {
+	for _, p := range []string{"goos: ", "goarch: ", "pkg: ", "cpu: "} {
+		if strings.HasPrefix(line, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBenchmark parses a result line such as
+// "BenchmarkX-8   1000   1234 ns/op   64 B/op   2 allocs/op".
+func parseBenchmark(line string) (*Benchmark, bool) {
+	m := benchLine.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+	if m == nil {
+		return nil, false
+	}
+	b := &Benchmark{Name: m[1]}
+	b.Procs, _ = strconv.Atoi(m[2])
+	b.Iterations, _ = strconv.ParseInt(m[3], 10, 64)
+	ok := false
+	for _, metric := range strings.Split(m[4], "\t") {
+		f := strings.Fields(metric)
+		if len(f) != 2 {
+			continue
+		}
+		v, err := strconv.ParseFloat(f[0], 64)
+		if err != nil {
+			continue
+		}
+		switch f[1] {
+		case "ns/op":
+			b.NsPerOp, ok = v, true
+		case "B/op":
+			n := int64(v)
+			b.BytesPerOp = &n
+		case "allocs/op":
+			n := int64(v)
+			b.AllocsPerOp = &n
+		case "MB/s":
+			b.MBPerSec = v
+		}
+	}
+	return b, ok
+}
+
+// setBenchmarks collects the results the benchmarks of tp printed. Since
+// go test only ends benchmarks with an event in some versions, the ones
+// that ran without one get the bench result once the package is done,
+// provided they printed a result line. A benchmark that only ran
+// sub-benchmarks has no result of its own and is dropped.
+func (tp *TestPkg) setBenchmarks() {
+	var parents map[string]bool
+	kept := tp.TEList[:0]
+	for _, u := range tp.TEList {
+		if !strings.HasPrefix(u.Test, benchPrefix) {
+			kept = append(kept, u)
+			continue
+		}
+		results := 0
+		for _, line := range strings.Split(u.Output.String(), "\n") {
+			if b, ok := parseBenchmark(line); ok {
+				tp.Benchmarks = append(tp.Benchmarks, b)
+				results++
+			}
+		}
+		if len(u.Action) < 1 && len(tp.Action) > 0 {
+			if results < 1 {
+				if parents == nil {
+					parents = tp.parents()
+				}
+				if parents[u.Test] {
+					delete(tp.uts, u.Test)
+					u.Output.remove()
+					continue
+				}
+			}
+			u.Action = events.ActionBench
+			u.ActionType = events.ActionTypeEnd
+		}
+		kept = append(kept, u)
+	}
+	for i := len(kept); i < len(tp.TEList); i++ {
+		tp.TEList[i] = nil
+	}
+	tp.TEList = kept
+}
+
+// CompareBenchmarks sets the change of the ns/op of every benchmark of ti
+// against the mean of its results in base, and marks the benchmarks more
+// than threshold percent slower.
+func (ti *TestInfo) CompareBenchmarks(base *TestInfo, threshold float64) {
+	type mean struct {
+		sum float64
+		n   int
+	}
+	baseMap := map[string]*mean{}
+	for _, tp := range base.TpList {
+		for _, b := range tp.Benchmarks {
+			key := tp.Package + "\x00" + b.Name
+			m := baseMap[key]
+			if m == nil {
+				m = &mean{}
+				baseMap[key] = m
+			}
+			m.sum += b.NsPerOp
+			m.n++
+		}
+	}
+	for _, tp := range ti.TpList {
+		for _, b := range tp.Benchmarks {
+			m := baseMap[tp.Package+"\x00"+b.Name]
+			if m == nil || m.sum <= 0 {
+				continue
+			}
+			b.BaselineNsPerOp = m.sum / float64(m.n)
+			b.Change = (b.NsPerOp - b.BaselineNsPerOp) / b.BaselineNsPerOp * 100
+			b.Slower = b.Change > threshold
+			if b.Slower {
+				tp.SlowerBenchmarks++
+			}
+		}
+	}
+}
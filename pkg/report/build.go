@@ -0,0 +1,71 @@
+package report
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+// buildLog holds the compiler output of the builds of a run by import
+// path, since build-output events precede the final events of the
+// packages that failed for them. The parsers of ParseContext share one.
+type buildLog struct {
+	mu  sync.Mutex
+	out map[string]*strings.Builder
+	// claimed marks the builds whose package has been read, and
+	// dependents are the packages that failed for a build of another.
+	claimed    map[string]bool
+	dependents map[string][]*TestPkg
+}
+
+func newBuildLog() *buildLog {
+	return &buildLog{out: map[string]*strings.Builder{}, claimed: map[string]bool{}, dependents: map[string][]*TestPkg{}}
+}
+
+func (b *buildLog) add(importPath, output string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	w := b.out[importPath]
+	if w == nil {
+		w = &strings.Builder{}
+		b.out[importPath] = w
+	}
+	w.WriteString(output)
+}
+
+// fail returns the output to add to tp, which failed for the build
+// importPath: the compiler output if the build was of tp, or else a line
+// naming the build. Dependents are remembered for attachUnclaimed if
+// keep is set.
+func (b *buildLog) fail(tp *TestPkg, importPath string, keep bool) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if events.BuildPackage(importPath) == tp.Package {
+		b.claimed[importPath] = true
+		if w := b.out[importPath]; w != nil {
+			return w.String()
+		}
+		return ""
+	}
+	if keep {
+		b.dependents[importPath] = append(b.dependents[importPath], tp)
+	}
+	return "build failed in " + importPath + "\n"
+}
+
+// attachUnclaimed adds the compiler output of the builds whose package
+// was not part of the run to the packages that failed for them.
+func (b *buildLog) attachUnclaimed() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for importPath, list := range b.dependents {
+		w := b.out[importPath]
+		if b.claimed[importPath] || w == nil {
+			continue
+		}
+		for _, tp := range list {
+			_, _ = tp.Output.WriteString(w.String())
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var coverageLine = regexp.MustCompile(`coverage: (\d+(?:\.\d+)?)% of statements`)
+
+// Coverage returns the statement coverage of tp in percent: from the
+// cover profile applied with ApplyCoverProfile, or else the one go test
+// -cover printed.
+func (tp *TestPkg) Coverage() (float64, bool) {
+	if tp.Statements > 0 {
+		return tp.StatementCoverage, true
+	}
+	m := coverageLine.FindAllStringSubmatch(tp.Output.String(), -1)
+	if m == nil {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(m[len(m)-1][1], 64)
+	return f, err == nil
+}
+
+// CoverFile is the statement coverage of a source file.
+type CoverFile struct {
+	// Name is the import path of the package followed by the file name.
+	Name       string `xml:"name,attr"`
+	Statements int    `xml:"statements,attr"`
+	Covered    int    `xml:"covered,attr"`
+}
+
+// Percent returns the share of covered statements of f in percent.
+func (f *CoverFile) Percent() float64 {
+	return percent(f.Covered, f.Statements)
+}
+
+// percent is covered of statements in percent, to two decimals.
+func percent(covered, statements int) float64 {
+	if statements < 1 {
+		return 0
+	}
+	return math.Round(float64(covered)/float64(statements)*10000) / 100
+}
+
+// CoverProfile is a cover profile go test -coverprofile wrote, summed
+// per file.
+type CoverProfile struct {
+	Mode  string
+	Files []*CoverFile
+}
+
+// ReadCoverProfile parses the cover profile of r. Blocks found more than
+// once, as when several test binaries cover the same package with
+// -coverpkg, count once and are covered if any of them is.
+func ReadCoverProfile(r io.Reader) (*CoverProfile, error) {
+	type block struct {
+		stmts   int
+		covered bool
+	}
+	p := &CoverProfile{}
+	blocks := map[string]map[string]*block{}
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for no := 1; s.Scan(); no++ {
+		line := strings.TrimSpace(s.Text())
+		if len(line) < 1 {
+			continue
+		}
+		if no == 1 && strings.HasPrefix(line, "mode: ") {
+			p.Mode = strings.TrimPrefix(line, "mode: ")
+			continue
+		}
+		// file:start.col,end.col statements count
+		f := strings.Fields(line)
+		i := strings.LastIndex(line, ":")
+		if len(f) != 3 || i < 0 {
+			return nil, fmt.Errorf("line %d: invalid cover profile line %q", no, line)
+		}
+		stmts, err1 := strconv.Atoi(f[1])
+		count, err2 := strconv.ParseInt(f[2], 10, 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("line %d: invalid cover profile line %q", no, line)
+		}
+		file, pos := line[:i], strings.Fields(line[i+1:])[0]
+		fb := blocks[file]
+		if fb == nil {
+			fb = map[string]*block{}
+			blocks[file] = fb
+		}
+		b := fb[pos]
+		if b == nil {
+			b = &block{stmts: stmts}
+			fb[pos] = b
+		}
+		b.covered = b.covered || count > 0
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	for file, fb := range blocks {
+		cf := &CoverFile{Name: file}
+		for _, b := range fb {
+			cf.Statements += b.stmts
+			if b.covered {
+				cf.Covered += b.stmts
+			}
+		}
+		p.Files = append(p.Files, cf)
+	}
+	sort.Slice(p.Files, func(i, j int) bool {
+		return p.Files[i].Name < p.Files[j].Name
+	})
+	return p, nil
+}
+
+// ApplyCoverProfile sets the statement coverage of the packages of ti,
+// and of ti, from the files of p.
+func (ti *TestInfo) ApplyCoverProfile(p *CoverProfile) {
+	byPkg := map[string][]*CoverFile{}
+	ti.Statements, ti.Covered = 0, 0
+	for _, f := range p.Files {
+		pkg := path.Dir(f.Name)
+		byPkg[pkg] = append(byPkg[pkg], f)
+		ti.Statements += f.Statements
+		ti.Covered += f.Covered
+	}
+	ti.StatementCoverage = percent(ti.Covered, ti.Statements)
+	for _, tp := range ti.TpList {
+		files := byPkg[tp.Package]
+		if len(files) < 1 {
+			continue
+		}
+		tp.CoverFiles, tp.Statements, tp.Covered = files, 0, 0
+		for _, f := range files {
+			tp.Statements += f.Statements
+			tp.Covered += f.Covered
+		}
+		tp.StatementCoverage = percent(tp.Covered, tp.Statements)
+	}
+}
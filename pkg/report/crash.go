@@ -0,0 +1,99 @@
+package report
+
+import (
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+// The failure kinds of tests that did not fail by an assertion, see
+// TestUt.FailureKind.
+const (
+	FailurePanic   = "panic"
+	FailureRace    = "race"
+	FailureTimeout = "timeout"
+)
+
+// failureRank orders the failure kinds a test may show more than one of:
+// a race may lead to a panic, and a timeout ends the test binary.
+var failureRank = map[string]int{FailureRace: 1, FailurePanic: 2, FailureTimeout: 3}
+
+// failureKind classifies a line of output that starts a panic, a fatal
+// runtime error, a timeout or a race report.
+func failureKind(line string) string {
+	switch {
+	case strings.HasPrefix(line, "panic: test timed out after "):
+		return FailureTimeout
+	case strings.HasPrefix(line, "panic: "), strings.HasPrefix(line, "fatal error: "):
+		return FailurePanic
+	case isRaceReport(line):
+		return FailureRace
+	}
+	return ""
+}
+
+// isGoroutineDump tells the header of a goroutine in a stack dump, as in
+// "goroutine 7 [running]:".
+func isGoroutineDump(line string) bool {
+	return strings.HasPrefix(line, "goroutine ") && strings.Contains(line, " [") && strings.HasSuffix(strings.TrimSuffix(line, "\n"), "]:")
+}
+
+// setFailureKind sets the failure kind of u to kind unless it has one
+// that ranks higher.
+func (u *TestUt) setFailureKind(kind string) {
+	if failureRank[kind] > failureRank[u.FailureKind] {
+		u.FailureKind = kind
+	}
+}
+
+// crashOwner returns the test the crash in the package output of tp
+// belongs to: the test that started last of those still running, or nil
+// when none is.
+func (tp *TestPkg) crashOwner() *TestUt {
+	for i := len(tp.TEList) - 1; i >= 0; i-- {
+		if u := tp.TEList[i]; len(u.Action) < 1 {
+			return u
+		}
+	}
+	return nil
+}
+
+func (c *Count) addFailure(kind string) {
+	switch kind {
+	case FailurePanic:
+		c.Panics++
+	case FailureRace:
+		c.Races++
+	case FailureTimeout:
+		c.Timeouts++
+	}
+}
+
+// removeFailure undoes addFailure for a failure that passed on a rerun.
+func (c *Count) removeFailure(kind string) {
+	switch kind {
+	case FailurePanic:
+		c.Panics--
+	case FailureRace:
+		c.Races--
+	case FailureTimeout:
+		c.Timeouts--
+	}
+}
+
+// Crashes returns the failed tests with a failure kind, and the packages
+// whose crash no test was running for, in report order.
+func (ti *TestInfo) Crashes() []*TestUt {
+	var list []*TestUt
+	for _, tp := range ti.TpList {
+		if len(tp.FailureKind) > 0 {
+			list = append(list, tp.TestUt)
+		}
+		for _, u := range tp.TEList {
+			if len(u.FailureKind) > 0 && u.Action == events.ActionFail {
+				list = append(list, u)
+			}
+		}
+	}
+	return list
+}
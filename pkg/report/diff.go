@@ -0,0 +1,66 @@
+package report
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+type DiffEntry struct {
+	Package string  `json:"package"`
+	Test    string  `json:"test"`
+	Old     string  `json:"old,omitempty"`
+	New     string  `json:"new,omitempty"`
+	OldDur  string  `json:"oldDur,omitempty"`
+	NewDur  string  `json:"newDur,omitempty"`
+	Change  float64 `json:"change,omitempty"`
+	oldDur  time.Duration
+	newDur  time.Duration
+}
+
+// Diff is the difference between two reports of the same suite.
+type Diff struct {
+	NewFailures     []*DiffEntry `json:"newFailures"`
+	Fixed           []*DiffEntry `json:"fixed"`
+	NewlySkipped    []*DiffEntry `json:"newlySkipped"`
+	DurationChanges []*DiffEntry `json:"durationChanges"`
+}
+
+// Compare diffs newTi against oldTi. Duration changes are reported when
+// they are at least threshold percent and one of the durations reaches minDur.
+func Compare(oldTi, newTi *TestInfo, threshold float64, minDur time.Duration) *Diff {
+	d := &Diff{NewFailures: []*DiffEntry{}, Fixed: []*DiffEntry{}, NewlySkipped: []*DiffEntry{}, DurationChanges: []*DiffEntry{}}
+	oldMap := oldTi.utMap()
+	for _, tp := range newTi.TpList {
+		for _, u := range tp.TEList {
+			e := &DiffEntry{Package: tp.Package, Test: u.Test, New: u.Action}
+			e.newDur, _ = time.ParseDuration(u.Dur)
+			o, ok := oldMap[tp.Package+"\x00"+u.Test]
+			if ok {
+				e.Old = o.Action
+				e.oldDur, _ = time.ParseDuration(o.Dur)
+			}
+			switch {
+			case u.Action == events.ActionFail && e.Old != events.ActionFail:
+				d.NewFailures = append(d.NewFailures, e)
+			case u.Action == events.ActionPass && e.Old == events.ActionFail:
+				d.Fixed = append(d.Fixed, e)
+			case u.Action == events.ActionSkip && ok && e.Old != events.ActionSkip:
+				d.NewlySkipped = append(d.NewlySkipped, e)
+			}
+			if !ok || (e.oldDur < minDur && e.newDur < minDur) || e.oldDur == 0 {
+				continue
+			}
+			e.Change = float64(e.newDur-e.oldDur) / float64(e.oldDur) * 100
+			if e.Change >= threshold || e.Change <= -threshold {
+				e.OldDur, e.NewDur = e.oldDur.String(), e.newDur.String()
+				d.DurationChanges = append(d.DurationChanges, e)
+			}
+		}
+	}
+	sort.Slice(d.DurationChanges, func(i, j int) bool {
+		return d.DurationChanges[i].Change > d.DurationChanges[j].Change
+	})
+	return d
+}
@@ -0,0 +1,50 @@
+package report
+
+import (
+	"regexp"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+// Filter selects the packages and tests a Parser aggregates. A nil
+// Include expression matches everything and a nil Exclude expression
+// nothing. Tests are matched by their full name, subtests included.
+type Filter struct {
+	IncludePkg, ExcludePkg   *regexp.Regexp
+	IncludeTest, ExcludeTest *regexp.Regexp
+	// FailuresOnly drops the tests that passed once their package is
+	// done, after they were counted.
+	FailuresOnly bool
+}
+
+// Package reports whether the package name is aggregated.
+func (f *Filter) Package(name string) bool {
+	return match(f.IncludePkg, f.ExcludePkg, name)
+}
+
+// Test reports whether the test name is aggregated.
+func (f *Filter) Test(name string) bool {
+	return match(f.IncludeTest, f.ExcludeTest, name)
+}
+
+func match(include, exclude *regexp.Regexp, s string) bool {
+	return (include == nil || include.MatchString(s)) && (exclude == nil || !exclude.MatchString(s))
+}
+
+// dropPassed removes the tests of tp that neither failed nor were
+// skipped, keeping flaky ones.
+func (tp *TestPkg) dropPassed() {
+	kept := tp.TEList[:0]
+	for _, u := range tp.TEList {
+		if u.Action == events.ActionFail || u.Action == events.ActionSkip || u.Flaky {
+			kept = append(kept, u)
+			continue
+		}
+		delete(tp.uts, u.Test)
+		u.Output.remove()
+	}
+	for i := len(kept); i < len(tp.TEList); i++ {
+		tp.TEList[i] = nil
+	}
+	tp.TEList = kept
+}
@@ -0,0 +1,64 @@
+package report
+
+import (
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+// InitTest is the name of the failed test AddInitPanics adds.
+const InitTest = "init"
+
+// initOutput collects the package output s into tp.InitOutput until the
+// first test or the lines go test ends a package with. Streams without
+// test events, such as go test without -v through test2json, only have
+// package output, so the first test header ends it as well.
+func (tp *TestPkg) initOutput(s string) {
+	if tp.initDone {
+		return
+	}
+	line := strings.TrimSuffix(s, "\n")
+	if strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "=== ") || line == "PASS" || line == "FAIL" || strings.HasPrefix(line, "coverage: ") || strings.HasPrefix(line, noTestsWarning) {
+		tp.initDone = true
+		return
+	}
+	if _, ok := parseSummary(s); ok {
+		tp.initDone = true
+		return
+	}
+	if isBenchHeader(line) {
+		return
+	}
+	tp.InitOutput += s
+}
+
+// InitPanic reports whether the package panicked while initializing.
+func (tp *TestPkg) InitPanic() bool {
+	return strings.Contains(tp.InitOutput, "panic: ")
+}
+
+// AddInitPanics adds a failed test named InitTest with the init output
+// to the failed packages that panicked while initializing, so the panic
+// counts as a failure. Call it before SetCount.
+func (ti *TestInfo) AddInitPanics() {
+	for _, tp := range ti.TpList {
+		if tp.Action != events.ActionFail || !tp.InitPanic() || len(tp.TEList) > 0 && tp.TEList[0].Test == InitTest {
+			continue
+		}
+		u := &TestUt{TestEvent: events.TestEvent{
+			Action:     events.ActionFail,
+			Package:    tp.Package,
+			Test:       InitTest,
+			Time:       tp.Time,
+			Index:      tp.Index,
+			ActionType: events.ActionTypeEnd,
+		}}
+		// The panic is counted already, as one of the package.
+		u.FailureKind, tp.FailureKind = tp.FailureKind, ""
+		_, _ = u.Output.WriteString(tp.InitOutput)
+		u.initTime()
+		tp.TEList = append([]*TestUt{u}, tp.TEList...)
+		tp.Total++
+		tp.Fail++
+	}
+}
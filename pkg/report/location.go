@@ -0,0 +1,42 @@
+package report
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Location is a place in a test file that output was logged from, such
+// as the t.Errorf reporting a failure.
+type Location struct {
+	// File is the base name go test prints, relative to the package.
+	File    string
+	Line    int
+	Message string
+}
+
+var locationLine = regexp.MustCompile(`^(\s+)([\w.+-]+\.go):(\d+): ?(.*)$`)
+
+// Locations returns the file:line prefixed messages t.Log and t.Error
+// wrote to the output of u, with their continuation lines.
+func (u *TestUt) Locations() []Location {
+	var locs []Location
+	indent := -1
+	for _, l := range strings.Split(u.Output.String(), "\n") {
+		if m := locationLine.FindStringSubmatch(l); m != nil {
+			n, _ := strconv.Atoi(m[3])
+			locs = append(locs, Location{File: m[2], Line: n, Message: m[4]})
+			indent = len(m[1])
+			continue
+		}
+		// Further lines of a message are indented deeper than its prefix.
+		trimmed := strings.TrimLeft(l, " \t")
+		if indent >= 0 && len(trimmed) > 0 && len(l)-len(trimmed) > indent {
+			last := &locs[len(locs)-1]
+			last.Message += "\n" + trimmed
+			continue
+		}
+		indent = -1
+	}
+	return locs
+}
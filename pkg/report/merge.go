@@ -0,0 +1,162 @@
+package report
+
+import (
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+// MergeMode says what Merge does with a package found in several reports.
+type MergeMode int
+
+const (
+	// MergeUnion keeps the tests of the package from every report, and
+	// the later result of a test found in several, as for sharded runs.
+	MergeUnion MergeMode = iota
+	// MergeLast keeps the package of the last report it is found in.
+	MergeLast
+)
+
+// Merge combines reports into one report, with the packages in the order
+// they are first found in, and recomputes the counts. The counts of
+// reports written with Filter.FailuresOnly then only cover the tests
+// they list. The Shard of a package found in several reports lists all
+// of theirs. The reports must not be used afterwards.
+func Merge(mode MergeMode, reports ...*TestInfo) *TestInfo {
+	ti := &TestInfo{Count: &Count{}}
+	pkgs := map[string]int{}
+	var deleted []string
+	var modules []*Module
+	for _, o := range reports {
+		if o.Time != nil && (ti.Time == nil || o.Time.After(*ti.Time)) {
+			ti.Time = o.Time
+		}
+		ti.Incomplete = ti.Incomplete || o.Incomplete
+		ti.MalformedLines += o.MalformedLines
+		ti.Race = ti.Race || o.Race
+		ti.FailuresOnly = ti.FailuresOnly || o.FailuresOnly
+		if len(o.Timeout) > 0 {
+			ti.Timeout = o.Timeout
+		}
+		modules = append(modules, o.Modules...)
+		deleted = append(deleted, o.DeletedPkgs...)
+		ti.spillDirs = append(ti.spillDirs, o.spillDirs...)
+		for _, tp := range o.TpList {
+			i, ok := pkgs[tp.Package]
+			switch {
+			case !ok:
+				pkgs[tp.Package] = len(ti.TpList)
+				ti.TpList = append(ti.TpList, tp)
+			case mode == MergeLast:
+				ti.TpList[i] = tp
+			default:
+				ti.TpList[i].merge(tp)
+			}
+		}
+	}
+	for _, name := range deleted {
+		if _, ok := pkgs[name]; !ok {
+			pkgs[name] = -1
+			ti.DeletedPkgs = append(ti.DeletedPkgs, name)
+		}
+	}
+	for _, tp := range ti.TpList {
+		tp.recount()
+	}
+	if len(modules) > 0 {
+		ti.GroupModules(modules)
+	}
+	ti.SetCount()
+	return ti
+}
+
+// merge adds the tests of o, a later run of the package, to tp.
+func (tp *TestPkg) merge(o *TestPkg) {
+	pkgFailed := tp.Action == events.ActionFail && tp.Fail < 1 || o.Action == events.ActionFail && o.Fail < 1
+	// The same run of the package read twice, as from the same log given
+	// twice, only replaces the tests.
+	dup := o.Elapsed == tp.Elapsed && o.Output.String() == tp.Output.String()
+	index := map[string]int{}
+	for i, u := range tp.TEList {
+		index[u.Test] = i
+	}
+	for _, u := range o.TEList {
+		if i, ok := index[u.Test]; ok {
+			tp.TEList[i] = u
+			continue
+		}
+		index[u.Test] = len(tp.TEList)
+		tp.TEList = append(tp.TEList, u)
+	}
+	var deleted []*DeletedTest
+	for _, d := range append(tp.DeletedTests, o.DeletedTests...) {
+		if _, ok := index[d.Test]; !ok {
+			index[d.Test] = -1
+			deleted = append(deleted, d)
+		}
+	}
+	tp.DeletedTests = deleted
+	tp.Benchmarks = append(tp.Benchmarks, o.Benchmarks...)
+
+	if !dup {
+		_, _ = tp.Output.WriteString(o.Output.String())
+		tp.Elapsed += o.Elapsed
+	}
+	if len(o.Shard) > 0 && !strings.Contains(","+tp.Shard+",", ","+o.Shard+",") {
+		if len(tp.Shard) > 0 {
+			tp.Shard += ","
+		}
+		tp.Shard += o.Shard
+	}
+	if len(o.InitOutput) > 0 {
+		tp.InitOutput = o.InitOutput
+	}
+	if o.Time != nil {
+		tp.Time = o.Time
+	}
+	tp.NoTests = tp.NoTests && o.NoTests
+	if o.TimeoutUsed > tp.TimeoutUsed {
+		tp.TimeoutUsed = o.TimeoutUsed
+	}
+	tp.TimeoutRisk = tp.TimeoutRisk || o.TimeoutRisk
+	tp.BuildFailed = tp.BuildFailed || o.BuildFailed
+
+	switch {
+	case pkgFailed:
+		tp.Action = events.ActionFail
+	case tp.Action == events.ActionSkip || o.Action == events.ActionPass:
+		tp.Action = o.Action
+	}
+	for _, u := range tp.TEList {
+		if u.Action == events.ActionFail {
+			tp.Action = events.ActionFail
+		}
+	}
+	tp.initTime()
+}
+
+// recount recomputes the counts of tp from its tests.
+func (tp *TestPkg) recount() {
+	tp.Count = &Count{Total: len(tp.TEList), Deleted: len(tp.DeletedTests)}
+	for _, u := range tp.TEList {
+		_ = tp.setCount(u)
+		if u.New {
+			tp.Added++
+		}
+		if u.Regression == RegressionNew {
+			tp.Regressions++
+		}
+		if len(u.Drift) > 0 {
+			tp.Drifted++
+		}
+		if u.Slow {
+			tp.SlowTests++
+		}
+	}
+	tp.addFailure(tp.FailureKind)
+	for _, b := range tp.Benchmarks {
+		if b.Slower {
+			tp.SlowerBenchmarks++
+		}
+	}
+}
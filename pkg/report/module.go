@@ -0,0 +1,57 @@
+package report
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Module is the rollup of the packages of one module of the run.
+type Module struct {
+	Path string `xml:"path,attr"`
+	// Dir is the directory of the module relative to the root of the
+	// repository, if known.
+	Dir string `json:",omitempty" xml:"dir,attr,omitempty"`
+	*Count
+}
+
+// GroupModules sets the Module and Dir of every package of ti from the
+// longest of modules its import path is in, and sums the counts of the
+// packages of each module into ti.Modules. Only Path and Dir of modules
+// are used. Packages outside all modules are left out.
+func (ti *TestInfo) GroupModules(modules []*Module) {
+	sorted := append([]*Module(nil), modules...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i].Path) > len(sorted[j].Path)
+	})
+	byPath := map[string]*Module{}
+	ti.Modules = nil
+	for _, tp := range ti.TpList {
+		var in *Module
+		for _, m := range sorted {
+			if tp.Package == m.Path || strings.HasPrefix(tp.Package, m.Path+"/") {
+				in = m
+				break
+			}
+		}
+		tp.Module, tp.Dir = "", ""
+		if in == nil {
+			continue
+		}
+		tp.Module = in.Path
+		tp.Dir = path.Join(in.Dir, strings.TrimPrefix(tp.Package[len(in.Path):], "/"))
+		if len(tp.Dir) < 1 {
+			tp.Dir = "."
+		}
+		mod := byPath[in.Path]
+		if mod == nil {
+			mod = &Module{Path: in.Path, Dir: in.Dir, Count: &Count{}}
+			byPath[in.Path] = mod
+			ti.Modules = append(ti.Modules, mod)
+		}
+		mod.Add(tp.Count)
+	}
+	sort.Slice(ti.Modules, func(i, j int) bool {
+		return ti.Modules[i].Path < ti.Modules[j].Path
+	})
+}
@@ -0,0 +1,195 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"unicode/utf8"
+)
+
+// Output is the captured output of a test or package. Output that was
+// spilled past the parser's memory budget lives in a temporary file and
+// is only read back while being marshalled.
+type Output struct {
+	buf  []byte
+	path string
+	w    *os.File
+	size int
+}
+
+// Len returns the size of the output in bytes.
+func (o *Output) Len() int {
+	return o.size
+}
+
+// Spilled reports whether the output is kept in a temporary file.
+func (o *Output) Spilled() bool {
+	return len(o.path) > 0
+}
+
+// WriteString appends s.
+func (o *Output) WriteString(s string) (int, error) {
+	o.size += len(s)
+	if !o.Spilled() {
+		if o.buf == nil {
+			o.buf = (*bufPool.Get().(*[]byte))[:0]
+		}
+		o.buf = append(o.buf, s...)
+		return len(s), nil
+	}
+	if o.w == nil {
+		w, err := os.OpenFile(o.path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return 0, err
+		}
+		o.w = w
+	}
+	return io.WriteString(o.w, s)
+}
+
+// String returns the whole output, reading it back if it was spilled.
+func (o *Output) String() string {
+	if !o.Spilled() {
+		return string(o.buf)
+	}
+	_ = o.flush()
+	b, err := ioutil.ReadFile(o.path)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// spill moves the output to a new file in dir.
+func (o *Output) spill(dir string) error {
+	f, err := ioutil.TempFile(dir, "output-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(o.buf)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	o.path, o.w = f.Name(), f
+	o.release()
+	return nil
+}
+
+// flush closes the file being appended to, if any.
+func (o *Output) flush() error {
+	if o.w == nil {
+		return nil
+	}
+	err := o.w.Close()
+	o.w = nil
+	return err
+}
+
+// remove deletes the spill file and returns the buffer to the pool.
+func (o *Output) remove() {
+	if o.Spilled() {
+		_ = o.flush()
+		_ = os.Remove(o.path)
+		o.path = ""
+	}
+	o.release()
+}
+
+// maxPooled is the largest buffer returned to the pool, so one chatty
+// test does not pin its output for the rest of the run.
+const maxPooled = 1 << 20
+
+var bufPool = sync.Pool{New: func() interface{} {
+	b := make([]byte, 0, 512)
+	return &b
+}}
+
+func (o *Output) release() {
+	if o.buf != nil && cap(o.buf) <= maxPooled {
+		b := o.buf[:0]
+		bufPool.Put(&b)
+	}
+	o.buf = nil
+}
+
+// spillChunk is the size of the pieces spilled output is marshalled in.
+const spillChunk = 64 << 10
+
+func (o *Output) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if !o.Spilled() {
+		return e.EncodeElement(string(o.buf), start)
+	}
+	err := o.flush()
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(o.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = e.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, spillChunk)
+	carry := 0
+	for {
+		n, err := f.Read(buf[carry:])
+		n += carry
+		// Hold back a rune split across reads for the next chunk.
+		cut := n
+		for i := 1; i <= utf8.UTFMax && i <= n; i++ {
+			if utf8.RuneStart(buf[n-i]) {
+				if !utf8.FullRune(buf[n-i : n]) {
+					cut = n - i
+				}
+				break
+			}
+		}
+		if err == io.EOF {
+			cut = n
+		}
+		if cut > 0 {
+			if encErr := e.EncodeToken(xml.CharData(buf[:cut])); encErr != nil {
+				return encErr
+			}
+		}
+		carry = copy(buf, buf[cut:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func (o *Output) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	err := d.DecodeElement(&s, &start)
+	if err != nil {
+		return err
+	}
+	*o = Output{buf: []byte(s), size: len(s)}
+	return nil
+}
+
+func (o *Output) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.String())
+}
+
+func (o *Output) UnmarshalJSON(b []byte) error {
+	var s string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+	*o = Output{buf: []byte(s), size: len(s)}
+	return nil
+}
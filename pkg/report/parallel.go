@@ -0,0 +1,217 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+// parallelBatch is the number of lines handed to a worker at once.
+const parallelBatch = 256
+
+// ParseParallel is like Parse but decodes and aggregates the packages of
+// r on up to workers goroutines. Lines are fanned out by package, so
+// every package is still aggregated in order by a single Parser.
+func ParseParallel(r io.Reader, workers int) (*TestInfo, error) {
+	if workers < 2 {
+		return Parse(r)
+	}
+	return ParseContext(context.Background(), r, ParseOptions{Workers: workers})
+}
+
+// readLines reads the lines of r on their own goroutine, so that a
+// blocked read does not hold up cancellation. Lines are sent in batches
+// of up to parallelBatch, a batch as soon as the next read may block, so
+// a slow stream is not held back. Once r is exhausted, lines is closed
+// and the error, io.EOF at the end of r, sent on errc. Once ctx is done,
+// nothing more is sent.
+func readLines(ctx context.Context, r io.Reader) (lines <-chan []*events.Line, errc <-chan error) {
+	lc := make(chan []*events.Line, 4)
+	ec := make(chan error, 1)
+	go func() {
+		d := events.NewDecoder(r)
+		batch := make([]*events.Line, 0, parallelBatch)
+		for {
+			l, err := d.NextLine()
+			if err == nil {
+				batch = append(batch, l)
+			}
+			if len(batch) > 0 && (err != nil || len(batch) >= parallelBatch || d.Buffered() < 1) {
+				select {
+				case lc <- batch:
+				case <-ctx.Done():
+					return
+				}
+				batch = make([]*events.Line, 0, parallelBatch)
+			}
+			if err != nil {
+				ec <- err
+				close(lc)
+				return
+			}
+		}
+	}()
+	return lc, ec
+}
+
+// ParseOptions configures ParseContext.
+type ParseOptions struct {
+	// Workers is the number of goroutines decoding and aggregating.
+	Workers int
+	// OutputBudget is shared by the workers, see Parser.OutputBudget.
+	OutputBudget int64
+	SpillDir     string
+	Malformed    MalformedPolicy
+	Filter       *Filter
+}
+
+// ParseContext is like ParseParallel but stops reading r once ctx is
+// done. The report of what was read so far is then returned with
+// Incomplete set, see Parser.Interrupt.
+func ParseContext(ctx context.Context, r io.Reader, opts ParseOptions) (*TestInfo, error) {
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	now := time.Now()
+	t := &TestInfo{Count: &Count{}, Time: &now}
+	parsers := make([]*Parser, workers)
+	chans := make([]chan []*events.Line, workers)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	builds := newBuildLog()
+	for i := range parsers {
+		parsers[i] = NewParser()
+		parsers[i].builds, parsers[i].sharedBuilds = builds, true
+		parsers[i].SpillDir = opts.SpillDir
+		parsers[i].Malformed = opts.Malformed
+		parsers[i].Filter = opts.Filter
+		if opts.OutputBudget > 0 {
+			parsers[i].OutputBudget = opts.OutputBudget/int64(workers) + 1
+		}
+		chans[i] = make(chan []*events.Line, 4)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for batch := range chans[i] {
+				for _, l := range batch {
+					if errs[i] != nil {
+						break
+					}
+					errs[i] = parsers[i].Line(l)
+				}
+			}
+		}(i)
+	}
+
+	lines, readErr := readLines(ctx, r)
+
+	batches := make([][]*events.Line, workers)
+	for i := range batches {
+		batches[i] = make([]*events.Line, 0, parallelBatch)
+	}
+	// Build events are kept here, since the package that failed for a
+	// build may be another than the one it compiled, and other lines
+	// without a package, such as text that is not JSON, go to the worker
+	// of the preceding event.
+	route := map[string]int{}
+	last := 0
+	interrupted := false
+read:
+	for {
+		select {
+		case <-ctx.Done():
+			interrupted = true
+			break read
+		case batch, ok := <-lines:
+			if !ok {
+				break read
+			}
+			for _, l := range batch {
+				pkg := l.PackageBytes()
+				if len(pkg) < 1 && l.BuildPackageBytes() != nil {
+					if e, err := l.Decode(); err == nil {
+						if e.Action == events.ActionBuildOutput {
+							builds.add(e.ImportPath, e.Output)
+						}
+						continue
+					}
+				}
+				if len(pkg) > 0 {
+					i, ok := route[string(pkg)]
+					if !ok {
+						h := fnv.New32a()
+						_, _ = h.Write(pkg)
+						i = int(h.Sum32() % uint32(workers))
+						route[string(pkg)] = i
+					}
+					last = i
+				}
+				batches[last] = append(batches[last], l)
+				if len(batches[last]) >= parallelBatch {
+					chans[last] <- batches[last]
+					batches[last] = make([]*events.Line, 0, parallelBatch)
+				}
+			}
+		}
+	}
+	for i, batch := range batches {
+		if len(batch) > 0 {
+			chans[i] <- batch
+		}
+		close(chans[i])
+	}
+	wg.Wait()
+	if !interrupted {
+		if err := <-readErr; err != io.EOF {
+			return nil, err
+		}
+	}
+
+	// Report the error of the earliest line, as Parse would.
+	var first error
+	firstLine := 0
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		line := 0
+		var de *events.Error
+		if errors.As(err, &de) {
+			line = de.Line
+		}
+		if first == nil || line < firstLine {
+			first, firstLine = err, line
+		}
+	}
+	for _, p := range parsers {
+		t.spillDirs = append(t.spillDirs, p.ti.spillDirs...)
+	}
+	if first != nil {
+		t.Close()
+		return nil, first
+	}
+	builds.attachUnclaimed()
+	for _, p := range parsers {
+		if interrupted {
+			p.Interrupt()
+		}
+		ti, err := p.Report()
+		if err != nil {
+			t.Close()
+			return nil, err
+		}
+		t.TpList = append(t.TpList, ti.TpList...)
+		t.MalformedLines += ti.MalformedLines
+		t.Race = t.Race || ti.Race
+	}
+	t.Incomplete = interrupted
+	t.FailuresOnly = opts.Filter != nil && opts.Filter.FailuresOnly
+	sortPackages(t.TpList)
+	return t, nil
+}
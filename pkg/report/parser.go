@@ -0,0 +1,545 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+// MalformedPolicy says what to do with lines of the stream that are not
+// JSON events, or events of an unknown action.
+type MalformedPolicy int
+
+const (
+	// MalformedCapture appends the line to the output of the package of
+	// the preceding event.
+	MalformedCapture MalformedPolicy = iota
+	// MalformedSkip drops the line.
+	MalformedSkip
+	// MalformedError stops parsing with an *events.Error.
+	MalformedError
+)
+
+// Parser aggregates a go test -json stream one event at a time, so
+// consumers can react to tests and packages while the stream is still
+// being read. The hooks are optional.
+type Parser struct {
+	// OnTestStart is called when a test starts running.
+	OnTestStart func(tp *TestPkg, u *TestUt)
+	// OnTestEnd is called when a test passes, fails or is skipped.
+	OnTestEnd func(tp *TestPkg, u *TestUt)
+	// OnOutput is called with every piece of output read, u being nil for
+	// the output of the package itself.
+	OnOutput func(tp *TestPkg, u *TestUt, output string)
+	// OnPackageDone is called once the final event of a package is read,
+	// with its counts set.
+	OnPackageDone func(tp *TestPkg)
+	// Filter selects the packages and tests aggregated; nil keeps all.
+	Filter *Filter
+	// Release drops finished packages from the report once OnPackageDone
+	// returns, so memory is bounded by the packages still running.
+	Release bool
+	// OutputBudget is the number of output bytes kept in memory; output
+	// beyond it is spilled to temporary files in SpillDir, or the default
+	// temporary directory. 0 means no limit. See TestInfo.Close.
+	OutputBudget int64
+	SpillDir     string
+	// Malformed says what to do with lines that are not JSON, such as
+	// text a test binary wrote directly to stdout, and with events of
+	// actions newer versions of go test may add.
+	Malformed MalformedPolicy
+
+	mem  int64
+	last string
+	// lastPkg and lastUt spare the map lookups for runs of events of
+	// the same package or test.
+	lastPkg   *TestPkg
+	lastUt    *TestUt
+	lastUtPkg *TestPkg
+	spillDir  string
+	// adopted is set once events without a package were named after a
+	// summary line, see adopt.
+	adopted bool
+
+	ti   *TestInfo
+	pkgs map[string]*TestPkg
+	done map[string]bool
+	// builds holds the compiler output of the run; sharedBuilds is set
+	// when another parser of the run attaches what no package claimed.
+	builds       *buildLog
+	sharedBuilds bool
+}
+
+func NewParser() *Parser {
+	now := time.Now()
+	return &Parser{
+		ti:   &TestInfo{Count: &Count{}, Time: &now},
+		pkgs: map[string]*TestPkg{},
+		done: map[string]bool{},
+	}
+}
+
+// Parse feeds every event of r to p and returns the resulting report.
+func (p *Parser) Parse(r io.Reader) (*TestInfo, error) {
+	d := events.NewDecoder(r)
+	d.Reuse = true
+	for {
+		l, err := d.NextLine()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			err = p.Line(l)
+		}
+		if err != nil {
+			p.ti.Close()
+			return nil, err
+		}
+	}
+	return p.Report()
+}
+
+// ParseContext is like Parse but stops reading r once ctx is done. The
+// report of what was read so far is then returned, see Interrupt.
+func (p *Parser) ParseContext(ctx context.Context, r io.Reader) (*TestInfo, error) {
+	lines, readErr := readLines(ctx, r)
+	for {
+		select {
+		case <-ctx.Done():
+			p.Interrupt()
+			return p.Report()
+		case batch, ok := <-lines:
+			var err error
+			if ok {
+				for _, l := range batch {
+					if err = p.Line(l); err != nil {
+						break
+					}
+				}
+			} else if err = <-readErr; err == io.EOF {
+				return p.Report()
+			}
+			if err != nil {
+				p.ti.Close()
+				return nil, err
+			}
+		}
+	}
+}
+
+// Line decodes l and adds its event to the report. Lines that are not
+// JSON are handled according to p.Malformed.
+func (p *Parser) Line(l *events.Line) error {
+	e, err := l.Decode()
+	if err == nil {
+		return p.Event(e)
+	}
+	var de *events.Error
+	if p.Malformed == MalformedError || !errors.As(err, &de) || !de.Malformed() {
+		return err
+	}
+	p.ti.MalformedLines++
+	if p.Malformed == MalformedSkip {
+		return nil
+	}
+	text := string(l.Text)
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	tp := p.pkg(p.last)
+	if p.OnOutput != nil {
+		p.OnOutput(tp, nil, text)
+	}
+	return p.write(&tp.Output, text)
+}
+
+func (p *Parser) pkg(name string) *TestPkg {
+	if p.lastPkg != nil && p.lastPkg.Package == name {
+		return p.lastPkg
+	}
+	tp := p.pkgs[name]
+	if tp == nil {
+		tp = &TestPkg{TestUt: &TestUt{}, uts: map[string]*TestUt{}, Count: &Count{}}
+		tp.Package = name
+		p.pkgs[name] = tp
+		p.ti.TpList = append(p.ti.TpList, tp)
+	}
+	p.lastPkg = tp
+	return tp
+}
+
+// noTestsWarning is printed by a test binary none of whose tests,
+// examples, fuzz targets or benchmarks matched its flags.
+const noTestsWarning = "testing: warning: no tests to run"
+
+// isRaceReport tells the lines the race detector starts a report, or
+// fails a test on, with.
+func isRaceReport(s string) bool {
+	return strings.HasPrefix(s, "WARNING: DATA RACE") || strings.Contains(s, "race detected during execution of test")
+}
+
+// Event adds e to the report.
+func (p *Parser) Event(e *events.TestEvent) error {
+	switch e.Action {
+	case events.ActionBuildOutput:
+		p.buildLog().add(e.ImportPath, e.Output)
+		return nil
+	case events.ActionBuildFail:
+		return nil
+	}
+	name := e.Package
+	var sum packageSummary
+	isSum := false
+	if len(e.Test) < 1 {
+		sum, isSum = parseSummary(e.Output)
+		if isSum && len(name) < 1 {
+			name = sum.pkg
+			p.adopt(name)
+		}
+	}
+	if f := p.Filter; f != nil && ((len(name) > 0 && !f.Package(name)) || (len(e.Test) > 0 && !f.Test(e.Test))) {
+		return nil
+	}
+	tp := p.pkg(name)
+	p.last = name
+	if p.done[name] && (e.Action == events.ActionStart || (len(e.Test) > 0 && e.ActionType == events.ActionTypeStart)) {
+		p.reopen(tp)
+	}
+	if !p.ti.Race && isRaceReport(e.Output) {
+		p.ti.Race = true
+	}
+	if len(e.Test) < 1 && len(e.Output) > 0 {
+		if u := p.crashTest(tp, e.Output, isSum); u != nil {
+			if p.OnOutput != nil {
+				p.OnOutput(tp, u, e.Output)
+			}
+			return p.write(&u.Output, e.Output)
+		}
+		if p.OnOutput != nil {
+			p.OnOutput(tp, nil, e.Output)
+		}
+	}
+	if len(e.Test) < 1 {
+		err := p.write(&tp.Output, e.Output)
+		if err != nil {
+			return err
+		}
+		if isSum {
+			tp.applySummary(sum, e)
+			tp.BuildFailed = tp.BuildFailed || sum.buildFailed
+		}
+		tp.initOutput(e.Output)
+		if strings.HasPrefix(e.Output, noTestsWarning) {
+			tp.NoTests = true
+		}
+		if e.ActionType == events.ActionTypeEnd {
+			if len(e.FailedBuild) > 0 {
+				tp.BuildFailed = true
+				if out := p.buildLog().fail(tp, e.FailedBuild, !p.Release); len(out) > 0 {
+					if p.OnOutput != nil {
+						p.OnOutput(tp, nil, out)
+					}
+					if err := p.write(&tp.Output, out); err != nil {
+						return err
+					}
+				}
+			}
+			tp.Action = e.Action
+			tp.Time = e.Time
+			tp.Index = e.Index
+			if e.HasElapsed() {
+				tp.Elapsed = e.Elapsed
+				tp.initTime()
+			}
+			return p.finish(tp)
+		}
+		return nil
+	}
+
+	u := p.lastUt
+	if u == nil || u.Test != e.Test || p.lastUtPkg != tp {
+		u = tp.uts[e.Test]
+	}
+	if u == nil {
+		u = &TestUt{TestEvent: events.TestEvent{Test: e.Test}}
+		tp.uts[e.Test] = u
+		tp.TEList = append(tp.TEList, u)
+	}
+	p.lastUt, p.lastUtPkg = u, tp
+	tp.initDone = true
+	if p.OnOutput != nil && len(e.Output) > 0 {
+		p.OnOutput(tp, u, e.Output)
+	}
+	u.setFailureKind(failureKind(e.Output))
+	from := u.Output.Len()
+	err := p.write(&u.Output, e.Output)
+	if err != nil {
+		return err
+	}
+	switch e.ActionType {
+	case events.ActionTypeStart:
+		u.Index = e.Index
+		u.Package = e.Package
+		u.spans = append(u.spans, span{from: from, start: e.Time})
+		if p.OnTestStart != nil {
+			p.OnTestStart(tp, u)
+		}
+	case events.ActionTypeEnd:
+		if e.HasElapsed() {
+			u.Elapsed = e.Elapsed
+		}
+		u.Action = e.Action
+		u.Time = e.Time
+		u.ActionType = events.ActionTypeEnd
+		u.initTime()
+		if n := len(u.spans); n > 0 {
+			u.spans[n-1].to, u.spans[n-1].action, u.spans[n-1].elapsed = u.Output.Len(), u.Action, u.Elapsed
+		}
+		err := u.Output.flush()
+		if err != nil {
+			return err
+		}
+		if p.OnTestEnd != nil {
+			p.OnTestEnd(tp, u)
+		}
+	}
+	return nil
+}
+
+// crashTest returns the test the package output s belongs to when it is
+// part of a crash: a panic, fatal error, timeout, race report or
+// goroutine dump and the output following it up to the end of the
+// package. The failure kind is set on the test, or on the package when
+// no test is running.
+func (p *Parser) crashTest(tp *TestPkg, s string, isSum bool) *TestUt {
+	if isSum || s == "FAIL\n" {
+		tp.crashed = nil
+		return nil
+	}
+	kind := failureKind(s)
+	if len(kind) < 1 && !isGoroutineDump(s) {
+		return tp.crashed
+	}
+	if tp.crashed == nil {
+		tp.crashed = tp.crashOwner()
+	}
+	if tp.crashed == nil {
+		tp.setFailureKind(kind)
+		return nil
+	}
+	tp.crashed.setFailureKind(kind)
+	return tp.crashed
+}
+
+func (p *Parser) buildLog() *buildLog {
+	if p.builds == nil {
+		p.builds = newBuildLog()
+	}
+	return p.builds
+}
+
+// adopt names the events without a package read so far after the
+// package name, since streams converted by test2json only name packages
+// in their summary lines.
+func (p *Parser) adopt(name string) {
+	tp := p.pkgs[""]
+	if tp == nil || p.pkgs[name] != nil {
+		return
+	}
+	p.adopted = true
+	delete(p.pkgs, "")
+	tp.Package = name
+	for _, u := range tp.TEList {
+		u.Package = name
+	}
+	p.pkgs[name] = tp
+}
+
+// Interrupt marks the report as incomplete and fails the tests and
+// packages that have not finished yet, as go test does on a timeout.
+// Call Report afterwards.
+func (p *Parser) Interrupt() {
+	p.ti.Incomplete = true
+	for _, tp := range p.ti.TpList {
+		if p.done[tp.Package] {
+			continue
+		}
+		for _, u := range tp.TEList {
+			if len(u.Action) < 1 {
+				u.Action = events.ActionFail
+				u.ActionType = events.ActionTypeEnd
+			}
+		}
+		if len(tp.Action) < 1 {
+			tp.Action = events.ActionFail
+		}
+	}
+}
+
+// Report finishes the packages whose final event was never read and
+// returns the report. Packages are ordered by the position of their
+// final event.
+func (p *Parser) Report() (*TestInfo, error) {
+	// The result of the whole run that test2json streams end with.
+	if tp := p.pkgs[""]; p.adopted && tp != nil && len(tp.TEList) < 1 {
+		delete(p.pkgs, "")
+		p.dropPkg(tp)
+	}
+	if p.builds != nil && !p.sharedBuilds {
+		p.builds.attachUnclaimed()
+	}
+	for _, tp := range append([]*TestPkg(nil), p.ti.TpList...) {
+		err := p.finish(tp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	sortPackages(p.ti.TpList)
+	p.ti.FailuresOnly = p.Filter != nil && p.Filter.FailuresOnly
+	return p.ti, nil
+}
+
+// sortPackages orders packages by the position of their final event,
+// and those that never ended by name.
+func sortPackages(list []*TestPkg) {
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Index != list[j].Index {
+			return list[i].Index < list[j].Index
+		}
+		return list[i].Package < list[j].Package
+	})
+}
+
+// sortTests orders the tests of tp by the position of the event they
+// started with, and those that never started by name, so parallel tests
+// keep the same order from run to run.
+func (tp *TestPkg) sortTests() {
+	sort.SliceStable(tp.TEList, func(i, j int) bool {
+		a, b := tp.TEList[i], tp.TEList[j]
+		if a.Index != b.Index {
+			return a.Index < b.Index
+		}
+		return a.Test < b.Test
+	})
+}
+
+func (p *Parser) finish(tp *TestPkg) error {
+	if p.done[tp.Package] {
+		return nil
+	}
+	p.done[tp.Package] = true
+	tp.setBenchmarks()
+	tp.sortTests()
+	if tp.Action != events.ActionFail {
+		tp.FailureKind = ""
+	}
+	for _, u := range tp.TEList {
+		if len(u.Action) < 1 && tp.Action == events.ActionFail {
+			// The test binary exited while the test ran.
+			u.Action = events.ActionFail
+			u.ActionType = events.ActionTypeEnd
+			u.Time = tp.Time
+			if n := len(u.spans); n > 0 && u.spans[n-1].start != nil && tp.Time != nil {
+				u.Elapsed = tp.Time.Sub(*u.spans[n-1].start).Seconds()
+				u.spans[n-1].elapsed = u.Elapsed
+			}
+			u.initTime()
+		}
+		u.setAttempts()
+		if u.Action != events.ActionFail {
+			u.FailureKind = ""
+		}
+		err := tp.setCount(u)
+		if err != nil {
+			return err
+		}
+	}
+	tp.Total = len(tp.TEList)
+	// go test -count fails the package of a test that failed and then
+	// passed, which is flaky rather than failed.
+	if tp.Action == events.ActionFail && tp.Fail == 0 && tp.Flakes > 0 && !tp.BuildFailed && len(tp.FailureKind) < 1 {
+		tp.Action = events.ActionPass
+	}
+	tp.addFailure(tp.FailureKind)
+	if p.Filter != nil && p.Filter.FailuresOnly {
+		tp.dropPassed()
+	}
+	if p.OnPackageDone != nil {
+		p.OnPackageDone(tp)
+	}
+	err := tp.Output.flush()
+	if err != nil {
+		return err
+	}
+	if p.Release {
+		tp.Output.remove()
+		for _, u := range tp.TEList {
+			u.Output.remove()
+		}
+		delete(p.pkgs, tp.Package)
+		p.dropPkg(tp)
+	}
+	return nil
+}
+
+// reopen takes tp up again after its final event when the stream goes on
+// with another run of it, as when the log of a rerun is appended to the
+// first one. The earlier runs of its tests become attempts, and its
+// counts are redone once it ends again.
+func (p *Parser) reopen(tp *TestPkg) {
+	delete(p.done, tp.Package)
+	p.lastUt, p.lastUtPkg = nil, nil
+	tp.Count = &Count{}
+	tp.Benchmarks = nil
+	tp.crashed = nil
+	for _, u := range tp.TEList {
+		if len(u.Attempts) < 1 && len(u.Action) > 0 {
+			u.spans = []span{{to: u.Output.Len(), action: u.Action, elapsed: u.Elapsed}}
+		}
+	}
+}
+
+// dropPkg removes tp from the report.
+func (p *Parser) dropPkg(tp *TestPkg) {
+	p.lastPkg, p.lastUt, p.lastUtPkg = nil, nil, nil
+	for i, t := range p.ti.TpList {
+		if t == tp {
+			p.ti.TpList = append(p.ti.TpList[:i], p.ti.TpList[i+1:]...)
+			break
+		}
+	}
+}
+
+// write appends s to o, spilling o to disk if the output kept in memory
+// would exceed the budget.
+func (p *Parser) write(o *Output, s string) error {
+	if len(s) < 1 {
+		return nil
+	}
+	if p.OutputBudget > 0 && !o.Spilled() && p.mem+int64(len(s)) > p.OutputBudget {
+		if len(p.spillDir) < 1 {
+			dir, err := ioutil.TempDir(p.SpillDir, "go-test-report-")
+			if err != nil {
+				return err
+			}
+			p.spillDir = dir
+			p.ti.spillDirs = append(p.ti.spillDirs, dir)
+		}
+		p.mem -= int64(len(o.buf))
+		err := o.spill(p.spillDir)
+		if err != nil {
+			return err
+		}
+	}
+	if !o.Spilled() {
+		p.mem += int64(len(s))
+	}
+	_, err := o.WriteString(s)
+	return err
+}
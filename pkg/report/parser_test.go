@@ -0,0 +1,740 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+// stream returns a go test -json log of pkgs packages with tests tests
+// each, every test writing lines lines of output.
+func stream(pkgs, tests, lines int) []byte {
+	b := &bytes.Buffer{}
+	enc := json.NewEncoder(b)
+	now := time.Date(2022, 1, 23, 16, 58, 49, 0, time.UTC)
+	type event struct {
+		Time    time.Time
+		Action  string
+		Package string
+		Test    string  `json:",omitempty"`
+		Output  string  `json:",omitempty"`
+		Elapsed float64 `json:",omitempty"`
+	}
+	for p := 0; p < pkgs; p++ {
+		pkg := fmt.Sprintf("example.com/module/pkg%d", p)
+		_ = enc.Encode(event{Time: now, Action: "start", Package: pkg})
+		for t := 0; t < tests; t++ {
+			test := fmt.Sprintf("TestCase%d", t)
+			_ = enc.Encode(event{Time: now, Action: "run", Package: pkg, Test: test})
+			_ = enc.Encode(event{Time: now, Action: "output", Package: pkg, Test: test, Output: "=== RUN   " + test + "\n"})
+			for l := 0; l < lines; l++ {
+				_ = enc.Encode(event{Time: now, Action: "output", Package: pkg, Test: test, Output: fmt.Sprintf("    case_test.go:%d: step %d done\n", l, l)})
+			}
+			_ = enc.Encode(event{Time: now, Action: "output", Package: pkg, Test: test, Output: "--- PASS: " + test + " (0.01s)\n"})
+			_ = enc.Encode(event{Time: now, Action: "pass", Package: pkg, Test: test, Elapsed: 0.01})
+		}
+		_ = enc.Encode(event{Time: now, Action: "output", Package: pkg, Output: "PASS\n"})
+		_ = enc.Encode(event{Time: now, Action: "pass", Package: pkg, Elapsed: 1.5})
+	}
+	return b.Bytes()
+}
+
+// parse aggregates the events of log, one per line, and sets the counts.
+func parse(t *testing.T, log string) *TestInfo {
+	t.Helper()
+	ti, err := Parse(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti.SetCount()
+	return ti
+}
+
+func TestParse(t *testing.T) {
+	ti := parse(t, `{"Action":"start","Package":"a"}
+{"Action":"run","Package":"a","Test":"TestPass"}
+{"Action":"output","Package":"a","Test":"TestPass","Output":"=== RUN   TestPass\n"}
+{"Action":"pass","Package":"a","Test":"TestPass","Elapsed":0.5}
+{"Action":"run","Package":"a","Test":"TestFail"}
+{"Action":"output","Package":"a","Test":"TestFail","Output":"    a_test.go:9: broken\n"}
+{"Action":"fail","Package":"a","Test":"TestFail","Elapsed":0.25}
+{"Action":"run","Package":"a","Test":"TestSkip"}
+{"Action":"skip","Package":"a","Test":"TestSkip"}
+{"Action":"output","Package":"a","Output":"FAIL\n"}
+{"Action":"fail","Package":"a","Elapsed":1}
+{"Action":"start","Package":"b"}
+{"Action":"output","Package":"b","Output":"testing: warning: no tests to run\n"}
+{"Action":"pass","Package":"b","Elapsed":0}
+`)
+	if len(ti.TpList) != 2 || ti.TpList[0].Package != "a" || ti.TpList[1].Package != "b" {
+		t.Fatalf("got packages %v", ti.TpList)
+	}
+	if c := *ti.Count; c.Total != 3 || c.Pass != 1 || c.Fail != 1 || c.Skip != 1 {
+		t.Errorf("got count %+v", c)
+	}
+	a := ti.Pkg("a")
+	if a.Action != "fail" || a.Elapsed != 1 || a.Output.String() != "FAIL\n" {
+		t.Errorf("got package %s %g %q", a.Action, a.Elapsed, a.Output.String())
+	}
+	want := []struct {
+		test, action string
+		elapsed      float64
+	}{{"TestPass", "pass", 0.5}, {"TestFail", "fail", 0.25}, {"TestSkip", "skip", 0}}
+	if len(a.TEList) != len(want) {
+		t.Fatalf("got %d tests", len(a.TEList))
+	}
+	for i, w := range want {
+		u := a.TEList[i]
+		if u.Test != w.test || u.Action != w.action || u.Elapsed != w.elapsed {
+			t.Errorf("test %d: got %s %s %g, want %+v", i, u.Test, u.Action, u.Elapsed, w)
+		}
+	}
+	if locs := a.TEList[1].Locations(); len(locs) != 1 || locs[0].Message != "broken" {
+		t.Errorf("got locations %+v", locs)
+	}
+	if b := ti.Pkg("b"); !b.NoTests {
+		t.Error("package without tests to run not marked")
+	}
+}
+
+func TestParseSubtests(t *testing.T) {
+	ti := parse(t, `{"Action":"run","Package":"a","Test":"TestA"}
+{"Action":"run","Package":"a","Test":"TestA/one"}
+{"Action":"pass","Package":"a","Test":"TestA/one"}
+{"Action":"run","Package":"a","Test":"TestA/two"}
+{"Action":"fail","Package":"a","Test":"TestA/two"}
+{"Action":"fail","Package":"a","Test":"TestA"}
+{"Action":"fail","Package":"a"}
+`)
+	if n := len(ti.Pkg("a").TEList); n != 3 {
+		t.Fatalf("got %d tests, want 3 flat ones", n)
+	}
+	tree := ti.Tree().Pkg("a").TEList
+	if len(tree) != 1 || len(tree[0].Subtests) != 2 {
+		t.Fatalf("got tree %+v", tree)
+	}
+	if r := tree[0].Rollup; r.SubTotal != 2 || r.SubPass != 1 || r.SubFail != 1 {
+		t.Errorf("got rollup %+v", r)
+	}
+}
+
+func TestSetSlowest(t *testing.T) {
+	ti := parse(t, `{"Action":"run","Package":"a","Test":"TestA"}
+{"Action":"run","Package":"a","Test":"TestA/one"}
+{"Action":"pass","Package":"a","Test":"TestA/one","Elapsed":2}
+{"Action":"run","Package":"a","Test":"TestA/two"}
+{"Action":"pass","Package":"a","Test":"TestA/two","Elapsed":1}
+{"Action":"pass","Package":"a","Test":"TestA","Elapsed":3}
+{"Action":"run","Package":"a","Test":"TestB"}
+{"Action":"pass","Package":"a","Test":"TestB","Elapsed":1.5}
+{"Action":"run","Package":"a","Test":"TestZero"}
+{"Action":"pass","Package":"a","Test":"TestZero"}
+{"Action":"pass","Package":"a","Elapsed":5}
+`)
+	ti.SetSlowest(10)
+	var got []string
+	for _, st := range ti.Slowest {
+		got = append(got, st.Test)
+	}
+	if want := "TestA/one TestB TestA/two"; strings.Join(got, " ") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+}
+
+func TestParseAttempts(t *testing.T) {
+	ti := parse(t, `{"Action":"run","Package":"a","Test":"TestFlaky"}
+{"Action":"output","Package":"a","Test":"TestFlaky","Output":"first\n"}
+{"Action":"fail","Package":"a","Test":"TestFlaky","Elapsed":1}
+{"Action":"run","Package":"a","Test":"TestFlaky"}
+{"Action":"output","Package":"a","Test":"TestFlaky","Output":"second\n"}
+{"Action":"pass","Package":"a","Test":"TestFlaky","Elapsed":2}
+{"Action":"run","Package":"a","Test":"TestBroken"}
+{"Action":"pass","Package":"a","Test":"TestBroken"}
+{"Action":"run","Package":"a","Test":"TestBroken"}
+{"Action":"fail","Package":"a","Test":"TestBroken"}
+{"Action":"fail","Package":"a"}
+`)
+	u := ti.Pkg("a").TEList
+	if len(u) != 2 {
+		t.Fatalf("got %d tests", len(u))
+	}
+	if u[0].Action != "pass" || !u[0].Flaky || u[0].Elapsed != 3 || len(u[0].Attempts) != 2 {
+		t.Fatalf("got %s %g with %d attempts, flaky %v", u[0].Action, u[0].Elapsed, len(u[0].Attempts), u[0].Flaky)
+	}
+	if a := u[0].Attempts[1]; a.Action != "pass" || a.Output != "second\n" {
+		t.Errorf("got attempt %+v", a)
+	}
+	if u[1].Action != "fail" || u[1].Flaky {
+		t.Errorf("test failing its last attempt: got %s, flaky %v", u[1].Action, u[1].Flaky)
+	}
+	if c := *ti.Count; c.Pass != 1 || c.Fail != 1 || c.Flakes != 1 {
+		t.Errorf("got count %+v", c)
+	}
+}
+
+func TestParseCountFlaky(t *testing.T) {
+	ti := parse(t, `{"Action":"run","Package":"a","Test":"TestFlaky"}
+{"Action":"fail","Package":"a","Test":"TestFlaky","Elapsed":1}
+{"Action":"run","Package":"a","Test":"TestFlaky"}
+{"Action":"pass","Package":"a","Test":"TestFlaky","Elapsed":1}
+{"Action":"output","Package":"a","Output":"FAIL\ta\t2.000s\n"}
+{"Action":"fail","Package":"a","Elapsed":2}
+`)
+	if tp := ti.Pkg("a"); tp.Action != "pass" || tp.Fail != 0 || tp.Flakes != 1 {
+		t.Errorf("got package %s with %d failed and %d flaky", tp.Action, tp.Fail, tp.Flakes)
+	}
+}
+
+func TestParseRerunInStream(t *testing.T) {
+	first := `{"Action":"start","Package":"a"}
+{"Action":"run","Package":"a","Test":"TestFlaky"}
+{"Action":"output","Package":"a","Test":"TestFlaky","Output":"    a_test.go:7: boom\n"}
+{"Action":"fail","Package":"a","Test":"TestFlaky","Elapsed":1}
+{"Action":"run","Package":"a","Test":"TestPass"}
+{"Action":"pass","Package":"a","Test":"TestPass","Elapsed":1}
+{"Action":"output","Package":"a","Output":"FAIL\ta\t2.000s\n"}
+{"Action":"fail","Package":"a","Elapsed":2}
+`
+	second := `{"Action":"start","Package":"a"}
+{"Action":"run","Package":"a","Test":"TestFlaky"}
+{"Action":"output","Package":"a","Test":"TestFlaky","Output":"ok\n"}
+{"Action":"pass","Package":"a","Test":"TestFlaky","Elapsed":2}
+{"Action":"output","Package":"a","Output":"ok  \ta\t2.000s\n"}
+{"Action":"pass","Package":"a","Elapsed":2}
+`
+	for _, workers := range []int{1, 4} {
+		ti, err := ParseParallel(strings.NewReader(first+second), workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ti.SetCount()
+		tp := ti.Pkg("a")
+		if tp.Action != "pass" || len(ti.TpList) != 1 {
+			t.Fatalf("workers %d: got package %s of %d", workers, tp.Action, len(ti.TpList))
+		}
+		u := tp.Ut("TestFlaky")
+		if u.Action != "pass" || !u.Flaky || len(u.Attempts) != 2 || u.Elapsed != 3 {
+			t.Errorf("workers %d: got %s %g with %d attempts, flaky %v", workers, u.Action, u.Elapsed, len(u.Attempts), u.Flaky)
+		} else if u.Attempts[0].Action != "fail" || u.Attempts[1].Output != "ok\n" {
+			t.Errorf("workers %d: got attempts %+v %+v", workers, u.Attempts[0], u.Attempts[1])
+		}
+		if u := tp.Ut("TestPass"); u.Action != "pass" || len(u.Attempts) > 0 {
+			t.Errorf("workers %d: TestPass: got %s with %d attempts", workers, u.Action, len(u.Attempts))
+		}
+		if c := *ti.Count; c.Pass != 2 || c.Fail != 0 || c.Flakes != 1 || c.Total != 2 {
+			t.Errorf("workers %d: got count %+v", workers, c)
+		}
+	}
+}
+
+func TestParseBuildFailed(t *testing.T) {
+	ti := parse(t, `{"ImportPath":"a [a.test]","Action":"build-output","Output":"# a [a.test]\n"}
+{"ImportPath":"a [a.test]","Action":"build-output","Output":"a_test.go:3:2: undefined: x\n"}
+{"ImportPath":"a [a.test]","Action":"build-fail"}
+{"Action":"start","Package":"a"}
+{"Action":"output","Package":"a","Output":"FAIL\ta [build failed]\n"}
+{"Action":"fail","Package":"a","FailedBuild":"a [a.test]"}
+`)
+	a := ti.Pkg("a")
+	if a == nil || !a.BuildFailed || a.Action != "fail" {
+		t.Fatalf("got %+v", a)
+	}
+	if !strings.Contains(a.Output.String(), "undefined: x") {
+		t.Errorf("compiler output missing from %q", a.Output.String())
+	}
+}
+
+// buildFailed is a log of four packages: a passes, b fails to build, c
+// has a broken external test package, and d fails for a dependency on b.
+const buildFailed = `{"Action":"start","Package":"a"}
+{"Action":"run","Package":"a","Test":"TestA"}
+{"Action":"pass","Package":"a","Test":"TestA","Elapsed":0.1}
+{"Action":"pass","Package":"a","Elapsed":0.2}
+{"ImportPath":"b","Action":"build-output","Output":"# b\n"}
+{"ImportPath":"b","Action":"build-output","Output":"b.go:5:1: syntax error\n"}
+{"ImportPath":"b","Action":"build-fail"}
+{"ImportPath":"c_test [c.test]","Action":"build-output","Output":"# c_test [c.test]\n"}
+{"ImportPath":"c_test [c.test]","Action":"build-output","Output":"c_test.go:7:2: undefined: y\n"}
+{"ImportPath":"c_test [c.test]","Action":"build-fail"}
+{"Action":"start","Package":"c"}
+{"Action":"output","Package":"c","Output":"FAIL\tc [build failed]\n"}
+{"Action":"fail","Package":"c","FailedBuild":"c_test [c.test]"}
+{"Action":"start","Package":"b"}
+{"Action":"output","Package":"b","Output":"FAIL\tb [build failed]\n"}
+{"Action":"fail","Package":"b","FailedBuild":"b"}
+{"Action":"start","Package":"d"}
+{"Action":"output","Package":"d","Output":"FAIL\td [build failed]\n"}
+{"Action":"fail","Package":"d","FailedBuild":"b"}
+`
+
+func TestParseBuildFailedPackages(t *testing.T) {
+	ti := parse(t, buildFailed)
+	for _, c := range []struct {
+		pkg          string
+		failed       bool
+		want, absent string
+	}{
+		{"a", false, "", "syntax error"},
+		{"b", true, "syntax error", "build failed in"},
+		{"c", true, "undefined: y", "build failed in"},
+		{"d", true, "build failed in b", "syntax error"},
+	} {
+		tp := ti.Pkg(c.pkg)
+		if tp == nil || tp.BuildFailed != c.failed {
+			t.Errorf("%s: got %+v", c.pkg, tp)
+			continue
+		}
+		out := tp.Output.String()
+		if !strings.Contains(out, c.want) || strings.Contains(out, c.absent) {
+			t.Errorf("%s: output is %q", c.pkg, out)
+		}
+	}
+
+	// Without the broken package in the run, its dependents show its
+	// compiler output.
+	ti = parse(t, `{"ImportPath":"b","Action":"build-output","Output":"b.go:5:1: syntax error\n"}
+{"ImportPath":"b","Action":"build-fail"}
+{"Action":"start","Package":"d"}
+{"Action":"fail","Package":"d","FailedBuild":"b"}
+`)
+	if out := ti.Pkg("d").Output.String(); !strings.Contains(out, "syntax error") {
+		t.Errorf("d: output is %q", out)
+	}
+}
+
+func TestParseCrashes(t *testing.T) {
+	ti := parse(t, `{"Action":"run","Package":"a","Test":"TestRace"}
+{"Action":"output","Package":"a","Test":"TestRace","Output":"WARNING: DATA RACE\n"}
+{"Action":"output","Package":"a","Test":"TestRace","Output":"--- FAIL: TestRace (0.00s)\n"}
+{"Action":"fail","Package":"a","Test":"TestRace"}
+{"Action":"run","Package":"a","Test":"TestHang"}
+{"Action":"output","Package":"a","Output":"panic: test timed out after 1s\n"}
+{"Action":"output","Package":"a","Output":"goroutine 7 [sleep]:\n"}
+{"Action":"output","Package":"a","Output":"FAIL\ta\t1.002s\n"}
+{"Action":"fail","Package":"a","Elapsed":1.002}
+{"Action":"output","Package":"b","Output":"panic: init\n"}
+{"Action":"output","Package":"b","Output":"FAIL\tb\t0.001s\n"}
+{"Action":"fail","Package":"b","Elapsed":0.001}
+{"Action":"run","Package":"c","Test":"TestPrint"}
+{"Action":"output","Package":"c","Test":"TestPrint","Output":"panic: not really\n"}
+{"Action":"pass","Package":"c","Test":"TestPrint"}
+{"Action":"pass","Package":"c"}
+`)
+	a := ti.Pkg("a")
+	if u := a.TEList[0]; u.FailureKind != FailureRace {
+		t.Errorf("TestRace: got failure kind %q", u.FailureKind)
+	}
+	u := a.TEList[1]
+	if u.Action != "fail" || u.FailureKind != FailureTimeout {
+		t.Errorf("TestHang: got %s, failure kind %q", u.Action, u.FailureKind)
+	}
+	if out := u.Output.String(); !strings.Contains(out, "goroutine 7") || strings.Contains(a.Output.String(), "timed out") {
+		t.Errorf("TestHang: timeout not attributed, got %q and package output %q", out, a.Output.String())
+	}
+	if b := ti.Pkg("b"); b.FailureKind != FailurePanic || b.Panics != 1 {
+		t.Errorf("b: got failure kind %q, %d panics", b.FailureKind, b.Panics)
+	}
+	if u := ti.Pkg("c").TEList[0]; len(u.FailureKind) > 0 {
+		t.Errorf("TestPrint passed but got failure kind %q", u.FailureKind)
+	}
+	if ti.Races != 1 || ti.Timeouts != 1 || ti.Panics != 1 || len(ti.Crashes()) != 3 {
+		t.Errorf("got %d races, %d timeouts, %d panics, %d crashes", ti.Races, ti.Timeouts, ti.Panics, len(ti.Crashes()))
+	}
+}
+
+func TestMergeRerun(t *testing.T) {
+	ti, err := Parse(strings.NewReader(`{"Action":"run","Package":"a","Test":"TestRace"}
+{"Action":"output","Package":"a","Test":"TestRace","Output":"WARNING: DATA RACE\n"}
+{"Action":"fail","Package":"a","Test":"TestRace"}
+{"Action":"run","Package":"a","Test":"TestPanic"}
+{"Action":"output","Package":"a","Output":"panic: boom\n"}
+{"Action":"output","Package":"a","Output":"FAIL\ta\t0.001s\n"}
+{"Action":"fail","Package":"a","Elapsed":0.001}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti.MergeRerun(parse(t, `{"Action":"run","Package":"a","Test":"TestPanic"}
+{"Action":"pass","Package":"a","Test":"TestPanic"}
+{"Action":"pass","Package":"a"}
+`))
+	ti.SetCount()
+	a := ti.Pkg("a")
+	if u := a.TEList[1]; u.Action != "pass" || !u.Flaky || len(u.FailureKind) > 0 {
+		t.Errorf("TestPanic: got %s, flaky %v, failure kind %q", u.Action, u.Flaky, u.FailureKind)
+	}
+	if u := a.TEList[0]; u.FailureKind != FailureRace {
+		t.Errorf("TestRace: got failure kind %q", u.FailureKind)
+	}
+	if ti.Panics != 0 || ti.Races != 1 || ti.Fail != 1 || ti.Flakes != 1 || len(ti.Crashes()) != 1 {
+		t.Errorf("got %d panics, %d races, %d failed, %d flaky, %d crashes", ti.Panics, ti.Races, ti.Fail, ti.Flakes, len(ti.Crashes()))
+	}
+}
+
+func TestParseTimes(t *testing.T) {
+	ti := parse(t, `{"Time":"2024-01-02T10:00:00Z","Action":"run","Package":"a","Test":"TestB"}
+{"Time":"2024-01-02T10:00:00Z","Action":"run","Package":"a","Test":"TestA"}
+{"Time":"2024-01-02T10:00:01.5Z","Action":"pass","Package":"a","Test":"TestA","Elapsed":1.5}
+{"Time":"2024-01-02T10:00:02Z","Action":"pass","Package":"a","Test":"TestB","Elapsed":2}
+{"Time":"2024-01-02T10:00:02.5Z","Action":"pass","Package":"a","Elapsed":2.5}
+`)
+	a := ti.Pkg("a")
+	if a.TEList[0].Test != "TestB" || a.TEList[1].Test != "TestA" {
+		t.Errorf("tests not in start order: %s, %s", a.TEList[0].Test, a.TEList[1].Test)
+	}
+	if u := a.TEList[1]; u.StarTime != "10:00:00.000" || u.EndTime != "10:00:01.500" {
+		t.Errorf("TestA: got %s to %s", u.StarTime, u.EndTime)
+	}
+	ti.SetTimestamps(TimestampsRelative)
+	if a.StarTime != "00:00:00.000" || a.EndTime != "00:00:02.500" || a.TEList[1].EndTime != "00:00:01.500" {
+		t.Errorf("relative: got package %s to %s, TestA ending %s", a.StarTime, a.EndTime, a.TEList[1].EndTime)
+	}
+	ti.SetTimestamps(TimestampsOff)
+	if len(a.StarTime) > 0 || len(a.TEList[0].EndTime) > 0 || a.Dur != "2.5s" {
+		t.Errorf("off: got %q to %q, %s", a.StarTime, a.TEList[0].EndTime, a.Dur)
+	}
+	if a.Time != nil || a.TEList[0].Time != nil {
+		t.Errorf("off: got package time %v, TestB time %v", a.Time, a.TEList[0].Time)
+	}
+}
+
+func TestParseBenchmarks(t *testing.T) {
+	ti := parse(t, `{"Action":"output","Package":"a","Output":"goos: linux\n"}
+{"Action":"run","Package":"a","Test":"BenchmarkX"}
+{"Action":"output","Package":"a","Test":"BenchmarkX","Output":"BenchmarkX-8   \t 1000\t  1234 ns/op\t  64 B/op\t   2 allocs/op\n"}
+{"Action":"pass","Package":"a"}
+`)
+	b := ti.Pkg("a").Benchmarks
+	if len(b) != 1 {
+		t.Fatalf("got %d benchmarks", len(b))
+	}
+	if b[0].Name != "BenchmarkX" || b[0].Procs != 8 || b[0].Iterations != 1000 || b[0].NsPerOp != 1234 ||
+		b[0].BytesPerOp == nil || *b[0].BytesPerOp != 64 || b[0].AllocsPerOp == nil || *b[0].AllocsPerOp != 2 {
+		t.Errorf("got %+v", b[0])
+	}
+	if ti.Bench != 1 {
+		t.Errorf("got %d benchmarks counted", ti.Bench)
+	}
+}
+
+func TestParseSubBenchmarks(t *testing.T) {
+	ti := parse(t, `{"Action":"run","Package":"a","Test":"BenchmarkX"}
+{"Action":"output","Package":"a","Test":"BenchmarkX","Output":"BenchmarkX\n"}
+{"Action":"run","Package":"a","Test":"BenchmarkX/a"}
+{"Action":"output","Package":"a","Test":"BenchmarkX/a","Output":"BenchmarkX/a         \t     100\t         2.480 ns/op\n"}
+{"Action":"run","Package":"a","Test":"BenchmarkX/b"}
+{"Action":"output","Package":"a","Test":"BenchmarkX/b","Output":"BenchmarkX/b         \t"}
+{"Action":"output","Package":"a","Test":"BenchmarkX/b","Output":"     100\t         3.200 ns/op\n"}
+{"Action":"pass","Package":"a"}
+`)
+	tp := ti.Pkg("a")
+	if len(tp.Benchmarks) != 2 {
+		t.Fatalf("got %d benchmarks", len(tp.Benchmarks))
+	}
+	if ti.Bench != 2 || ti.Total != 2 {
+		t.Errorf("got %d benchmarks counted of %d tests", ti.Bench, ti.Total)
+	}
+	for _, u := range tp.TEList {
+		if u.Test == "BenchmarkX" {
+			t.Error("parent benchmark listed")
+		}
+	}
+}
+
+func TestInitPanic(t *testing.T) {
+	for _, c := range []struct {
+		name, log string
+		init      bool
+	}{
+		{"init", `{"Action":"start","Package":"a"}
+{"Action":"output","Package":"a","Output":"panic: boom\n"}
+{"Action":"output","Package":"a","Output":"\n"}
+{"Action":"output","Package":"a","Output":"goroutine 1 [running]:\n"}
+{"Action":"output","Package":"a","Output":"FAIL\ta\t0.01s\n"}
+{"Action":"fail","Package":"a"}
+`, true},
+		// Without -v a test that panics only writes package output.
+		{"test without -v", `{"Action":"start","Package":"a"}
+{"Action":"output","Package":"a","Output":"--- FAIL: TestX (0.00s)\n"}
+{"Action":"output","Package":"a","Output":"panic: boom [recovered]\n"}
+{"Action":"output","Package":"a","Output":"\tpanic: boom\n"}
+{"Action":"output","Package":"a","Output":"FAIL\ta\t0.01s\n"}
+{"Action":"fail","Package":"a"}
+`, false},
+	} {
+		ti, err := Parse(strings.NewReader(c.log))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ti.AddInitPanics()
+		ti.SetCount()
+		tp := ti.Pkg("a")
+		if tp.InitPanic() != c.init {
+			t.Errorf("%s: init panic %v, init output %q", c.name, tp.InitPanic(), tp.InitOutput)
+		}
+		if added := len(tp.TEList) > 0 && tp.TEList[0].Test == InitTest; added != c.init {
+			t.Errorf("%s: init test added: %v", c.name, added)
+		}
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	log := `{"Action":"run","Package":"a","Test":"TestA"}
+not json
+{"Action":"pass","Package":"a","Test":"TestA"}
+{"Action":"pass","Package":"a"}
+`
+	for _, policy := range []MalformedPolicy{MalformedCapture, MalformedSkip, MalformedError} {
+		ti, err := ParseContext(context.Background(), strings.NewReader(log), ParseOptions{Malformed: policy})
+		if policy == MalformedError {
+			if err == nil {
+				t.Error("error policy: no error")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ti.MalformedLines != 1 {
+			t.Errorf("policy %d: got %d malformed lines", policy, ti.MalformedLines)
+		}
+		captured := strings.Contains(ti.Pkg("a").Output.String(), "not json")
+		if captured != (policy == MalformedCapture) {
+			t.Errorf("policy %d: line captured: %v", policy, captured)
+		}
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	log := `{"Action":"run","Package":"a","Test":"TestA"}
+{"Action":"pass","Package":"a","Test":"TestA"}
+{"Action":"run","Package":"a","Test":"TestB"}
+{"Action":"fail","Package":"a","Test":"TestB"}
+{"Action":"run","Package":"a","Test":"TestC"}
+{"Action":"skip","Package":"a","Test":"TestC"}
+{"Action":"fail","Package":"a"}
+{"Action":"run","Package":"b","Test":"TestA"}
+{"Action":"pass","Package":"b","Test":"TestA"}
+{"Action":"pass","Package":"b"}
+`
+	ti, err := ParseContext(context.Background(), strings.NewReader(log), ParseOptions{Filter: &Filter{
+		ExcludePkg:   regexp.MustCompile("^b$"),
+		ExcludeTest:  regexp.MustCompile("C$"),
+		FailuresOnly: true,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti.SetCount()
+	if len(ti.TpList) != 1 || !ti.FailuresOnly {
+		t.Fatalf("got %d packages, failures only %v", len(ti.TpList), ti.FailuresOnly)
+	}
+	if u := ti.TpList[0].TEList; len(u) != 1 || u[0].Test != "TestB" {
+		t.Errorf("got tests %v", u)
+	}
+	if c := *ti.Count; c.Total != 2 || c.Pass != 1 || c.Fail != 1 || c.Skip != 0 {
+		t.Errorf("got count %+v", c)
+	}
+	if problems := ti.Validate(); len(problems) > 0 {
+		t.Errorf("invalid report: %v", problems)
+	}
+}
+
+func TestApplyBaseline(t *testing.T) {
+	base := parse(t, `{"Action":"run","Package":"a","Test":"TestA"}
+{"Action":"pass","Package":"a","Test":"TestA"}
+{"Action":"run","Package":"a","Test":"TestB"}
+{"Action":"pass","Package":"a","Test":"TestB"}
+{"Action":"run","Package":"a","Test":"TestGone"}
+{"Action":"pass","Package":"a","Test":"TestGone"}
+{"Action":"pass","Package":"a"}
+{"Action":"run","Package":"old","Test":"TestX"}
+{"Action":"pass","Package":"old","Test":"TestX"}
+{"Action":"pass","Package":"old"}
+`)
+	log := `{"Action":"run","Package":"a","Test":"TestA"}
+{"Action":"pass","Package":"a","Test":"TestA"}
+{"Action":"run","Package":"a","Test":"TestB"}
+{"Action":"fail","Package":"a","Test":"TestB"}
+{"Action":"run","Package":"a","Test":"TestNew"}
+{"Action":"pass","Package":"a","Test":"TestNew"}
+{"Action":"fail","Package":"a"}
+`
+	for _, c := range []struct {
+		name         string
+		filter       *Filter
+		deletedPkgs  []string
+		deletedTests []string
+		deleted      int
+		added        int
+	}{
+		{"none", nil, []string{"old"}, []string{"TestGone"}, 1, 1},
+		{"failures only", &Filter{FailuresOnly: true}, []string{"old"}, nil, 1, 0},
+		{"exclude package", &Filter{ExcludePkg: regexp.MustCompile("^old$")}, nil, []string{"TestGone"}, 0, 1},
+		{"exclude test", &Filter{ExcludeTest: regexp.MustCompile("Gone|X")}, []string{"old"}, nil, 0, 1},
+	} {
+		ti, err := ParseContext(context.Background(), strings.NewReader(log), ParseOptions{Filter: c.filter})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ti.SetCount()
+		ti.ApplyBaseline(base, c.filter)
+		var deleted []string
+		for _, d := range ti.Pkg("a").DeletedTests {
+			deleted = append(deleted, d.Test)
+		}
+		if !reflect.DeepEqual(ti.DeletedPkgs, c.deletedPkgs) || !reflect.DeepEqual(deleted, c.deletedTests) {
+			t.Errorf("%s: got deleted packages %v and tests %v", c.name, ti.DeletedPkgs, deleted)
+		}
+		if ti.Deleted != c.deleted {
+			t.Errorf("%s: got %d tests of deleted packages, want %d", c.name, ti.Deleted, c.deleted)
+		}
+		a := ti.Pkg("a")
+		if a.Regressions != 1 || a.Added != c.added {
+			t.Errorf("%s: got %d regressions and %d added", c.name, a.Regressions, a.Added)
+		}
+	}
+}
+
+func TestParseContextMatchesParse(t *testing.T) {
+	log := append(stream(8, 20, 2), buildFailed...)
+	want, err := Parse(bytes.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want.SetCount()
+	for _, workers := range []int{1, 2, 3, 4, 8} {
+		ti, err := ParseContext(context.Background(), bytes.NewReader(log), ParseOptions{Workers: workers})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ti.SetCount()
+		if *ti.Count != *want.Count || len(ti.TpList) != len(want.TpList) {
+			t.Fatalf("%d workers: got %+v, want %+v", workers, *ti.Count, *want.Count)
+		}
+		for i, tp := range ti.TpList {
+			w := want.TpList[i]
+			if tp.Package != w.Package || len(tp.TEList) != len(w.TEList) || tp.Output.String() != w.Output.String() {
+				t.Errorf("%d workers: package %d is %s with output %q", workers, i, tp.Package, tp.Output.String())
+			}
+		}
+	}
+}
+
+func TestMergeShards(t *testing.T) {
+	shard := func(name, log string) *TestInfo {
+		ti := parse(t, log)
+		for _, tp := range ti.TpList {
+			tp.Shard = name
+		}
+		return ti
+	}
+	a := `{"Action":"run","Package":"a","Test":"TestA"}
+{"Action":"pass","Package":"a","Test":"TestA","Elapsed":0.1}
+{"Action":"output","Package":"a","Output":"ok  \ta\t0.1s\n"}
+{"Action":"pass","Package":"a","Elapsed":0.1}
+`
+	b := `{"Action":"run","Package":"a","Test":"TestB"}
+{"Action":"fail","Package":"a","Test":"TestB","Elapsed":0.2}
+{"Action":"fail","Package":"a","Elapsed":0.2}
+{"Action":"run","Package":"b","Test":"TestC"}
+{"Action":"pass","Package":"b","Test":"TestC"}
+{"Action":"pass","Package":"b"}
+`
+	ti := Merge(MergeUnion, shard("1", a), shard("2", b), shard("3", a))
+	if ti.Total != 3 || ti.Pass != 2 || ti.Fail != 1 {
+		t.Errorf("got %d tests, %d passed, %d failed", ti.Total, ti.Pass, ti.Fail)
+	}
+	pa := ti.Pkg("a")
+	if pa.Action != "fail" || pa.Shard != "1,2,3" {
+		t.Errorf("a: got %s, shard %q", pa.Action, pa.Shard)
+	}
+	if pb := ti.Pkg("b"); pb.Shard != "2" {
+		t.Errorf("b: got shard %q", pb.Shard)
+	}
+
+	ti = Merge(MergeUnion, shard("1", a), shard("1", a))
+	if pa := ti.Pkg("a"); pa.Shard != "1" || pa.Elapsed != 0.1 || strings.Count(pa.Output.String(), "ok") != 1 {
+		t.Errorf("duplicate: got shard %q, elapsed %g, output %q", pa.Shard, pa.Elapsed, pa.Output.String())
+	}
+}
+
+func TestReadCoverProfile(t *testing.T) {
+	p, err := ReadCoverProfile(strings.NewReader(`mode: set
+example.com/m/a/a.go:4.2,4.11 1 1
+example.com/m/a/a.go:5.3,6.1 2 0
+example.com/m/a/a.go:5.3,6.1 2 1
+example.com/m/b/b.go:3.16,3.26 1 0
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Mode != "set" || len(p.Files) != 2 {
+		t.Fatalf("got %+v", p)
+	}
+	if f := p.Files[0]; f.Statements != 3 || f.Covered != 3 {
+		t.Errorf("got %+v, want duplicate blocks counted once", f)
+	}
+	ti := &TestInfo{Count: &Count{}, TpList: []*TestPkg{{TestUt: &TestUt{TestEvent: events.TestEvent{Package: "example.com/m/b"}}}}}
+	ti.ApplyCoverProfile(p)
+	if ti.Statements != 4 || ti.Covered != 3 || ti.StatementCoverage != 75 {
+		t.Errorf("got total %d/%d %g%%", ti.Covered, ti.Statements, ti.StatementCoverage)
+	}
+	if c, ok := ti.TpList[0].Coverage(); !ok || c != 0 || ti.TpList[0].Statements != 1 {
+		t.Errorf("got package coverage %g %v", c, ok)
+	}
+	_, err = ReadCoverProfile(strings.NewReader("mode: set\nbroken line\n"))
+	if err == nil {
+		t.Error("invalid profile accepted")
+	}
+}
+
+func benchmarkParse(b *testing.B, parse func([]byte) (*TestInfo, error)) {
+	log := stream(50, 200, 5)
+	events := bytes.Count(log, []byte("\n"))
+	b.SetBytes(int64(len(log)))
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		ti, err := parse(log)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(ti.TpList) != 50 {
+			b.Fatalf("got %d packages", len(ti.TpList))
+		}
+	}
+	b.ReportMetric(float64(events)*float64(b.N)/time.Since(start).Seconds(), "events/s")
+}
+
+func BenchmarkParse(b *testing.B) {
+	benchmarkParse(b, func(log []byte) (*TestInfo, error) {
+		return Parse(bytes.NewReader(log))
+	})
+}
+
+func BenchmarkParseContext(b *testing.B) {
+	benchmarkParse(b, func(log []byte) (*TestInfo, error) {
+		return ParseContext(context.Background(), bytes.NewReader(log), ParseOptions{Workers: 4})
+	})
+}
+
+func BenchmarkParseChatty(b *testing.B) {
+	log := stream(1, 2, 20000)
+	b.SetBytes(int64(len(log)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := Parse(bytes.NewReader(log))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
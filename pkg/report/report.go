@@ -0,0 +1,455 @@
+// Package report aggregates go test -json events into a report of
+// packages and their tests.
+//
+// Parse a stream, sum the counts and write it with package render:
+//
+//	ti, err := report.Parse(r)
+//	if err != nil {
+//		return err
+//	}
+//	ti.SetCount()
+//	return render.XML(w, ti)
+//
+// The go-test-report command is a wrapper around this package and
+// render; everything it does is available to other tools.
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+type Count struct {
+	Total int `xml:"total,attr"`
+	Pass  int `xml:"pass,attr"`
+	Skip  int `xml:"skip,attr"`
+	Bench int `xml:"bench,attr"`
+	Fail  int `xml:"fail,attr"`
+	Added int `json:",omitempty" xml:"added,attr,omitempty"`
+	// Regressions counts failures that passed, or did not exist, in the baseline.
+	Regressions int `json:",omitempty" xml:"regressions,attr,omitempty"`
+	// Deleted counts baseline tests that did not run this time.
+	Deleted int `json:",omitempty" xml:"deleted,attr,omitempty"`
+	// Drifted counts tests that got slower than their historical median.
+	Drifted int `json:",omitempty" xml:"drifted,attr,omitempty"`
+	// SlowTests counts tests that took longer than the slow threshold, see
+	// MarkSlow.
+	SlowTests int `json:",omitempty" xml:"slow-tests,attr,omitempty"`
+	// Flakes counts tests that failed and then passed when rerun, in the
+	// same stream or by MergeRerun.
+	Flakes int `json:",omitempty" xml:"flakes,attr,omitempty"`
+	// SlowerBenchmarks counts benchmark results slower than the baseline.
+	SlowerBenchmarks int `json:",omitempty" xml:"slower-benchmarks,attr,omitempty"`
+	// Panics, Races and Timeouts count failures by their FailureKind.
+	Panics   int `json:",omitempty" xml:"panics,attr,omitempty"`
+	Races    int `json:",omitempty" xml:"races,attr,omitempty"`
+	Timeouts int `json:",omitempty" xml:"timeouts,attr,omitempty"`
+}
+
+// PassRate is the share of passed tests among the tests that were not
+// skipped, leaving out benchmarks.
+func (c *Count) PassRate() float64 {
+	run := c.Total - c.Skip - c.Bench
+	if run < 1 {
+		return 0
+	}
+	return float64(c.Pass) / float64(run)
+}
+
+// Add adds the counts of o to c.
+func (c *Count) Add(o *Count) {
+	c.Total += o.Total
+	c.Pass += o.Pass
+	c.Skip += o.Skip
+	c.Bench += o.Bench
+	c.Fail += o.Fail
+	c.Added += o.Added
+	c.Regressions += o.Regressions
+	c.Deleted += o.Deleted
+	c.Drifted += o.Drifted
+	c.SlowTests += o.SlowTests
+	c.Flakes += o.Flakes
+	c.Panics += o.Panics
+	c.Races += o.Races
+	c.Timeouts += o.Timeouts
+	c.SlowerBenchmarks += o.SlowerBenchmarks
+}
+
+// TrendRun is the result of a previous run.
+type TrendRun struct {
+	ID     string `xml:"id,attr"`
+	Branch string `json:",omitempty" xml:"branch,attr,omitempty"`
+	// PassRate is in percent.
+	PassRate float64 `xml:"pass-rate,attr"`
+	Fail     int     `xml:"fail,attr"`
+	// Elapsed is the time its packages took, in seconds.
+	Elapsed float64 `xml:"elapsed,attr"`
+}
+
+type TestInfo struct {
+	XMLName xml.Name   `json:"-" xml:"all"`
+	TpList  []*TestPkg `json:"Packages" xml:"pkg"`
+	// Time is when the report was generated, nil when timestamps are
+	// left out.
+	Time *time.Time `json:",omitempty" xml:"xml-create-time,attr,omitempty"`
+	// RunID is the id the run has in the history, taken from the clock
+	// when it was recorded rather than from Time, which may be pinned.
+	RunID string `json:",omitempty" xml:"run-id,attr,omitempty"`
+	// DeletedPkgs lists baseline packages missing from this run entirely.
+	DeletedPkgs []string `json:"DeletedPackages,omitempty" xml:"deleted-pkg"`
+	// Incomplete is set when reading the stream was interrupted.
+	Incomplete bool `json:",omitempty" xml:"incomplete,attr,omitempty"`
+	// MalformedLines counts the lines of the stream that were not JSON.
+	MalformedLines int `json:",omitempty" xml:"malformed-lines,attr,omitempty"`
+	// Race is set when the tests ran with the race detector, as told by
+	// the caller or by a race the detector reported.
+	Race bool `json:",omitempty" xml:"race,attr,omitempty"`
+	// Modules are the rollups of the modules of the run, see GroupModules.
+	Modules []*Module `json:",omitempty" xml:"module"`
+	// FailuresOnly is set when the tests that passed were left out of the
+	// report, though not out of its counts, see Filter.
+	FailuresOnly bool `json:",omitempty" xml:"failures-only,attr,omitempty"`
+	// SlowThreshold is the duration beyond which tests are slow, and
+	// Slowest the slowest tests, see MarkSlow and SetSlowest.
+	SlowThreshold string      `json:",omitempty" xml:"slow-threshold,attr,omitempty"`
+	Slowest       []*SlowTest `json:",omitempty" xml:"slowest"`
+	// Branch is the branch of the run, as recorded in the history, and
+	// Trend the previous runs of it and of other branches, oldest first.
+	Branch string      `json:",omitempty" xml:"branch,attr,omitempty"`
+	Trend  []*TrendRun `json:",omitempty" xml:"trend-run"`
+	// Timeout is the -timeout of the test binaries, see ApplyTimeout.
+	Timeout string `json:",omitempty" xml:"timeout,attr,omitempty"`
+	// StatementCoverage is the coverage of the whole cover profile, see
+	// ApplyCoverProfile.
+	StatementCoverage float64 `json:",omitempty" xml:"coverage,attr,omitempty"`
+	Statements        int     `json:",omitempty" xml:"statements,attr,omitempty"`
+	Covered           int     `json:",omitempty" xml:"covered,attr,omitempty"`
+	*Count
+	// spillDirs hold the output spilled while parsing.
+	spillDirs []string
+}
+
+// Close removes the output spilled to disk while parsing. Spilled output
+// is lost afterwards.
+func (ti *TestInfo) Close() error {
+	var err error
+	for _, dir := range ti.spillDirs {
+		if rerr := os.RemoveAll(dir); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	ti.spillDirs = nil
+	return err
+}
+
+// AddPackages moves the packages of o, and the output they spilled, to ti.
+func (ti *TestInfo) AddPackages(o *TestInfo) {
+	ti.TpList = append(ti.TpList, o.TpList...)
+	ti.spillDirs = append(ti.spillDirs, o.spillDirs...)
+	o.TpList, o.spillDirs = nil, nil
+}
+
+// SetCount sums the counts of all packages into ti.
+func (ti *TestInfo) SetCount() {
+	for _, testPkg := range ti.TpList {
+		ti.Add(testPkg.Count)
+	}
+}
+
+type TestUt struct {
+	events.TestEvent
+	// Output replaces TestEvent.Output so it can be spilled to disk.
+	Output   Output `json:"Output" xml:"output"`
+	StarTime string `json:"-" xml:"star-time,attr,omitempty"`
+	EndTime  string `json:"-" xml:"end-time,attr,omitempty"`
+	Dur      string `json:"-" xml:"dur,attr"`
+	New      bool   `json:"New,omitempty" xml:"new,attr,omitempty"`
+	// Regression is "pre-existing" or "new" for failed tests when a baseline is given.
+	Regression string `json:"Regression,omitempty" xml:"regression,attr,omitempty"`
+	// FirstFailed is the history run id where the current failure streak began.
+	FirstFailed       string `json:"FirstFailed,omitempty" xml:"first-failed,attr,omitempty"`
+	FirstFailedCommit string `json:"FirstFailedCommit,omitempty" xml:"first-failed-commit,attr,omitempty"`
+	// Median and Drift are set when the test is drift-factor times slower than its history.
+	Median string `json:"Median,omitempty" xml:"median,attr,omitempty"`
+	Drift  string `json:"Drift,omitempty" xml:"drift,attr,omitempty"`
+	Flaky  bool   `json:"Flaky,omitempty" xml:"flaky,attr,omitempty"`
+	Slow   bool   `json:"Slow,omitempty" xml:"slow,attr,omitempty"`
+	// FailureKind is FailurePanic, FailureRace or FailureTimeout for a
+	// failure the output shows to be one.
+	FailureKind string `json:"FailureKind,omitempty" xml:"failure-kind,attr,omitempty"`
+	// Attempts lists every run of a test that ran more than once, by
+	// -count or by a rerun, in order.
+	Attempts []*Attempt `json:",omitempty" xml:"attempt"`
+	// Subtests and Rollup are only set in the copy Tree returns.
+	Subtests []*TestUt `json:",omitempty" xml:"ut"`
+	Rollup
+	// spans are the runs read so far, as ranges of Output.
+	spans []span
+}
+
+// Attempt is one run of a test.
+type Attempt struct {
+	Action  string  `xml:"action,attr"`
+	Elapsed float64 `xml:"-"`
+	Dur     string  `json:"-" xml:"dur,attr"`
+	Output  string  `json:",omitempty" xml:"output,omitempty"`
+}
+
+type span struct {
+	from, to int
+	action   string
+	elapsed  float64
+	start    *time.Time
+}
+
+// addAttempt appends a to the attempts of u, starting them with the
+// result u has so far if it had none.
+func (u *TestUt) addAttempt(a *Attempt) {
+	if len(u.Attempts) < 1 {
+		u.Attempts = []*Attempt{{Action: u.Action, Elapsed: u.Elapsed, Dur: u.Dur, Output: u.Output.String()}}
+	}
+	u.Attempts = append(u.Attempts, a)
+}
+
+// setAttempts turns the runs of u read by the parser into its attempts
+// when there was more than one, adding to those of a package read again,
+// see Parser.reopen. A test that failed and then passed in its last
+// attempt is flaky and passed; otherwise failing any attempt fails it.
+func (u *TestUt) setAttempts() {
+	if len(u.Attempts)+len(u.spans) < 2 {
+		u.spans = nil
+		return
+	}
+	out := u.Output.String()
+	for _, s := range u.spans {
+		if len(s.action) < 1 {
+			// Interrupted before it ended.
+			s.to, s.action = len(out), u.Action
+		}
+		if s.to > len(out) {
+			s.to = len(out)
+		}
+		if s.from > s.to {
+			s.from = s.to
+		}
+		u.Attempts = append(u.Attempts, &Attempt{
+			Action:  s.action,
+			Elapsed: s.elapsed,
+			Dur:     time.Duration(s.elapsed * float64(time.Second)).String(),
+			Output:  out[s.from:s.to],
+		})
+	}
+	u.spans = nil
+	elapsed := 0.0
+	u.Flaky = false
+	for _, a := range u.Attempts {
+		elapsed += a.Elapsed
+		if a.Action == events.ActionFail {
+			u.Action = events.ActionFail
+		}
+	}
+	if last := u.Attempts[len(u.Attempts)-1]; u.Action == events.ActionFail && last.Action == events.ActionPass {
+		u.Action = events.ActionPass
+		u.Flaky = true
+	}
+	u.Elapsed = elapsed
+	u.initTime()
+}
+
+// initTime sets the times as text from the time u ended and its
+// elapsed time.
+func (u *TestUt) initTime() {
+	dur := time.Duration(u.Elapsed * float64(time.Second))
+	u.Dur = dur.String()
+	if u.Time == nil {
+		// test2json streams have no times.
+		return
+	}
+	u.StarTime = u.Time.Add(-dur).Format(clockFormat)
+	u.EndTime = u.Time.Format(clockFormat)
+}
+
+// restoreTime fills in what the format u was read from leaves out: the
+// times as text of JSON, or the elapsed seconds of XML.
+func (u *TestUt) restoreTime(isJSON bool) {
+	if isJSON {
+		u.initTime()
+		for _, a := range u.Attempts {
+			a.Dur = time.Duration(a.Elapsed * float64(time.Second)).String()
+		}
+		return
+	}
+	if d, err := time.ParseDuration(u.Dur); err == nil {
+		u.Elapsed = d.Seconds()
+	}
+	for _, a := range u.Attempts {
+		if d, err := time.ParseDuration(a.Dur); err == nil {
+			a.Elapsed = d.Seconds()
+		}
+	}
+}
+
+type TestPkg struct {
+	*TestUt
+	uts    map[string]*TestUt
+	TEList []*TestUt `json:"Tests" xml:"ut"`
+	// DeletedTests lists baseline tests of this package that did not run.
+	DeletedTests []*DeletedTest `json:"DeletedTests,omitempty" xml:"deleted"`
+	// Module is the path of the module of the package and Dir its
+	// directory relative to the root of the repository, see GroupModules.
+	Module string `json:",omitempty" xml:"module,attr,omitempty"`
+	Dir    string `json:",omitempty" xml:"dir,attr,omitempty"`
+	// TimeoutUsed is the percentage of the timeout the package took, and
+	// TimeoutRisk set when it is close enough to flake, see ApplyTimeout.
+	TimeoutUsed float64 `json:",omitempty" xml:"timeout-used,attr,omitempty"`
+	TimeoutRisk bool    `json:",omitempty" xml:"timeout-risk,attr,omitempty"`
+	// InitOutput is the output of the package before its first test, such
+	// as what its init functions print.
+	InitOutput string `json:",omitempty" xml:"init-output,omitempty"`
+	// initDone is set once InitOutput is complete.
+	initDone bool
+	// crashed is the test the package output is attributed to while a
+	// crash is printed, see Parser.crashTest.
+	crashed *TestUt
+	// NoTests is set when the package passed because no test matched, as
+	// when -run matches nothing.
+	NoTests bool `json:",omitempty" xml:"no-tests,attr,omitempty"`
+	// BuildFailed is set when the package or its tests did not compile;
+	// the compiler output is part of the package output then.
+	BuildFailed bool `json:",omitempty" xml:"build-failed,attr,omitempty"`
+	// StatementCoverage is the share of the Statements of the package
+	// its tests Covered in percent, per file in CoverFiles, see
+	// ApplyCoverProfile.
+	StatementCoverage float64      `json:",omitempty" xml:"coverage,attr,omitempty"`
+	Statements        int          `json:",omitempty" xml:"statements,attr,omitempty"`
+	Covered           int          `json:",omitempty" xml:"covered,attr,omitempty"`
+	CoverFiles        []*CoverFile `json:",omitempty" xml:"cover-file"`
+	// Benchmarks are the results the benchmarks of the package printed.
+	Benchmarks []*Benchmark `json:",omitempty" xml:"benchmark"`
+	// File is the report of the package in the index of a split report.
+	File string `json:",omitempty" xml:"file,attr,omitempty"`
+	// Shard names the input of a merged report the package was read
+	// from, several of them separated by commas, see Merge.
+	Shard string `json:",omitempty" xml:"shard,attr,omitempty"`
+	*Count
+}
+
+type DeletedTest struct {
+	Test   string `xml:"name,attr"`
+	Action string `xml:"last-action,attr,omitempty"`
+}
+
+func (tp *TestPkg) setCount(u *TestUt) error {
+	action := u.Action
+	if u.Flaky {
+		tp.Flakes++
+	}
+	if u.Action == events.ActionFail {
+		tp.addFailure(u.FailureKind)
+	}
+	switch action {
+	case events.ActionSkip:
+		tp.Skip++
+	case events.ActionPass:
+		tp.Pass++
+	case events.ActionFail:
+		tp.Fail++
+	case events.ActionBench:
+		tp.Bench++
+	default:
+		if len(action) < 1 {
+			return fmt.Errorf("%s: test %s has no result", tp.Package, u.Test)
+		}
+	}
+	return nil
+}
+
+// Parse aggregates the go test -json stream of r. Packages are ordered
+// by the position of their final event. Counts are summed per package
+// only; call SetCount once the report is complete.
+func Parse(r io.Reader) (*TestInfo, error) {
+	return NewParser().Parse(r)
+}
+
+// Read loads a report previously written in the native XML or JSON
+// format.
+func Read(path string) (*TestInfo, error) {
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ti := &TestInfo{Count: &Count{}}
+	isJSON := false
+	if b := bytes.TrimSpace(bts); len(b) > 0 && b[0] == '{' {
+		isJSON = true
+		err = json.Unmarshal(b, ti)
+	} else {
+		err = unmarshalXML(bts, ti)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, tp := range ti.TpList {
+		tp.flatten()
+		for _, u := range append([]*TestUt{tp.TestUt}, tp.TEList...) {
+			u.restoreTime(isJSON)
+		}
+	}
+	for _, st := range ti.Slowest {
+		st.restoreTime(isJSON)
+	}
+	return ti, nil
+}
+
+// unmarshalXML decodes a report whose root element may have been renamed
+// or put in a namespace.
+func unmarshalXML(bts []byte, ti *TestInfo) error {
+	dec := xml.NewDecoder(bytes.NewReader(bts))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			start.Name = xml.Name{Local: "all"}
+			return dec.DecodeElement(ti, &start)
+		}
+	}
+}
+
+func (ti *TestInfo) utMap() map[string]*TestUt {
+	m := map[string]*TestUt{}
+	for _, tp := range ti.TpList {
+		for _, u := range tp.TEList {
+			m[tp.Package+"\x00"+u.Test] = u
+		}
+	}
+	return m
+}
+
+// Pkg returns the package with the given import path, or nil.
+func (ti *TestInfo) Pkg(name string) *TestPkg {
+	for _, tp := range ti.TpList {
+		if tp.Package == name {
+			return tp
+		}
+	}
+	return nil
+}
+
+// Ut returns the test with the given name, or nil.
+func (tp *TestPkg) Ut(name string) *TestUt {
+	for _, u := range tp.TEList {
+		if u.Test == name {
+			return u
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package report
+
+import "github.com/jiuliyemingzhi/go-test-report/pkg/events"
+
+// MergeRerun folds the results of a rerun into ti: failed tests that
+// passed this time are kept as passed and marked flaky, and no longer
+// count as crashes.
+func (ti *TestInfo) MergeRerun(re *TestInfo) {
+	for _, rtp := range re.TpList {
+		tp := ti.Pkg(rtp.Package)
+		if tp == nil {
+			continue
+		}
+		for _, ru := range rtp.TEList {
+			u := tp.Ut(ru.Test)
+			if u == nil {
+				continue
+			}
+			u.addAttempt(&Attempt{Action: ru.Action, Elapsed: ru.Elapsed, Dur: ru.Dur, Output: ru.Output.String()})
+			_, _ = u.Output.WriteString(ru.Output.String())
+			if u.Action == events.ActionFail && ru.Action == events.ActionPass {
+				u.Action = events.ActionPass
+				u.Flaky = true
+				tp.Fail--
+				tp.Pass++
+				tp.Flakes++
+				tp.removeFailure(u.FailureKind)
+				u.FailureKind = ""
+			}
+		}
+		if tp.Action == events.ActionFail && tp.Fail == 0 && rtp.Action == events.ActionPass {
+			tp.Action = events.ActionPass
+			tp.removeFailure(tp.FailureKind)
+			tp.FailureKind = ""
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package report
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:generate sh -c "go run ../../cmd/go-test-report schema > ../../schema/report.schema.json"
+
+// JSONSchema returns the JSON Schema of the report as written by the json
+// format. It is derived from the model, so it changes only with it.
+func JSONSchema() ([]byte, error) {
+	g := &schemaGen{defs: map[string]interface{}{}}
+	root := g.object(reflect.TypeOf(TestInfo{}))
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "go-test-report report"
+	root["$defs"] = g.defs
+	return json.MarshalIndent(root, "", "\t")
+}
+
+type schemaGen struct {
+	defs map[string]interface{}
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	outputType     = reflect.TypeOf(Output{})
+	schemaTypeName = map[reflect.Kind]string{
+		reflect.String:  "string",
+		reflect.Bool:    "boolean",
+		reflect.Int:     "integer",
+		reflect.Int64:   "integer",
+		reflect.Float64: "number",
+	}
+)
+
+func (g *schemaGen) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == outputType:
+		return map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Slice:
+		// Empty slices are written as null.
+		return map[string]interface{}{"type": []string{"array", "null"}, "items": g.schema(t.Elem())}
+	case t.Kind() == reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil
+			g.defs[t.Name()] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	}
+	return map[string]interface{}{"type": schemaTypeName[t.Kind()]}
+}
+
+// object describes a struct the way encoding/json marshals it: embedded
+// structs are flattened and shallower fields hide deeper ones.
+func (g *schemaGen) object(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	required := map[string]bool{}
+	depth := map[string]int{}
+	var walk func(t reflect.Type, d int)
+	walk = func(t reflect.Type, d int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			parts := strings.Split(tag, ",")
+			name, opts := parts[0], parts[1:]
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if f.Anonymous && len(name) < 1 && ft.Kind() == reflect.Struct {
+				walk(ft, d+1)
+				continue
+			}
+			if len(f.PkgPath) > 0 {
+				continue
+			}
+			if len(name) < 1 {
+				name = f.Name
+			}
+			if old, ok := depth[name]; ok && old <= d {
+				continue
+			}
+			depth[name] = d
+			props[name] = g.schema(f.Type)
+			required[name] = true
+			for _, opt := range opts {
+				if opt == "omitempty" {
+					required[name] = false
+				}
+			}
+		}
+	}
+	walk(t, 0)
+	obj := map[string]interface{}{"type": "object", "properties": props}
+	var names []string
+	for name, ok := range required {
+		if ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		obj["required"] = names
+	}
+	return obj
+}
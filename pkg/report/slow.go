@@ -0,0 +1,72 @@
+package report
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+// SlowTest is one of the slowest tests of a run, see SetSlowest.
+type SlowTest struct {
+	Package string  `xml:"package,attr"`
+	Test    string  `xml:"name,attr"`
+	Action  string  `xml:"action,attr"`
+	Elapsed float64 `xml:"-"`
+	Dur     string  `json:"-" xml:"dur,attr"`
+}
+
+// MarkSlow records threshold and marks the tests that took longer as
+// slow. Benchmarks are left out, they run as long as they need to.
+func (ti *TestInfo) MarkSlow(threshold time.Duration) {
+	if threshold <= 0 {
+		return
+	}
+	ti.SlowThreshold = threshold.String()
+	for _, tp := range ti.TpList {
+		for _, u := range tp.TEList {
+			u.Slow = u.Action != events.ActionBench && u.Elapsed > threshold.Seconds()
+			if u.Slow {
+				tp.SlowTests++
+			}
+		}
+	}
+}
+
+// SetSlowest lists the n slowest tests of ti across all packages,
+// slowest first. Only tests without subtests are ranked, since the time
+// of a parent includes that of its subtests, and benchmarks and tests
+// without a duration are left out.
+func (ti *TestInfo) SetSlowest(n int) {
+	ti.Slowest = nil
+	if n < 1 {
+		return
+	}
+	var tests []*TestUt
+	for _, tp := range ti.TpList {
+		parents := tp.parents()
+		for _, u := range tp.TEList {
+			if u.Action != events.ActionBench && u.Elapsed > 0 && !parents[u.Test] {
+				tests = append(tests, u)
+			}
+		}
+	}
+	sort.SliceStable(tests, func(i, j int) bool {
+		return tests[i].Elapsed > tests[j].Elapsed
+	})
+	if len(tests) > n {
+		tests = tests[:n]
+	}
+	for _, u := range tests {
+		ti.Slowest = append(ti.Slowest, &SlowTest{Package: u.Package, Test: u.Test, Action: u.Action, Elapsed: u.Elapsed,
+			Dur: time.Duration(u.Elapsed * float64(time.Second)).String()})
+	}
+}
+
+func (st *SlowTest) restoreTime(isJSON bool) {
+	if isJSON {
+		st.Dur = time.Duration(st.Elapsed * float64(time.Second)).String()
+	} else if d, err := time.ParseDuration(st.Dur); err == nil {
+		st.Elapsed = d.Seconds()
+	}
+}
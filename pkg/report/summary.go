@@ -0,0 +1,56 @@
+package report
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+// summaryLine matches the line go test prints for every package, such as
+// "ok  \tpkg\t0.53s", "FAIL\tpkg\t1.2s" or "?   \tpkg\t[no test files]".
+var summaryLine = regexp.MustCompile(`^(ok|FAIL|\?)\s+(\S+)\s+(?:(\d+(?:\.\d+)?)s|\(cached\)|(\[[^]]*\]))`)
+
+var summaryActions = map[string]string{
+	"ok":   events.ActionPass,
+	"FAIL": events.ActionFail,
+	"?":    events.ActionSkip,
+}
+
+// packageSummary is the result of a package from its summary line.
+type packageSummary struct {
+	pkg, action string
+	elapsed     float64
+	// buildFailed is set by "[build failed]" or "[setup failed]".
+	buildFailed bool
+}
+
+func parseSummary(output string) (packageSummary, bool) {
+	m := summaryLine.FindStringSubmatch(strings.TrimSuffix(output, "\n"))
+	if m == nil {
+		return packageSummary{}, false
+	}
+	s := packageSummary{pkg: m[2], action: summaryActions[m[1]], elapsed: events.Dv}
+	if len(m[3]) > 0 {
+		s.elapsed, _ = strconv.ParseFloat(m[3], 64)
+	}
+	s.buildFailed = m[4] == "[build failed]" || m[4] == "[setup failed]"
+	return s, true
+}
+
+// applySummary sets the result of tp from its summary line, for streams
+// where the final event of the package is missing, such as go test
+// output converted by test2json. The final event takes precedence.
+func (tp *TestPkg) applySummary(s packageSummary, e *events.TestEvent) {
+	if len(tp.Action) > 0 {
+		return
+	}
+	tp.Action = s.action
+	tp.Index = e.Index
+	if s.elapsed != events.Dv {
+		tp.Elapsed = s.elapsed
+	}
+	tp.Time = e.Time
+	tp.initTime()
+}
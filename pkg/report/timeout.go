@@ -0,0 +1,18 @@
+package report
+
+import "time"
+
+// ApplyTimeout records the timeout of the test binaries of the run and
+// the share of it every package used, and flags the packages that used
+// at least risk of it as at risk of timing out.
+func (ti *TestInfo) ApplyTimeout(timeout time.Duration, risk float64) {
+	if timeout <= 0 {
+		return
+	}
+	ti.Timeout = timeout.String()
+	for _, tp := range ti.TpList {
+		used := tp.Elapsed / timeout.Seconds()
+		tp.TimeoutUsed = used * 100
+		tp.TimeoutRisk = used >= risk
+	}
+}
@@ -0,0 +1,60 @@
+package report
+
+import "time"
+
+// clockFormat is the format of the start and end times of packages and
+// tests.
+const clockFormat = "15:04:05.000"
+
+// TimestampMode says how SetTimestamps writes the start and end times of
+// packages and tests.
+type TimestampMode int
+
+const (
+	// TimestampsAbsolute writes the clock time of the events.
+	TimestampsAbsolute TimestampMode = iota
+	// TimestampsRelative writes the time since the first package of the
+	// run started.
+	TimestampsRelative
+	// TimestampsOff leaves the times out, including the clock times of
+	// the final events of packages and tests.
+	TimestampsOff
+)
+
+// SetTimestamps rewrites the start and end times of the packages and
+// tests of ti for mode. TimestampsOff also drops their Time.
+func (ti *TestInfo) SetTimestamps(mode TimestampMode) {
+	start, _, ok := ti.RunTimes()
+	for _, tp := range ti.TpList {
+		for _, u := range append([]*TestUt{tp.TestUt}, tp.TEList...) {
+			u.initTime()
+			switch {
+			case mode == TimestampsOff:
+				u.StarTime, u.EndTime = "", ""
+				u.Time = nil
+			case mode == TimestampsRelative && ok && u.Time != nil:
+				end := u.Time.Sub(start)
+				u.StarTime = time.Time{}.Add(end - time.Duration(u.Elapsed*float64(time.Second))).Format(clockFormat)
+				u.EndTime = time.Time{}.Add(end).Format(clockFormat)
+			}
+		}
+	}
+}
+
+// RunTimes returns when the first package of ti started and the last
+// ended; ok is false for streams without times.
+func (ti *TestInfo) RunTimes() (start, end time.Time, ok bool) {
+	for _, tp := range ti.TpList {
+		if tp.Time == nil {
+			continue
+		}
+		if s := tp.Time.Add(-time.Duration(tp.Elapsed * float64(time.Second))); !ok || s.Before(start) {
+			start = s
+		}
+		if !ok || tp.Time.After(end) {
+			end = *tp.Time
+		}
+		ok = true
+	}
+	return start, end, ok
+}
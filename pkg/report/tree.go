@@ -0,0 +1,116 @@
+package report
+
+import (
+	"strings"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+// Rollup counts the subtests of a test at any depth.
+type Rollup struct {
+	SubTotal int `json:",omitempty" xml:"sub-total,attr,omitempty"`
+	SubPass  int `json:",omitempty" xml:"sub-pass,attr,omitempty"`
+	SubFail  int `json:",omitempty" xml:"sub-fail,attr,omitempty"`
+	SubSkip  int `json:",omitempty" xml:"sub-skip,attr,omitempty"`
+}
+
+// Tree returns a copy of ti whose packages list their top-level tests
+// only, with the subtests t.Run started nested under their parents. The
+// copy shares the output of ti.
+func (ti *TestInfo) Tree() *TestInfo {
+	t := *ti
+	t.TpList = make([]*TestPkg, len(ti.TpList))
+	for i, tp := range ti.TpList {
+		t.TpList[i] = tp.Tree()
+	}
+	return &t
+}
+
+// Tree returns a copy of tp whose tests are nested as in TestInfo.Tree.
+// A subtest whose parent did not run is nested under its closest
+// ancestor that did, or listed at the top level.
+func (tp *TestPkg) Tree() *TestPkg {
+	t := *tp
+	nodes := make(map[string]*TestUt, len(tp.TEList))
+	for _, u := range tp.TEList {
+		n := *u
+		n.Subtests, n.Rollup = nil, Rollup{}
+		nodes[u.Test] = &n
+	}
+	t.TEList = nil
+	for _, u := range tp.TEList {
+		n := nodes[u.Test]
+		var parent *TestUt
+		for i := strings.LastIndex(u.Test, "/"); i > 0 && parent == nil; i = strings.LastIndex(u.Test[:i], "/") {
+			parent = nodes[u.Test[:i]]
+		}
+		if parent == nil {
+			t.TEList = append(t.TEList, n)
+			continue
+		}
+		parent.Subtests = append(parent.Subtests, n)
+	}
+	for _, n := range t.TEList {
+		n.rollup()
+	}
+	return &t
+}
+
+// parents returns the names of the tests of tp that have subtests, as
+// nested by Tree.
+func (tp *TestPkg) parents() map[string]bool {
+	names := make(map[string]bool, len(tp.TEList))
+	for _, u := range tp.TEList {
+		names[u.Test] = false
+	}
+	for _, u := range tp.TEList {
+		for i := strings.LastIndex(u.Test, "/"); i > 0; i = strings.LastIndex(u.Test[:i], "/") {
+			if _, ok := names[u.Test[:i]]; ok {
+				names[u.Test[:i]] = true
+				break
+			}
+		}
+	}
+	return names
+}
+
+// rollup sets the Rollup of u and its subtests.
+func (u *TestUt) rollup() {
+	if len(u.Subtests) < 1 {
+		return
+	}
+	var r Rollup
+	for _, s := range u.Subtests {
+		s.rollup()
+		r.SubTotal += s.SubTotal + 1
+		r.SubPass += s.SubPass
+		r.SubFail += s.SubFail
+		r.SubSkip += s.SubSkip
+		switch s.Action {
+		case events.ActionPass:
+			r.SubPass++
+		case events.ActionFail:
+			r.SubFail++
+		case events.ActionSkip:
+			r.SubSkip++
+		}
+	}
+	u.Rollup = r
+}
+
+// flatten lists the subtests of a package read as a tree after their
+// parents again.
+func (tp *TestPkg) flatten() {
+	var list []*TestUt
+	var walk func(tests []*TestUt)
+	walk = func(tests []*TestUt) {
+		for _, u := range tests {
+			list = append(list, u)
+			sub := u.Subtests
+			u.Subtests, u.Rollup = nil, Rollup{}
+			walk(sub)
+		}
+	}
+	walk(tp.TEList)
+	tp.TEList = list
+}
@@ -0,0 +1,251 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jiuliyemingzhi/go-test-report/pkg/events"
+)
+
+var validActions = map[string]bool{
+	events.ActionPass:  true,
+	events.ActionFail:  true,
+	events.ActionSkip:  true,
+	events.ActionBench: true,
+}
+
+// Validate checks the invariants of ti: the counts match the tests, the
+// results are valid, the times parse and nothing is listed twice. It
+// returns a description of every problem found.
+func (ti *TestInfo) Validate() []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	sum := &Count{}
+	pkgs := map[string]bool{}
+	for _, tp := range ti.TpList {
+		name := tp.Package
+		if pkgs[name] {
+			add("%s: listed more than once", name)
+		}
+		pkgs[name] = true
+		if tp.Count == nil {
+			tp.Count = &Count{}
+		}
+		sum.Add(tp.Count)
+		if !validActions[tp.Action] && !(ti.Incomplete && len(tp.Action) < 1) {
+			add("%s: invalid result %q", name, tp.Action)
+		}
+		problems = append(problems, validTimes(name, tp.TestUt)...)
+		want := &Count{Total: len(tp.TEList)}
+		tests := map[string]bool{}
+		for _, u := range tp.TEList {
+			where := name + " " + u.Test
+			if tests[u.Test] {
+				add("%s: listed more than once", where)
+			}
+			tests[u.Test] = true
+			if len(u.Package) > 0 && u.Package != name {
+				add("%s: belongs to package %s", where, u.Package)
+			}
+			switch u.Action {
+			case events.ActionPass:
+				want.Pass++
+			case events.ActionFail:
+				want.Fail++
+			case events.ActionSkip:
+				want.Skip++
+			case events.ActionBench:
+				want.Bench++
+			default:
+				add("%s: invalid result %q", where, u.Action)
+			}
+			for i, a := range u.Attempts {
+				if !validActions[a.Action] {
+					add("%s: attempt %d: invalid result %q", where, i+1, a.Action)
+				}
+			}
+			problems = append(problems, validTimes(where, u)...)
+		}
+		if ti.FailuresOnly {
+			// Only the failed and skipped tests are listed.
+			want.Total, want.Pass, want.Bench = tp.Total, tp.Pass, tp.Bench
+		}
+		problems = append(problems, compareCounts(name, tp.Count, want)...)
+	}
+	if ti.Count == nil {
+		add("the report has no totals")
+	} else {
+		problems = append(problems, compareCounts("the report", ti.Count, sum)...)
+	}
+	return problems
+}
+
+func compareCounts(where string, got, want *Count) []string {
+	var problems []string
+	for _, c := range []struct {
+		name      string
+		got, want int
+	}{
+		{"total", got.Total, want.Total},
+		{"pass", got.Pass, want.Pass},
+		{"fail", got.Fail, want.Fail},
+		{"skip", got.Skip, want.Skip},
+		{"bench", got.Bench, want.Bench},
+	} {
+		if c.got != c.want {
+			problems = append(problems, fmt.Sprintf("%s: %s count is %d, want %d", where, c.name, c.got, c.want))
+		}
+	}
+	return problems
+}
+
+func validTimes(where string, u *TestUt) []string {
+	var problems []string
+	if u.Elapsed < 0 || math.IsNaN(u.Elapsed) {
+		problems = append(problems, fmt.Sprintf("%s: invalid elapsed time %v", where, u.Elapsed))
+	}
+	if len(u.Dur) > 0 {
+		if d, err := time.ParseDuration(u.Dur); err != nil || d < 0 {
+			problems = append(problems, fmt.Sprintf("%s: invalid duration %q", where, u.Dur))
+		}
+	}
+	for _, t := range []string{u.StarTime, u.EndTime} {
+		if _, err := time.Parse("15:04:05.000", t); len(t) > 0 && err != nil {
+			problems = append(problems, fmt.Sprintf("%s: invalid time %q", where, t))
+		}
+	}
+	return problems
+}
+
+// ValidateJSON checks doc, a report written by the json format, against
+// JSONSchema. Only the keywords JSONSchema uses are supported.
+func ValidateJSON(doc []byte) ([]string, error) {
+	b, err := JSONSchema()
+	if err != nil {
+		return nil, err
+	}
+	var schema map[string]interface{}
+	err = json.Unmarshal(b, &schema)
+	if err != nil {
+		return nil, err
+	}
+	var x interface{}
+	err = json.Unmarshal(doc, &x)
+	if err != nil {
+		return nil, err
+	}
+	defs, _ := schema["$defs"].(map[string]interface{})
+	v := &schemaValidator{defs: defs}
+	v.check(schema, x, "")
+	return v.problems, nil
+}
+
+type schemaValidator struct {
+	defs     map[string]interface{}
+	problems []string
+}
+
+func (v *schemaValidator) fail(path, format string, args ...interface{}) {
+	if len(path) < 1 {
+		path = "/"
+	}
+	v.problems = append(v.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *schemaValidator) check(s map[string]interface{}, x interface{}, path string) {
+	if ref, ok := s["$ref"].(string); ok {
+		def, _ := v.defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{})
+		if def == nil {
+			v.fail(path, "unknown reference %s", ref)
+			return
+		}
+		s = def
+	}
+	if t, ok := s["type"]; ok {
+		var types []string
+		switch t := t.(type) {
+		case string:
+			types = []string{t}
+		case []interface{}:
+			for _, name := range t {
+				types = append(types, fmt.Sprint(name))
+			}
+		}
+		if !hasSchemaType(types, x) {
+			v.fail(path, "want %s", strings.Join(types, " or "))
+			return
+		}
+	}
+	if s["format"] == "date-time" {
+		if str, _ := x.(string); len(str) > 0 {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				v.fail(path, "invalid date-time %q", str)
+			}
+		}
+	}
+	switch x := x.(type) {
+	case map[string]interface{}:
+		props, _ := s["properties"].(map[string]interface{})
+		names := make([]string, 0, len(x))
+		for name := range x {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if p, ok := props[name].(map[string]interface{}); ok {
+				v.check(p, x[name], path+"/"+name)
+			}
+		}
+		required, _ := s["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := x[fmt.Sprint(name)]; !ok {
+				v.fail(path, "missing %s", name)
+			}
+		}
+	case []interface{}:
+		if items, ok := s["items"].(map[string]interface{}); ok {
+			for i, item := range x {
+				v.check(items, item, path+"/"+strconv.Itoa(i))
+			}
+		}
+	}
+}
+
+func hasSchemaType(types []string, x interface{}) bool {
+	for _, t := range types {
+		switch x := x.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case float64:
+			if t == "number" || t == "integer" && x == math.Trunc(x) {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}